@@ -0,0 +1,83 @@
+// knb-barrier is the start barrier of kubenetbench clients. It wraps the
+// command of a client container:
+//
+//	knb-barrier -port <port> -- <command> [<args>...]
+//
+// It blocks until kubenetbench connects to the barrier port and sends the
+// common start time (ns since the epoch), waits until then, replies with the
+// time it executes the command (ns since the epoch), and executes the command
+// (which replaces the barrier process).
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var port = flag.Int("port", 7070, "Barrier port")
+
+// waitForRelease accepts connections on l until one of them sends a valid
+// start time, and waits until then. It returns the connection, so that the
+// exec time can be reported on it.
+func waitForRelease(l net.Listener) (net.Conn, error) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		var ns int64
+		if err == nil {
+			ns, err = strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+		}
+		if err != nil {
+			log.Printf("invalid start time from %s: %s", conn.RemoteAddr(), err)
+			conn.Close()
+			continue
+		}
+
+		time.Sleep(time.Until(time.Unix(0, ns)))
+		return conn, nil
+	}
+}
+
+func main() {
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		log.Fatal("no command")
+	}
+	// NB: the command is resolved before the barrier, so that a missing
+	// command fails the client before it is released
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		log.Fatal(fmt.Errorf("listen on barrier port failed: %w", err))
+	}
+	conn, err := waitForRelease(l)
+	l.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	_, err = fmt.Fprintf(conn, "%d\n", time.Now().UnixNano())
+	conn.Close()
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to report exec time: %w", err))
+	}
+	log.Fatal(syscall.Exec(path, args, os.Environ()))
+}
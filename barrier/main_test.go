@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWaitForRelease(t *testing.T) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	start := time.Now().Add(50 * time.Millisecond)
+	go func() {
+		// an invalid start time does not release the barrier
+		if conn, err := net.Dial("tcp", l.Addr().String()); err == nil {
+			fmt.Fprintf(conn, "now\n")
+			conn.Close()
+		}
+		if conn, err := net.Dial("tcp", l.Addr().String()); err == nil {
+			fmt.Fprintf(conn, "%d\n", start.UnixNano())
+		}
+	}()
+
+	conn, err := waitForRelease(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if now := time.Now(); now.Before(start) {
+		t.Errorf("released at %s before the start time %s", now, start)
+	}
+}
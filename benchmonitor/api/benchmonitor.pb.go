@@ -29,6 +29,61 @@ const (
 // of the legacy proto package is being used.
 const _ = proto.ProtoPackageIsVersion4
 
+type CollectionState int32
+
+const (
+	// created, collectors not started yet
+	CollectionState_COLLECTION_PENDING CollectionState = 0
+	CollectionState_COLLECTION_RUNNING CollectionState = 1
+	// results are available
+	CollectionState_COLLECTION_DONE CollectionState = 2
+	// failed or cancelled (see error)
+	CollectionState_COLLECTION_FAILED CollectionState = 3
+)
+
+// Enum value maps for CollectionState.
+var (
+	CollectionState_name = map[int32]string{
+		0: "COLLECTION_PENDING",
+		1: "COLLECTION_RUNNING",
+		2: "COLLECTION_DONE",
+		3: "COLLECTION_FAILED",
+	}
+	CollectionState_value = map[string]int32{
+		"COLLECTION_PENDING": 0,
+		"COLLECTION_RUNNING": 1,
+		"COLLECTION_DONE":    2,
+		"COLLECTION_FAILED":  3,
+	}
+)
+
+func (x CollectionState) Enum() *CollectionState {
+	p := new(CollectionState)
+	*p = x
+	return p
+}
+
+func (x CollectionState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CollectionState) Descriptor() protoreflect.EnumDescriptor {
+	return file_benchmonitor_benchmonitor_proto_enumTypes[0].Descriptor()
+}
+
+func (CollectionState) Type() protoreflect.EnumType {
+	return &file_benchmonitor_benchmonitor_proto_enumTypes[0]
+}
+
+func (x CollectionState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CollectionState.Descriptor instead.
+func (CollectionState) EnumDescriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{0}
+}
+
 type Empty struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -67,19 +122,135 @@ func (*Empty) Descriptor() ([]byte, []int) {
 	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{0}
 }
 
+type Param struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Param) Reset() {
+	*x = Param{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Param) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Param) ProtoMessage() {}
+
+func (x *Param) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Param.ProtoReflect.Descriptor instead.
+func (*Param) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Param) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Param) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// a collector (e.g., perf-record, softirqs) and its parameters
+type CollectorConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name   string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Params []*Param `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty"`
+}
+
+func (x *CollectorConf) Reset() {
+	*x = CollectorConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CollectorConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectorConf) ProtoMessage() {}
+
+func (x *CollectorConf) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectorConf.ProtoReflect.Descriptor instead.
+func (*CollectorConf) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CollectorConf) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CollectorConf) GetParams() []*Param {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
 type CollectionConf struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	// maximum duration of the collection (e.g., "30s", or "30" for seconds).
+	// If empty, the collection runs until it is stopped.
 	Duration     string `protobuf:"bytes,1,opt,name=duration,proto3" json:"duration,omitempty"`
 	CollectionId string `protobuf:"bytes,2,opt,name=collectionId,proto3" json:"collectionId,omitempty"`
+	// collectors of the collection (default: perf-record). The results are
+	// an archive with the files of every collector, and a manifest.
+	Collectors []*CollectorConf `protobuf:"bytes,3,rep,name=collectors,proto3" json:"collectors,omitempty"`
 }
 
 func (x *CollectionConf) Reset() {
 	*x = CollectionConf{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[1]
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -92,7 +263,7 @@ func (x *CollectionConf) String() string {
 func (*CollectionConf) ProtoMessage() {}
 
 func (x *CollectionConf) ProtoReflect() protoreflect.Message {
-	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[1]
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -105,7 +276,7 @@ func (x *CollectionConf) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CollectionConf.ProtoReflect.Descriptor instead.
 func (*CollectionConf) Descriptor() ([]byte, []int) {
-	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{1}
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *CollectionConf) GetDuration() string {
@@ -122,18 +293,30 @@ func (x *CollectionConf) GetCollectionId() string {
 	return ""
 }
 
+func (x *CollectionConf) GetCollectors() []*CollectorConf {
+	if x != nil {
+		return x.Collectors
+	}
+	return nil
+}
+
 type CollectionResultsConf struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	CollectionId string `protobuf:"bytes,1,opt,name=collectionId,proto3" json:"collectionId,omitempty"`
+	// offset in the results to start from (e.g., to resume a transfer)
+	Offset int64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	// size of the data of File messages (default: the chunk size of the
+	// monitor)
+	ChunkSize int64 `protobuf:"varint,3,opt,name=chunkSize,proto3" json:"chunkSize,omitempty"`
 }
 
 func (x *CollectionResultsConf) Reset() {
 	*x = CollectionResultsConf{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[2]
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -146,7 +329,7 @@ func (x *CollectionResultsConf) String() string {
 func (*CollectionResultsConf) ProtoMessage() {}
 
 func (x *CollectionResultsConf) ProtoReflect() protoreflect.Message {
-	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[2]
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -159,7 +342,7 @@ func (x *CollectionResultsConf) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CollectionResultsConf.ProtoReflect.Descriptor instead.
 func (*CollectionResultsConf) Descriptor() ([]byte, []int) {
-	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{2}
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *CollectionResultsConf) GetCollectionId() string {
@@ -169,18 +352,227 @@ func (x *CollectionResultsConf) GetCollectionId() string {
 	return ""
 }
 
+func (x *CollectionResultsConf) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *CollectionResultsConf) GetChunkSize() int64 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+type CollectionRef struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CollectionId string `protobuf:"bytes,1,opt,name=collectionId,proto3" json:"collectionId,omitempty"`
+}
+
+func (x *CollectionRef) Reset() {
+	*x = CollectionRef{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CollectionRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionRef) ProtoMessage() {}
+
+func (x *CollectionRef) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionRef.ProtoReflect.Descriptor instead.
+func (*CollectionRef) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CollectionRef) GetCollectionId() string {
+	if x != nil {
+		return x.CollectionId
+	}
+	return ""
+}
+
+type CollectionStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CollectionId string          `protobuf:"bytes,1,opt,name=collectionId,proto3" json:"collectionId,omitempty"`
+	State        CollectionState `protobuf:"varint,2,opt,name=state,proto3,enum=benchmonitor.CollectionState" json:"state,omitempty"`
+	// error of failed collections, or the errors of the collectors that
+	// failed (for done collections)
+	Error string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// names of the collectors
+	Collectors []string `protobuf:"bytes,4,rep,name=collectors,proto3" json:"collectors,omitempty"`
+	// start and end time (unix nanoseconds), or 0
+	StartTime int64 `protobuf:"varint,5,opt,name=startTime,proto3" json:"startTime,omitempty"`
+	EndTime   int64 `protobuf:"varint,6,opt,name=endTime,proto3" json:"endTime,omitempty"`
+}
+
+func (x *CollectionStatus) Reset() {
+	*x = CollectionStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CollectionStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionStatus) ProtoMessage() {}
+
+func (x *CollectionStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionStatus.ProtoReflect.Descriptor instead.
+func (*CollectionStatus) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CollectionStatus) GetCollectionId() string {
+	if x != nil {
+		return x.CollectionId
+	}
+	return ""
+}
+
+func (x *CollectionStatus) GetState() CollectionState {
+	if x != nil {
+		return x.State
+	}
+	return CollectionState_COLLECTION_PENDING
+}
+
+func (x *CollectionStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CollectionStatus) GetCollectors() []string {
+	if x != nil {
+		return x.Collectors
+	}
+	return nil
+}
+
+func (x *CollectionStatus) GetStartTime() int64 {
+	if x != nil {
+		return x.StartTime
+	}
+	return 0
+}
+
+func (x *CollectionStatus) GetEndTime() int64 {
+	if x != nil {
+		return x.EndTime
+	}
+	return 0
+}
+
+type CollectionList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Collections []*CollectionStatus `protobuf:"bytes,1,rep,name=collections,proto3" json:"collections,omitempty"`
+}
+
+func (x *CollectionList) Reset() {
+	*x = CollectionList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CollectionList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CollectionList) ProtoMessage() {}
+
+func (x *CollectionList) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CollectionList.ProtoReflect.Descriptor instead.
+func (*CollectionList) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CollectionList) GetCollections() []*CollectionStatus {
+	if x != nil {
+		return x.Collections
+	}
+	return nil
+}
+
+// File is a chunk of a file. All the chunks of a file have the same name,
+// size and sha256, and a stream has at least one chunk (even for empty files).
 type File struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// name of the file
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// total size of the file
+	Size int64 `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	// offset of data in the file
+	Offset int64 `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	// sha256 of the file (hex)
+	Sha256 string `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
 }
 
 func (x *File) Reset() {
 	*x = File{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[3]
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -193,7 +585,7 @@ func (x *File) String() string {
 func (*File) ProtoMessage() {}
 
 func (x *File) ProtoReflect() protoreflect.Message {
-	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[3]
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -201,17 +593,503 @@ func (x *File) ProtoReflect() protoreflect.Message {
 		}
 		return ms
 	}
-	return mi.MessageOf(x)
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use File.ProtoReflect.Descriptor instead.
+func (*File) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *File) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *File) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *File) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *File) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *File) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+type Counter struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value int64  `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Counter) Reset() {
+	*x = Counter{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Counter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Counter) ProtoMessage() {}
+
+func (x *Counter) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Counter.ProtoReflect.Descriptor instead.
+func (*Counter) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Counter) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Counter) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type ConntrackStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Counters []*Counter `protobuf:"bytes,1,rep,name=counters,proto3" json:"counters,omitempty"`
+}
+
+func (x *ConntrackStats) Reset() {
+	*x = ConntrackStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConntrackStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConntrackStats) ProtoMessage() {}
+
+func (x *ConntrackStats) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConntrackStats.ProtoReflect.Descriptor instead.
+func (*ConntrackStats) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ConntrackStats) GetCounters() []*Counter {
+	if x != nil {
+		return x.Counters
+	}
+	return nil
+}
+
+type NodeMetricsConf struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// sampling interval (e.g., "1s")
+	Interval string `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	// stop streaming after duration (e.g., "60s"). If empty, streaming stops
+	// when the client cancels the call.
+	Duration string `protobuf:"bytes,2,opt,name=duration,proto3" json:"duration,omitempty"`
+}
+
+func (x *NodeMetricsConf) Reset() {
+	*x = NodeMetricsConf{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NodeMetricsConf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeMetricsConf) ProtoMessage() {}
+
+func (x *NodeMetricsConf) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeMetricsConf.ProtoReflect.Descriptor instead.
+func (*NodeMetricsConf) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *NodeMetricsConf) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *NodeMetricsConf) GetDuration() string {
+	if x != nil {
+		return x.Duration
+	}
+	return ""
+}
+
+// CPU utilization (%) since the previous sample
+type CPUStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// cpu name (e.g., cpu0), or "cpu" for all cpus
+	Cpu     string  `protobuf:"bytes,1,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	User    float64 `protobuf:"fixed64,2,opt,name=user,proto3" json:"user,omitempty"`
+	Nice    float64 `protobuf:"fixed64,3,opt,name=nice,proto3" json:"nice,omitempty"`
+	System  float64 `protobuf:"fixed64,4,opt,name=system,proto3" json:"system,omitempty"`
+	Idle    float64 `protobuf:"fixed64,5,opt,name=idle,proto3" json:"idle,omitempty"`
+	Iowait  float64 `protobuf:"fixed64,6,opt,name=iowait,proto3" json:"iowait,omitempty"`
+	Irq     float64 `protobuf:"fixed64,7,opt,name=irq,proto3" json:"irq,omitempty"`
+	Softirq float64 `protobuf:"fixed64,8,opt,name=softirq,proto3" json:"softirq,omitempty"`
+	Steal   float64 `protobuf:"fixed64,9,opt,name=steal,proto3" json:"steal,omitempty"`
+}
+
+func (x *CPUStats) Reset() {
+	*x = CPUStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CPUStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CPUStats) ProtoMessage() {}
+
+func (x *CPUStats) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CPUStats.ProtoReflect.Descriptor instead.
+func (*CPUStats) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CPUStats) GetCpu() string {
+	if x != nil {
+		return x.Cpu
+	}
+	return ""
+}
+
+func (x *CPUStats) GetUser() float64 {
+	if x != nil {
+		return x.User
+	}
+	return 0
+}
+
+func (x *CPUStats) GetNice() float64 {
+	if x != nil {
+		return x.Nice
+	}
+	return 0
+}
+
+func (x *CPUStats) GetSystem() float64 {
+	if x != nil {
+		return x.System
+	}
+	return 0
+}
+
+func (x *CPUStats) GetIdle() float64 {
+	if x != nil {
+		return x.Idle
+	}
+	return 0
+}
+
+func (x *CPUStats) GetIowait() float64 {
+	if x != nil {
+		return x.Iowait
+	}
+	return 0
+}
+
+func (x *CPUStats) GetIrq() float64 {
+	if x != nil {
+		return x.Irq
+	}
+	return 0
+}
+
+func (x *CPUStats) GetSoftirq() float64 {
+	if x != nil {
+		return x.Softirq
+	}
+	return 0
+}
+
+func (x *CPUStats) GetSteal() float64 {
+	if x != nil {
+		return x.Steal
+	}
+	return 0
+}
+
+// NIC counters (see /proc/net/dev)
+type NICStats struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name      string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	RxBytes   uint64 `protobuf:"varint,2,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	RxPackets uint64 `protobuf:"varint,3,opt,name=rx_packets,json=rxPackets,proto3" json:"rx_packets,omitempty"`
+	RxErrors  uint64 `protobuf:"varint,4,opt,name=rx_errors,json=rxErrors,proto3" json:"rx_errors,omitempty"`
+	RxDropped uint64 `protobuf:"varint,5,opt,name=rx_dropped,json=rxDropped,proto3" json:"rx_dropped,omitempty"`
+	TxBytes   uint64 `protobuf:"varint,6,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	TxPackets uint64 `protobuf:"varint,7,opt,name=tx_packets,json=txPackets,proto3" json:"tx_packets,omitempty"`
+	TxErrors  uint64 `protobuf:"varint,8,opt,name=tx_errors,json=txErrors,proto3" json:"tx_errors,omitempty"`
+	TxDropped uint64 `protobuf:"varint,9,opt,name=tx_dropped,json=txDropped,proto3" json:"tx_dropped,omitempty"`
+}
+
+func (x *NICStats) Reset() {
+	*x = NICStats{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NICStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NICStats) ProtoMessage() {}
+
+func (x *NICStats) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NICStats.ProtoReflect.Descriptor instead.
+func (*NICStats) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *NICStats) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NICStats) GetRxBytes() uint64 {
+	if x != nil {
+		return x.RxBytes
+	}
+	return 0
+}
+
+func (x *NICStats) GetRxPackets() uint64 {
+	if x != nil {
+		return x.RxPackets
+	}
+	return 0
+}
+
+func (x *NICStats) GetRxErrors() uint64 {
+	if x != nil {
+		return x.RxErrors
+	}
+	return 0
+}
+
+func (x *NICStats) GetRxDropped() uint64 {
+	if x != nil {
+		return x.RxDropped
+	}
+	return 0
+}
+
+func (x *NICStats) GetTxBytes() uint64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
+}
+
+func (x *NICStats) GetTxPackets() uint64 {
+	if x != nil {
+		return x.TxPackets
+	}
+	return 0
+}
+
+func (x *NICStats) GetTxErrors() uint64 {
+	if x != nil {
+		return x.TxErrors
+	}
+	return 0
+}
+
+func (x *NICStats) GetTxDropped() uint64 {
+	if x != nil {
+		return x.TxDropped
+	}
+	return 0
+}
+
+type NodeMetrics struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// sample time (unix nanoseconds)
+	Timestamp int64       `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Cpus      []*CPUStats `protobuf:"bytes,2,rep,name=cpus,proto3" json:"cpus,omitempty"`
+	Nics      []*NICStats `protobuf:"bytes,3,rep,name=nics,proto3" json:"nics,omitempty"`
+	// TCP counters (see /proc/net/snmp)
+	Tcp []*Counter `protobuf:"bytes,4,rep,name=tcp,proto3" json:"tcp,omitempty"`
+}
+
+func (x *NodeMetrics) Reset() {
+	*x = NodeMetrics{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_benchmonitor_benchmonitor_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NodeMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeMetrics) ProtoMessage() {}
+
+func (x *NodeMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmonitor_benchmonitor_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeMetrics.ProtoReflect.Descriptor instead.
+func (*NodeMetrics) Descriptor() ([]byte, []int) {
+	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *NodeMetrics) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *NodeMetrics) GetCpus() []*CPUStats {
+	if x != nil {
+		return x.Cpus
+	}
+	return nil
 }
 
-// Deprecated: Use File.ProtoReflect.Descriptor instead.
-func (*File) Descriptor() ([]byte, []int) {
-	return file_benchmonitor_benchmonitor_proto_rawDescGZIP(), []int{3}
+func (x *NodeMetrics) GetNics() []*NICStats {
+	if x != nil {
+		return x.Nics
+	}
+	return nil
 }
 
-func (x *File) GetData() []byte {
+func (x *NodeMetrics) GetTcp() []*Counter {
 	if x != nil {
-		return x.Data
+		return x.Tcp
 	}
 	return nil
 }
@@ -222,33 +1100,161 @@ var file_benchmonitor_benchmonitor_proto_rawDesc = []byte{
 	0x0a, 0x1f, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2f, 0x62,
 	0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74,
 	0x6f, 0x12, 0x0c, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x22,
-	0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x50, 0x0a, 0x0e, 0x43, 0x6f, 0x6c, 0x6c,
-	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75,
-	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x75,
-	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x6f,
-	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0x3b, 0x0a, 0x15, 0x43, 0x6f,
-	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x43,
-	0x6f, 0x6e, 0x66, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f,
-	0x6e, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65,
-	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0x1a, 0x0a, 0x04, 0x46, 0x69, 0x6c, 0x65, 0x12,
-	0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64,
-	0x61, 0x74, 0x61, 0x32, 0xea, 0x01, 0x0a, 0x10, 0x4b, 0x75, 0x62, 0x65, 0x62, 0x65, 0x6e, 0x63,
-	0x68, 0x4d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x12, 0x39, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x53,
-	0x79, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x13, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f,
-	0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x12, 0x2e, 0x62, 0x65,
-	0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x22,
-	0x00, 0x30, 0x01, 0x12, 0x46, 0x0a, 0x0f, 0x53, 0x74, 0x61, 0x72, 0x74, 0x43, 0x6f, 0x6c, 0x6c,
-	0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f,
+	0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x31, 0x0a, 0x05, 0x50, 0x61, 0x72, 0x61,
+	0x6d, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x50, 0x0a, 0x0d, 0x43,
+	0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x12, 0x0a, 0x04,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x2b, 0x0a, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x13, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e,
+	0x50, 0x61, 0x72, 0x61, 0x6d, 0x52, 0x06, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x73, 0x22, 0x8d, 0x01,
+	0x0a, 0x0e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66,
+	0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c,
+	0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64,
+	0x12, 0x3b, 0x0a, 0x0a, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69,
+	0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x43, 0x6f, 0x6e,
+	0x66, 0x52, 0x0a, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x22, 0x71, 0x0a,
+	0x15, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66,
+	0x66, 0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73,
+	0x65, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65,
+	0x22, 0x33, 0x0a, 0x0d, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x66, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x49, 0x64, 0x22, 0xd9, 0x01, 0x0a, 0x10, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0c, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x33,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1d, 0x2e,
+	0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c,
+	0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6c,
+	0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x63,
+	0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x74, 0x61,
+	0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x73, 0x74,
+	0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69,
+	0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d,
+	0x65, 0x22, 0x52, 0x0a, 0x0e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4c,
+	0x69, 0x73, 0x74, 0x12, 0x40, 0x0a, 0x0b, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68,
+	0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x0b, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x72, 0x0a, 0x04, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74,
+	0x61, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66,
+	0x73, 0x65, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x22, 0x33, 0x0a, 0x07, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x43,
+	0x0a, 0x0e, 0x43, 0x6f, 0x6e, 0x6e, 0x74, 0x72, 0x61, 0x63, 0x6b, 0x53, 0x74, 0x61, 0x74, 0x73,
+	0x12, 0x31, 0x0a, 0x08, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f,
+	0x72, 0x2e, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x65, 0x72, 0x52, 0x08, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x65, 0x72, 0x73, 0x22, 0x49, 0x0a, 0x0f, 0x4e, 0x6f, 0x64, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76,
+	0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76,
+	0x61, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0xca,
+	0x01, 0x0a, 0x08, 0x43, 0x50, 0x55, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x63,
+	0x70, 0x75, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x63, 0x70, 0x75, 0x12, 0x12, 0x0a,
+	0x04, 0x75, 0x73, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x75, 0x73, 0x65,
+	0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x69, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x04, 0x6e, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x12, 0x12, 0x0a,
+	0x04, 0x69, 0x64, 0x6c, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x69, 0x64, 0x6c,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x6f, 0x77, 0x61, 0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x06, 0x69, 0x6f, 0x77, 0x61, 0x69, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x72, 0x71,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x69, 0x72, 0x71, 0x12, 0x18, 0x0a, 0x07, 0x73,
+	0x6f, 0x66, 0x74, 0x69, 0x72, 0x71, 0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x52, 0x07, 0x73, 0x6f,
+	0x66, 0x74, 0x69, 0x72, 0x71, 0x12, 0x14, 0x0a, 0x05, 0x73, 0x74, 0x65, 0x61, 0x6c, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x73, 0x74, 0x65, 0x61, 0x6c, 0x22, 0x8a, 0x02, 0x0a, 0x08,
+	0x4e, 0x49, 0x43, 0x53, 0x74, 0x61, 0x74, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x19, 0x0a, 0x08,
+	0x72, 0x78, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07,
+	0x72, 0x78, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x78, 0x5f, 0x70, 0x61,
+	0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x72, 0x78, 0x50,
+	0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x72, 0x78, 0x5f, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x72, 0x78, 0x45, 0x72, 0x72,
+	0x6f, 0x72, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x78, 0x5f, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65,
+	0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x72, 0x78, 0x44, 0x72, 0x6f, 0x70, 0x70,
+	0x65, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x74, 0x78, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x74, 0x78, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1d, 0x0a,
+	0x0a, 0x74, 0x78, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x09, 0x74, 0x78, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x1b, 0x0a, 0x09,
+	0x74, 0x78, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x08, 0x74, 0x78, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x78, 0x5f,
+	0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x74,
+	0x78, 0x44, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x22, 0xac, 0x01, 0x0a, 0x0b, 0x4e, 0x6f, 0x64,
+	0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x2a, 0x0a, 0x04, 0x63, 0x70, 0x75, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69,
+	0x74, 0x6f, 0x72, 0x2e, 0x43, 0x50, 0x55, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x04, 0x63, 0x70,
+	0x75, 0x73, 0x12, 0x2a, 0x0a, 0x04, 0x6e, 0x69, 0x63, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x16, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e,
+	0x4e, 0x49, 0x43, 0x53, 0x74, 0x61, 0x74, 0x73, 0x52, 0x04, 0x6e, 0x69, 0x63, 0x73, 0x12, 0x27,
+	0x0a, 0x03, 0x74, 0x63, 0x70, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x62, 0x65,
+	0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x65, 0x72, 0x52, 0x03, 0x74, 0x63, 0x70, 0x2a, 0x6d, 0x0a, 0x0f, 0x43, 0x6f, 0x6c, 0x6c, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x12, 0x43, 0x4f,
+	0x4c, 0x4c, 0x45, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47,
+	0x10, 0x00, 0x12, 0x16, 0x0a, 0x12, 0x43, 0x4f, 0x4c, 0x4c, 0x45, 0x43, 0x54, 0x49, 0x4f, 0x4e,
+	0x5f, 0x52, 0x55, 0x4e, 0x4e, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x43, 0x4f,
+	0x4c, 0x4c, 0x45, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x44, 0x4f, 0x4e, 0x45, 0x10, 0x02, 0x12,
+	0x15, 0x0a, 0x11, 0x43, 0x4f, 0x4c, 0x4c, 0x45, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x46, 0x41,
+	0x49, 0x4c, 0x45, 0x44, 0x10, 0x03, 0x32, 0xf3, 0x04, 0x0a, 0x10, 0x4b, 0x75, 0x62, 0x65, 0x62,
+	0x65, 0x6e, 0x63, 0x68, 0x4d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x12, 0x39, 0x0a, 0x0a, 0x47,
+	0x65, 0x74, 0x53, 0x79, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x13, 0x2e, 0x62, 0x65, 0x6e, 0x63,
+	0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x12,
+	0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x46, 0x69,
+	0x6c, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x46, 0x0a, 0x0f, 0x53, 0x74, 0x61, 0x72, 0x74, 0x43,
+	0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x2e, 0x62, 0x65, 0x6e, 0x63,
+	0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x66, 0x1a, 0x13, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d,
+	0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x00, 0x12, 0x53,
+	0x0a, 0x14, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x23, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f,
 	0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
-	0x43, 0x6f, 0x6e, 0x66, 0x1a, 0x13, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69,
-	0x74, 0x6f, 0x72, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x00, 0x12, 0x53, 0x0a, 0x14, 0x47,
-	0x65, 0x74, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x75,
-	0x6c, 0x74, 0x73, 0x12, 0x23, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74,
-	0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73,
-	0x75, 0x6c, 0x74, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x1a, 0x12, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68,
-	0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x22, 0x00, 0x30, 0x01,
-	0x42, 0x06, 0x5a, 0x04, 0x2f, 0x61, 0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x1a, 0x12, 0x2e, 0x62, 0x65,
+	0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x22,
+	0x00, 0x30, 0x01, 0x12, 0x4f, 0x0a, 0x0e, 0x53, 0x74, 0x6f, 0x70, 0x43, 0x6f, 0x6c, 0x6c, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e,
+	0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x66, 0x1a, 0x1e, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f,
+	0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x22, 0x00, 0x12, 0x51, 0x0a, 0x10, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x43, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68,
+	0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x65, 0x66, 0x1a, 0x1e, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e,
+	0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x22, 0x00, 0x12, 0x46, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x43,
+	0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x13, 0x2e, 0x62, 0x65, 0x6e,
+	0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a,
+	0x1c, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43,
+	0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4c, 0x69, 0x73, 0x74, 0x22, 0x00, 0x12,
+	0x48, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x6e, 0x74, 0x72, 0x61, 0x63, 0x6b, 0x53,
+	0x74, 0x61, 0x74, 0x73, 0x12, 0x13, 0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69,
+	0x74, 0x6f, 0x72, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x1c, 0x2e, 0x62, 0x65, 0x6e, 0x63,
+	0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x43, 0x6f, 0x6e, 0x6e, 0x74, 0x72, 0x61,
+	0x63, 0x6b, 0x53, 0x74, 0x61, 0x74, 0x73, 0x22, 0x00, 0x12, 0x51, 0x0a, 0x11, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x4e, 0x6f, 0x64, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x12, 0x1d,
+	0x2e, 0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x4e, 0x6f,
+	0x64, 0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x43, 0x6f, 0x6e, 0x66, 0x1a, 0x19, 0x2e,
+	0x62, 0x65, 0x6e, 0x63, 0x68, 0x6d, 0x6f, 0x6e, 0x69, 0x74, 0x6f, 0x72, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x4d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x22, 0x00, 0x30, 0x01, 0x42, 0x06, 0x5a, 0x04,
+	0x2f, 0x61, 0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -263,25 +1269,56 @@ func file_benchmonitor_benchmonitor_proto_rawDescGZIP() []byte {
 	return file_benchmonitor_benchmonitor_proto_rawDescData
 }
 
-var file_benchmonitor_benchmonitor_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_benchmonitor_benchmonitor_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_benchmonitor_benchmonitor_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_benchmonitor_benchmonitor_proto_goTypes = []interface{}{
-	(*Empty)(nil),                 // 0: benchmonitor.Empty
-	(*CollectionConf)(nil),        // 1: benchmonitor.CollectionConf
-	(*CollectionResultsConf)(nil), // 2: benchmonitor.CollectionResultsConf
-	(*File)(nil),                  // 3: benchmonitor.File
+	(CollectionState)(0),          // 0: benchmonitor.CollectionState
+	(*Empty)(nil),                 // 1: benchmonitor.Empty
+	(*Param)(nil),                 // 2: benchmonitor.Param
+	(*CollectorConf)(nil),         // 3: benchmonitor.CollectorConf
+	(*CollectionConf)(nil),        // 4: benchmonitor.CollectionConf
+	(*CollectionResultsConf)(nil), // 5: benchmonitor.CollectionResultsConf
+	(*CollectionRef)(nil),         // 6: benchmonitor.CollectionRef
+	(*CollectionStatus)(nil),      // 7: benchmonitor.CollectionStatus
+	(*CollectionList)(nil),        // 8: benchmonitor.CollectionList
+	(*File)(nil),                  // 9: benchmonitor.File
+	(*Counter)(nil),               // 10: benchmonitor.Counter
+	(*ConntrackStats)(nil),        // 11: benchmonitor.ConntrackStats
+	(*NodeMetricsConf)(nil),       // 12: benchmonitor.NodeMetricsConf
+	(*CPUStats)(nil),              // 13: benchmonitor.CPUStats
+	(*NICStats)(nil),              // 14: benchmonitor.NICStats
+	(*NodeMetrics)(nil),           // 15: benchmonitor.NodeMetrics
 }
 var file_benchmonitor_benchmonitor_proto_depIdxs = []int32{
-	0, // 0: benchmonitor.KubebenchMonitor.GetSysInfo:input_type -> benchmonitor.Empty
-	1, // 1: benchmonitor.KubebenchMonitor.StartCollection:input_type -> benchmonitor.CollectionConf
-	2, // 2: benchmonitor.KubebenchMonitor.GetCollectionResults:input_type -> benchmonitor.CollectionResultsConf
-	3, // 3: benchmonitor.KubebenchMonitor.GetSysInfo:output_type -> benchmonitor.File
-	0, // 4: benchmonitor.KubebenchMonitor.StartCollection:output_type -> benchmonitor.Empty
-	3, // 5: benchmonitor.KubebenchMonitor.GetCollectionResults:output_type -> benchmonitor.File
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2,  // 0: benchmonitor.CollectorConf.params:type_name -> benchmonitor.Param
+	3,  // 1: benchmonitor.CollectionConf.collectors:type_name -> benchmonitor.CollectorConf
+	0,  // 2: benchmonitor.CollectionStatus.state:type_name -> benchmonitor.CollectionState
+	7,  // 3: benchmonitor.CollectionList.collections:type_name -> benchmonitor.CollectionStatus
+	10, // 4: benchmonitor.ConntrackStats.counters:type_name -> benchmonitor.Counter
+	13, // 5: benchmonitor.NodeMetrics.cpus:type_name -> benchmonitor.CPUStats
+	14, // 6: benchmonitor.NodeMetrics.nics:type_name -> benchmonitor.NICStats
+	10, // 7: benchmonitor.NodeMetrics.tcp:type_name -> benchmonitor.Counter
+	1,  // 8: benchmonitor.KubebenchMonitor.GetSysInfo:input_type -> benchmonitor.Empty
+	4,  // 9: benchmonitor.KubebenchMonitor.StartCollection:input_type -> benchmonitor.CollectionConf
+	5,  // 10: benchmonitor.KubebenchMonitor.GetCollectionResults:input_type -> benchmonitor.CollectionResultsConf
+	6,  // 11: benchmonitor.KubebenchMonitor.StopCollection:input_type -> benchmonitor.CollectionRef
+	6,  // 12: benchmonitor.KubebenchMonitor.CancelCollection:input_type -> benchmonitor.CollectionRef
+	1,  // 13: benchmonitor.KubebenchMonitor.ListCollections:input_type -> benchmonitor.Empty
+	1,  // 14: benchmonitor.KubebenchMonitor.GetConntrackStats:input_type -> benchmonitor.Empty
+	12, // 15: benchmonitor.KubebenchMonitor.StreamNodeMetrics:input_type -> benchmonitor.NodeMetricsConf
+	9,  // 16: benchmonitor.KubebenchMonitor.GetSysInfo:output_type -> benchmonitor.File
+	1,  // 17: benchmonitor.KubebenchMonitor.StartCollection:output_type -> benchmonitor.Empty
+	9,  // 18: benchmonitor.KubebenchMonitor.GetCollectionResults:output_type -> benchmonitor.File
+	7,  // 19: benchmonitor.KubebenchMonitor.StopCollection:output_type -> benchmonitor.CollectionStatus
+	7,  // 20: benchmonitor.KubebenchMonitor.CancelCollection:output_type -> benchmonitor.CollectionStatus
+	8,  // 21: benchmonitor.KubebenchMonitor.ListCollections:output_type -> benchmonitor.CollectionList
+	11, // 22: benchmonitor.KubebenchMonitor.GetConntrackStats:output_type -> benchmonitor.ConntrackStats
+	15, // 23: benchmonitor.KubebenchMonitor.StreamNodeMetrics:output_type -> benchmonitor.NodeMetrics
+	16, // [16:24] is the sub-list for method output_type
+	8,  // [8:16] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_benchmonitor_benchmonitor_proto_init() }
@@ -303,7 +1340,7 @@ func file_benchmonitor_benchmonitor_proto_init() {
 			}
 		}
 		file_benchmonitor_benchmonitor_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CollectionConf); i {
+			switch v := v.(*Param); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -315,7 +1352,7 @@ func file_benchmonitor_benchmonitor_proto_init() {
 			}
 		}
 		file_benchmonitor_benchmonitor_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CollectionResultsConf); i {
+			switch v := v.(*CollectorConf); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -327,6 +1364,66 @@ func file_benchmonitor_benchmonitor_proto_init() {
 			}
 		}
 		file_benchmonitor_benchmonitor_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionResultsConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionRef); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CollectionList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*File); i {
 			case 0:
 				return &v.state
@@ -338,19 +1435,92 @@ func file_benchmonitor_benchmonitor_proto_init() {
 				return nil
 			}
 		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Counter); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConntrackStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NodeMetricsConf); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CPUStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NICStats); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_benchmonitor_benchmonitor_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NodeMetrics); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_benchmonitor_benchmonitor_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      1,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_benchmonitor_benchmonitor_proto_goTypes,
 		DependencyIndexes: file_benchmonitor_benchmonitor_proto_depIdxs,
+		EnumInfos:         file_benchmonitor_benchmonitor_proto_enumTypes,
 		MessageInfos:      file_benchmonitor_benchmonitor_proto_msgTypes,
 	}.Build()
 	File_benchmonitor_benchmonitor_proto = out.File
@@ -374,6 +1544,15 @@ type KubebenchMonitorClient interface {
 	GetSysInfo(ctx context.Context, in *Empty, opts ...grpc.CallOption) (KubebenchMonitor_GetSysInfoClient, error)
 	StartCollection(ctx context.Context, in *CollectionConf, opts ...grpc.CallOption) (*Empty, error)
 	GetCollectionResults(ctx context.Context, in *CollectionResultsConf, opts ...grpc.CallOption) (KubebenchMonitor_GetCollectionResultsClient, error)
+	// StopCollection stops the collectors of a running collection, and
+	// returns when its results are available
+	StopCollection(ctx context.Context, in *CollectionRef, opts ...grpc.CallOption) (*CollectionStatus, error)
+	// CancelCollection stops the collectors of a collection, and discards its
+	// results
+	CancelCollection(ctx context.Context, in *CollectionRef, opts ...grpc.CallOption) (*CollectionStatus, error)
+	ListCollections(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CollectionList, error)
+	GetConntrackStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ConntrackStats, error)
+	StreamNodeMetrics(ctx context.Context, in *NodeMetricsConf, opts ...grpc.CallOption) (KubebenchMonitor_StreamNodeMetricsClient, error)
 }
 
 type kubebenchMonitorClient struct {
@@ -457,11 +1636,88 @@ func (x *kubebenchMonitorGetCollectionResultsClient) Recv() (*File, error) {
 	return m, nil
 }
 
+func (c *kubebenchMonitorClient) StopCollection(ctx context.Context, in *CollectionRef, opts ...grpc.CallOption) (*CollectionStatus, error) {
+	out := new(CollectionStatus)
+	err := c.cc.Invoke(ctx, "/benchmonitor.KubebenchMonitor/StopCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kubebenchMonitorClient) CancelCollection(ctx context.Context, in *CollectionRef, opts ...grpc.CallOption) (*CollectionStatus, error) {
+	out := new(CollectionStatus)
+	err := c.cc.Invoke(ctx, "/benchmonitor.KubebenchMonitor/CancelCollection", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kubebenchMonitorClient) ListCollections(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CollectionList, error) {
+	out := new(CollectionList)
+	err := c.cc.Invoke(ctx, "/benchmonitor.KubebenchMonitor/ListCollections", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kubebenchMonitorClient) GetConntrackStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ConntrackStats, error) {
+	out := new(ConntrackStats)
+	err := c.cc.Invoke(ctx, "/benchmonitor.KubebenchMonitor/GetConntrackStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kubebenchMonitorClient) StreamNodeMetrics(ctx context.Context, in *NodeMetricsConf, opts ...grpc.CallOption) (KubebenchMonitor_StreamNodeMetricsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_KubebenchMonitor_serviceDesc.Streams[2], "/benchmonitor.KubebenchMonitor/StreamNodeMetrics", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kubebenchMonitorStreamNodeMetricsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type KubebenchMonitor_StreamNodeMetricsClient interface {
+	Recv() (*NodeMetrics, error)
+	grpc.ClientStream
+}
+
+type kubebenchMonitorStreamNodeMetricsClient struct {
+	grpc.ClientStream
+}
+
+func (x *kubebenchMonitorStreamNodeMetricsClient) Recv() (*NodeMetrics, error) {
+	m := new(NodeMetrics)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // KubebenchMonitorServer is the server API for KubebenchMonitor service.
 type KubebenchMonitorServer interface {
 	GetSysInfo(*Empty, KubebenchMonitor_GetSysInfoServer) error
 	StartCollection(context.Context, *CollectionConf) (*Empty, error)
 	GetCollectionResults(*CollectionResultsConf, KubebenchMonitor_GetCollectionResultsServer) error
+	// StopCollection stops the collectors of a running collection, and
+	// returns when its results are available
+	StopCollection(context.Context, *CollectionRef) (*CollectionStatus, error)
+	// CancelCollection stops the collectors of a collection, and discards its
+	// results
+	CancelCollection(context.Context, *CollectionRef) (*CollectionStatus, error)
+	ListCollections(context.Context, *Empty) (*CollectionList, error)
+	GetConntrackStats(context.Context, *Empty) (*ConntrackStats, error)
+	StreamNodeMetrics(*NodeMetricsConf, KubebenchMonitor_StreamNodeMetricsServer) error
 }
 
 // UnimplementedKubebenchMonitorServer can be embedded to have forward compatible implementations.
@@ -477,6 +1733,21 @@ func (*UnimplementedKubebenchMonitorServer) StartCollection(context.Context, *Co
 func (*UnimplementedKubebenchMonitorServer) GetCollectionResults(*CollectionResultsConf, KubebenchMonitor_GetCollectionResultsServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetCollectionResults not implemented")
 }
+func (*UnimplementedKubebenchMonitorServer) StopCollection(context.Context, *CollectionRef) (*CollectionStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopCollection not implemented")
+}
+func (*UnimplementedKubebenchMonitorServer) CancelCollection(context.Context, *CollectionRef) (*CollectionStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelCollection not implemented")
+}
+func (*UnimplementedKubebenchMonitorServer) ListCollections(context.Context, *Empty) (*CollectionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCollections not implemented")
+}
+func (*UnimplementedKubebenchMonitorServer) GetConntrackStats(context.Context, *Empty) (*ConntrackStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConntrackStats not implemented")
+}
+func (*UnimplementedKubebenchMonitorServer) StreamNodeMetrics(*NodeMetricsConf, KubebenchMonitor_StreamNodeMetricsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamNodeMetrics not implemented")
+}
 
 func RegisterKubebenchMonitorServer(s *grpc.Server, srv KubebenchMonitorServer) {
 	s.RegisterService(&_KubebenchMonitor_serviceDesc, srv)
@@ -542,6 +1813,99 @@ func (x *kubebenchMonitorGetCollectionResultsServer) Send(m *File) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _KubebenchMonitor_StopCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KubebenchMonitorServer).StopCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/benchmonitor.KubebenchMonitor/StopCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KubebenchMonitorServer).StopCollection(ctx, req.(*CollectionRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KubebenchMonitor_CancelCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectionRef)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KubebenchMonitorServer).CancelCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/benchmonitor.KubebenchMonitor/CancelCollection",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KubebenchMonitorServer).CancelCollection(ctx, req.(*CollectionRef))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KubebenchMonitor_ListCollections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KubebenchMonitorServer).ListCollections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/benchmonitor.KubebenchMonitor/ListCollections",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KubebenchMonitorServer).ListCollections(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KubebenchMonitor_GetConntrackStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KubebenchMonitorServer).GetConntrackStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/benchmonitor.KubebenchMonitor/GetConntrackStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KubebenchMonitorServer).GetConntrackStats(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KubebenchMonitor_StreamNodeMetrics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(NodeMetricsConf)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KubebenchMonitorServer).StreamNodeMetrics(m, &kubebenchMonitorStreamNodeMetricsServer{stream})
+}
+
+type KubebenchMonitor_StreamNodeMetricsServer interface {
+	Send(*NodeMetrics) error
+	grpc.ServerStream
+}
+
+type kubebenchMonitorStreamNodeMetricsServer struct {
+	grpc.ServerStream
+}
+
+func (x *kubebenchMonitorStreamNodeMetricsServer) Send(m *NodeMetrics) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _KubebenchMonitor_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "benchmonitor.KubebenchMonitor",
 	HandlerType: (*KubebenchMonitorServer)(nil),
@@ -550,6 +1914,22 @@ var _KubebenchMonitor_serviceDesc = grpc.ServiceDesc{
 			MethodName: "StartCollection",
 			Handler:    _KubebenchMonitor_StartCollection_Handler,
 		},
+		{
+			MethodName: "StopCollection",
+			Handler:    _KubebenchMonitor_StopCollection_Handler,
+		},
+		{
+			MethodName: "CancelCollection",
+			Handler:    _KubebenchMonitor_CancelCollection_Handler,
+		},
+		{
+			MethodName: "ListCollections",
+			Handler:    _KubebenchMonitor_ListCollections_Handler,
+		},
+		{
+			MethodName: "GetConntrackStats",
+			Handler:    _KubebenchMonitor_GetConntrackStats_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -562,6 +1942,11 @@ var _KubebenchMonitor_serviceDesc = grpc.ServiceDesc{
 			Handler:       _KubebenchMonitor_GetCollectionResults_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "StreamNodeMetrics",
+			Handler:       _KubebenchMonitor_StreamNodeMetrics_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "benchmonitor/benchmonitor.proto",
 }
@@ -4,14 +4,13 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net"
-	"os"
 	"os/exec"
-	"sync"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/cilium/kubenetbench/benchmonitor/api"
 )
@@ -22,99 +21,61 @@ var (
 
 type monitorSrv struct {
 	pb.UnimplementedKubebenchMonitorServer
-	pendingCmds sync.Map
-}
-
-type ErrCmdInProgress struct{}
-
-func (e *ErrCmdInProgress) Error() string {
-	return fmt.Sprintf("command still running")
+	collections *collectionManager
 }
 
 func (srv *monitorSrv) StartCollection(
 	ctx context.Context,
 	arg *pb.CollectionConf,
 ) (*pb.Empty, error) {
-
-	ret := &pb.Empty{}
-	cid := arg.CollectionId
-	_, loaded := srv.pendingCmds.LoadOrStore(cid, &ErrCmdInProgress{})
-
-	if loaded {
-		return ret, fmt.Errorf(fmt.Sprintf("id %s already exists", cid))
+	duration, err := parseCollectionDuration(arg.Duration)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-
-	go func() {
-		t := arg.Duration
-		cmd := exec.Command("/scripts/perf-record.sh", t, cid)
-		err := cmd.Run()
-		srv.pendingCmds.Store(cid, err)
-	}()
-
-	return ret, nil
+	err = srv.collections.start(arg.CollectionId, duration, arg.Collectors)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
 }
 
-type FileSender interface {
-	Send(*pb.File) error
+func (srv *monitorSrv) StopCollection(
+	ctx context.Context,
+	arg *pb.CollectionRef,
+) (*pb.CollectionStatus, error) {
+	return srv.collections.stop(ctx, arg.CollectionId)
 }
 
-func copyFileToStream(fname string, stream FileSender) error {
-
-	f, err := os.Open(fname)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	buff := make([]byte, 4096)
-	for {
-		n, err := f.Read(buff)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return fmt.Errorf("io error: %w", err)
-		}
-
-		err = stream.Send(&pb.File{
-			Data: buff[:n],
-		})
-
-		if err != nil {
-			return fmt.Errorf("io error: %w", err)
-		}
-	}
+func (srv *monitorSrv) CancelCollection(
+	ctx context.Context,
+	arg *pb.CollectionRef,
+) (*pb.CollectionStatus, error) {
+	return srv.collections.cancel(ctx, arg.CollectionId)
+}
 
-	return nil
+func (srv *monitorSrv) ListCollections(
+	ctx context.Context,
+	_ *pb.Empty,
+) (*pb.CollectionList, error) {
+	return &pb.CollectionList{Collections: srv.collections.list()}, nil
 }
 
 func (srv *monitorSrv) GetCollectionResults(
 	arg *pb.CollectionResultsConf,
 	stream pb.KubebenchMonitor_GetCollectionResultsServer,
 ) error {
-	cid := arg.CollectionId
-	cmd_err, ok := srv.pendingCmds.Load(cid)
-	if !ok {
-		return fmt.Errorf(fmt.Sprintf("invalid collection id %s", cid))
-	}
-
-	switch cmd_err.(type) {
-	case ErrCmdInProgress:
-		return fmt.Errorf("command still running: %w", cmd_err)
-	}
-
-	srv.pendingCmds.Delete(cid)
-	if cmd_err != nil {
-		return fmt.Errorf("command resulted in error: %w", cmd_err)
+	chunk, err := chunkSize(arg.ChunkSize)
+	if err != nil {
+		return err
 	}
-
-	cmd := exec.Command("/scripts/perf-collect.sh", cid)
-	collect_err := cmd.Run()
-	if collect_err != nil {
-		return fmt.Errorf("collection (%s) command resulted in error: %w", cmd, collect_err)
+	fname, release, err := srv.collections.results(arg.CollectionId)
+	if err != nil {
+		return err
 	}
 
-	fname := fmt.Sprintf("/tmp/%s-perf.data.tar.bz2", cid)
-	return copyFileToStream(fname, stream)
+	err = copyFileToStream(fname, arg.Offset, chunk, stream)
+	release(err == nil)
+	return err
 }
 
 func (*monitorSrv) GetSysInfo(
@@ -127,23 +88,22 @@ func (*monitorSrv) GetSysInfo(
 	if err != nil {
 		return fmt.Errorf("io error: %w", err)
 	}
-	err = stream.Send(&pb.File{
-		Data: data,
-	})
-	if err != nil {
-		return fmt.Errorf("io error: %w", err)
-	}
-
-	return nil
+	return copyDataToStream("sysinfo", data, stream)
 }
 
-func newMonitorSrv() *monitorSrv {
-	return &monitorSrv{}
+func newMonitorSrv(runner cmdRunner) *monitorSrv {
+	return &monitorSrv{
+		collections: newCollectionManager(runner),
+	}
 }
 
 func main() {
 	log.Println("starting monitor server")
 	flag.Parse()
+	if err := validateChunkSizeFlag(); err != nil {
+		log.Fatal(err)
+	}
+	logCollectors()
 
 	laddr := fmt.Sprintf(":%d", *srvPort)
 	listen, err := net.Listen("tcp", laddr)
@@ -152,6 +112,6 @@ func main() {
 	}
 
 	grpcSrv := grpc.NewServer()
-	pb.RegisterKubebenchMonitorServer(grpcSrv, newMonitorSrv())
+	pb.RegisterKubebenchMonitorServer(grpcSrv, newMonitorSrv(execRunner{}))
 	grpcSrv.Serve(listen)
 }
@@ -0,0 +1,710 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// fakeRunner runs fake commands: run writes the command line to the output,
+// and waits until ctx is done (unless the command fails, see fail, or ctx is
+// never done, e.g., for perf archive), and output returns the output of
+// outputs for the command name.
+type fakeRunner struct {
+	// command names that fail
+	fail map[string]bool
+	// outputs of output(), by command name
+	outputs map[string][]string
+
+	mu   sync.Mutex
+	cmds []string
+	// number of output() calls, by command name
+	calls map[string]int
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{
+		fail:    make(map[string]bool),
+		outputs: make(map[string][]string),
+		calls:   make(map[string]int),
+	}
+}
+
+func (r *fakeRunner) run(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+	cmd := strings.Join(append([]string{filepath.Base(name)}, args...), " ")
+	r.mu.Lock()
+	r.cmds = append(r.cmds, cmd)
+	r.mu.Unlock()
+
+	fmt.Fprintln(out, cmd)
+	if r.fail[filepath.Base(name)] {
+		return fmt.Errorf("%s: exit status 1", cmd)
+	}
+	if done := ctx.Done(); done != nil {
+		<-done
+	}
+	return nil
+}
+
+func (r *fakeRunner) output(name string, args ...string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	outs, ok := r.outputs[name]
+	if !ok || r.fail[name] {
+		return nil, fmt.Errorf("%s: exit status 1", name)
+	}
+	i := r.calls[name]
+	r.calls[name]++
+	if i >= len(outs) {
+		i = len(outs) - 1
+	}
+	return []byte(outs[i]), nil
+}
+
+// collectorFunc is a collector implemented by a function
+type collectorFunc func(ctx context.Context, r cmdRunner, dir string) error
+
+func (f collectorFunc) collect(ctx context.Context, r cmdRunner, dir string) error {
+	return f(ctx, r, dir)
+}
+
+// newTestMonitor starts a monitor server (using runner for the commands of
+// collectors) on a bufconn listener, and returns a client for it
+func newTestMonitor(t *testing.T, runner cmdRunner) (pb.KubebenchMonitorClient, *monitorSrv) {
+	dir, err := ioutil.TempDir("", "knb-monitor-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	origDir, origRetention := collectionsDir, sentResultsRetention
+	collectionsDir, sentResultsRetention = dir, 0
+
+	srv := newMonitorSrv(runner)
+	listener := bufconn.Listen(1 << 20)
+	grpcSrv := grpc.NewServer()
+	pb.RegisterKubebenchMonitorServer(grpcSrv, srv)
+	go grpcSrv.Serve(listener)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcSrv.Stop()
+		collectionsDir, sentResultsRetention = origDir, origRetention
+		os.RemoveAll(dir)
+	})
+	return pb.NewKubebenchMonitorClient(conn), srv
+}
+
+func checkCode(t *testing.T, what string, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("%s: got error %v while expected code %s", what, err, code)
+	}
+}
+
+// getResults retrieves the results of a collection, and returns the files of
+// the archive
+func getResults(ctx context.Context, cli pb.KubebenchMonitorClient, id string) (map[string][]byte, error) {
+	stream, err := cli.GetCollectionResults(ctx, &pb.CollectionResultsConf{CollectionId: id})
+	if err != nil {
+		return nil, err
+	}
+	var buff bytes.Buffer
+	for {
+		f, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		buff.Write(f.Data)
+	}
+
+	gr, err := gzip.NewReader(&buff)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data
+	}
+}
+
+func collectionState(t *testing.T, cli pb.KubebenchMonitorClient, id string) pb.CollectionState {
+	t.Helper()
+	list, err := cli.ListCollections(context.Background(), &pb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range list.Collections {
+		if c.CollectionId == id {
+			return c.State
+		}
+	}
+	t.Fatalf("collection %s not found in %v", id, list.Collections)
+	return 0
+}
+
+func TestCollectionLifecycle(t *testing.T) {
+	runner := newFakeRunner()
+	runner.outputs["ethtool"] = []string{
+		"NIC statistics:\n     rx_packets: 10\n     rx_missed: 1\n",
+		"NIC statistics:\n     rx_packets: 110\n     rx_missed: 3\n",
+	}
+	cli, _ := newTestMonitor(t, runner)
+	ctx := context.Background()
+
+	_, err := cli.StartCollection(ctx, &pb.CollectionConf{
+		CollectionId: "c1",
+		Collectors: []*pb.CollectorConf{
+			{Name: "perf-stat", Params: []*pb.Param{{Name: "events", Value: "cycles"}}},
+			{Name: "ethtool", Params: []*pb.Param{{Name: "devices", Value: "eth0"}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the results of a running collection are not available, and retrieving
+	// them does not affect the collection
+	for i := 0; i < 2; i++ {
+		_, err = getResults(ctx, cli, "c1")
+		checkCode(t, "results of running collection", err, codes.FailedPrecondition)
+	}
+	if st := collectionState(t, cli, "c1"); st != pb.CollectionState_COLLECTION_PENDING && st != pb.CollectionState_COLLECTION_RUNNING {
+		t.Errorf("unexpected state of started collection: %s", st)
+	}
+
+	st, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "c1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != pb.CollectionState_COLLECTION_DONE || st.Error != "" || st.StartTime == 0 || st.EndTime < st.StartTime {
+		t.Errorf("unexpected status of stopped collection: %v", st)
+	}
+	if strings.Join(st.Collectors, ",") != "perf-stat,ethtool" {
+		t.Errorf("unexpected collectors: %v", st.Collectors)
+	}
+
+	files, err := getResults(ctx, cli, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(files["perf-stat/perf-stat.log"]); out != "perf stat -a -o perf-stat.txt -e cycles\n" {
+		t.Errorf("unexpected perf-stat command: %q", out)
+	}
+
+	m := &collectionManifest{}
+	if err := json.Unmarshal(files[collectionManifestName], m); err != nil {
+		t.Fatal(err)
+	}
+	if m.CollectionID != "c1" || len(m.Collectors) != 2 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	if c := m.Collectors[1]; c.Name != "ethtool" || c.Params["devices"] != "eth0" || len(c.Files) != 1 || c.Error != "" {
+		t.Errorf("unexpected ethtool manifest: %+v", c)
+	}
+
+	snaps := &counterSnapshots{}
+	if err := json.Unmarshal(files["ethtool/ethtool.json"], snaps); err != nil {
+		t.Fatal(err)
+	}
+	if d := snaps.Delta["eth0"]; d["rx_packets"] != 100 || d["rx_missed"] != 2 {
+		t.Errorf("unexpected ethtool delta: %v", d)
+	}
+
+	// results can be retrieved once
+	_, err = getResults(ctx, cli, "c1")
+	checkCode(t, "results of retrieved collection", err, codes.NotFound)
+	if list, err := cli.ListCollections(ctx, &pb.Empty{}); err != nil || len(list.Collections) != 0 {
+		t.Errorf("unexpected collections: %v (err: %v)", list, err)
+	}
+	if _, err := os.Stat(collectionArchive("c1")); !os.IsNotExist(err) {
+		t.Errorf("archive of retrieved collection exists (err: %v)", err)
+	}
+}
+
+func TestCollectionDuration(t *testing.T) {
+	cli, _ := newTestMonitor(t, newFakeRunner())
+	ctx := context.Background()
+
+	_, err := cli.StartCollection(ctx, &pb.CollectionConf{
+		CollectionId: "c1",
+		Duration:     "50ms",
+		Collectors:   []*pb.CollectorConf{{Name: "bcc", Params: []*pb.Param{{Name: "tool", Value: "tcpretrans"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for collectionState(t, cli, "c1") != pb.CollectionState_COLLECTION_DONE {
+		if time.Now().After(deadline) {
+			t.Fatalf("collection not done after its duration")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// stopping a done collection is a no-op
+	st, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "c1"})
+	if err != nil || st.State != pb.CollectionState_COLLECTION_DONE {
+		t.Errorf("unexpected stop of done collection: %v (err: %v)", st, err)
+	}
+	files, err := getResults(ctx, cli, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(files["bcc/tcpretrans.txt"]); out != "tcpretrans\n" {
+		t.Errorf("unexpected bcc output: %q", out)
+	}
+}
+
+func TestCollectionCancel(t *testing.T) {
+	cli, _ := newTestMonitor(t, newFakeRunner())
+	ctx := context.Background()
+
+	for _, id := range []string{"running", "done"} {
+		_, err := cli.StartCollection(ctx, &pb.CollectionConf{CollectionId: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "done"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"running", "done"} {
+		st, err := cli.CancelCollection(ctx, &pb.CollectionRef{CollectionId: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.State != pb.CollectionState_COLLECTION_FAILED || st.Error != errCollectionCancelled.Error() {
+			t.Errorf("%s: unexpected status of cancelled collection: %v", id, st)
+		}
+		if _, err := os.Stat(collectionArchive(id)); !os.IsNotExist(err) {
+			t.Errorf("%s: archive of cancelled collection exists (err: %v)", id, err)
+		}
+
+		// the failure is reported once
+		_, err = getResults(ctx, cli, id)
+		checkCode(t, "results of cancelled collection", err, codes.Aborted)
+		_, err = getResults(ctx, cli, id)
+		checkCode(t, "results of reported collection", err, codes.NotFound)
+	}
+}
+
+func TestCollectionErrors(t *testing.T) {
+	runner := newFakeRunner()
+	runner.fail["bpftrace"] = true
+	cli, _ := newTestMonitor(t, runner)
+	ctx := context.Background()
+
+	for _, conf := range []*pb.CollectionConf{
+		{CollectionId: ""},
+		{CollectionId: "c", Duration: "foo"},
+		{CollectionId: "c", Collectors: []*pb.CollectorConf{{Name: "foo"}}},
+		{CollectionId: "c", Collectors: []*pb.CollectorConf{{Name: "ss", Params: []*pb.Param{{Name: "foo"}}}}},
+		{CollectionId: "c", Collectors: []*pb.CollectorConf{{Name: "ss", Params: []*pb.Param{{Name: "interval", Value: "0s"}}}}},
+		{CollectionId: "c", Collectors: []*pb.CollectorConf{{Name: "bpftrace"}}},
+		{CollectionId: "c", Collectors: []*pb.CollectorConf{{Name: "softirqs"}, {Name: "softirqs"}}},
+	} {
+		_, err := cli.StartCollection(ctx, conf)
+		checkCode(t, fmt.Sprintf("start %v", conf), err, codes.InvalidArgument)
+	}
+
+	for _, call := range []func() error{
+		func() error { _, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "c"}); return err },
+		func() error { _, err := cli.CancelCollection(ctx, &pb.CollectionRef{CollectionId: "c"}); return err },
+		func() error { _, err := getResults(ctx, cli, "c"); return err },
+	} {
+		checkCode(t, "unknown collection", call(), codes.NotFound)
+	}
+
+	// collector failures are recorded in the manifest
+	_, err := cli.StartCollection(ctx, &pb.CollectionConf{
+		CollectionId: "c",
+		Collectors: []*pb.CollectorConf{
+			{Name: "bpftrace", Params: []*pb.Param{{Name: "script", Value: "tcp.bt"}}},
+			{Name: "ss"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "c"})
+	if err != nil || st.State != pb.CollectionState_COLLECTION_DONE {
+		t.Fatalf("unexpected status: %v (err: %v)", st, err)
+	}
+	// ... and in the status
+	for _, s := range []string{"bpftrace: ", "ss: ", "exit status 1"} {
+		if !strings.Contains(st.Error, s) {
+			t.Errorf("status error %q does not contain %q", st.Error, s)
+		}
+	}
+	list, err := cli.ListCollections(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Collections) != 1 || list.Collections[0].Error != st.Error {
+		t.Errorf("unexpected collections: %v", list.Collections)
+	}
+	files, err := getResults(ctx, cli, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &collectionManifest{}
+	if err := json.Unmarshal(files[collectionManifestName], m); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range m.Collectors {
+		if c.Error == "" {
+			t.Errorf("%s: expected error in manifest", c.Name)
+		}
+	}
+}
+
+func TestCollectionConcurrency(t *testing.T) {
+	cli, srv := newTestMonitor(t, newFakeRunner())
+	ctx := context.Background()
+
+	// only one of the concurrent starts with the same id succeeds
+	const n = 8
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := cli.StartCollection(ctx, &pb.CollectionConf{CollectionId: "c1"})
+			errs <- err
+		}()
+	}
+	started := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			started++
+		} else {
+			checkCode(t, "concurrent start", err, codes.AlreadyExists)
+		}
+	}
+	if started != 1 {
+		t.Fatalf("%d concurrent starts succeeded", started)
+	}
+
+	// concurrent stops all wait until the collection is done
+	sts := make(chan *pb.CollectionStatus, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			st, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "c1"})
+			if err != nil {
+				t.Error(err)
+			}
+			sts <- st
+		}()
+	}
+	for i := 0; i < n; i++ {
+		if st := <-sts; st != nil && st.State != pb.CollectionState_COLLECTION_DONE {
+			t.Errorf("unexpected status after stop: %v", st)
+		}
+	}
+
+	// results are retrieved by one caller at a time, and are kept until they
+	// are sent
+	fname, release, err := srv.collections.results("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = srv.collections.results("c1")
+	checkCode(t, "concurrent results", err, codes.Unavailable)
+	release(false)
+	if _, err := os.Stat(fname); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getResults(ctx, cli, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = srv.collections.results("c1")
+	checkCode(t, "results after sent", err, codes.NotFound)
+}
+
+func TestStopTimeout(t *testing.T) {
+	cli, _ := newTestMonitor(t, newFakeRunner())
+	ctx := context.Background()
+
+	// the collection stops after the (cancelled) stop call returns
+	blocked := make(chan struct{})
+	collectorRegistry["test-block"] = collectorDesc{
+		params: map[string]string{},
+		new: func(map[string]string) (collector, error) {
+			return collectorFunc(func(ctx context.Context, r cmdRunner, dir string) error {
+				<-ctx.Done()
+				<-blocked
+				return nil
+			}), nil
+		},
+	}
+	t.Cleanup(func() { delete(collectorRegistry, "test-block") })
+
+	_, err := cli.StartCollection(ctx, &pb.CollectionConf{CollectionId: "c1", Collectors: []*pb.CollectorConf{{Name: "test-block"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = cli.StopCollection(tctx, &pb.CollectionRef{CollectionId: "c1"})
+	checkCode(t, "stop timeout", err, codes.DeadlineExceeded)
+	if st := collectionState(t, cli, "c1"); st != pb.CollectionState_COLLECTION_RUNNING {
+		t.Errorf("unexpected state of stopping collection: %s", st)
+	}
+
+	close(blocked)
+	st, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "c1"})
+	if err != nil || st.State != pb.CollectionState_COLLECTION_DONE {
+		t.Errorf("unexpected status: %v (err: %v)", st, err)
+	}
+}
+
+// fileChunks is a FileSender that keeps the chunks it is sent
+type fileChunks []*pb.File
+
+func (fc *fileChunks) Send(f *pb.File) error {
+	*fc = append(*fc, f)
+	return nil
+}
+
+func TestSendFile(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789abcdef", 3))
+	sum := sha256.Sum256(data)
+	for _, tc := range []struct {
+		offset  int64
+		offsets []int64
+	}{
+		{0, []int64{0, 16, 32}},
+		{20, []int64{20, 36}},
+		{48, []int64{48}},
+	} {
+		var chunks fileChunks
+		if err := sendFile(&chunks, "f", bytes.NewReader(data), int64(len(data)), tc.offset, 16); err != nil {
+			t.Fatal(err)
+		}
+		if len(chunks) != len(tc.offsets) {
+			t.Fatalf("offset %d: got %d chunks while expected %d", tc.offset, len(chunks), len(tc.offsets))
+		}
+		var got []byte
+		for i, c := range chunks {
+			if c.Offset != tc.offsets[i] || c.Name != "f" || c.Size != 48 || c.Sha256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("offset %d: unexpected chunk %d: %v", tc.offset, i, c)
+			}
+			got = append(got, c.Data...)
+		}
+		if !bytes.Equal(got, data[tc.offset:]) {
+			t.Errorf("offset %d: unexpected data: %q", tc.offset, got)
+		}
+	}
+
+	// empty files are sent as a single empty chunk
+	var chunks fileChunks
+	if err := sendFile(&chunks, "empty", bytes.NewReader(nil), 0, 0, 16); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || len(chunks[0].Data) != 0 || chunks[0].Size != 0 {
+		t.Errorf("unexpected chunks for empty file: %v", chunks)
+	}
+
+	err := sendFile(&chunks, "f", bytes.NewReader(data), int64(len(data)), 49, 16)
+	checkCode(t, "offset after end", err, codes.OutOfRange)
+
+	err = sendFile(&chunks, "f", bytes.NewReader(data), int64(len(data)), 0, 0)
+	checkCode(t, "zero chunk size", err, codes.InvalidArgument)
+}
+
+func TestValidateChunkSizeFlag(t *testing.T) {
+	saved := *defaultChunkSize
+	defer func() { *defaultChunkSize = saved }()
+
+	for _, tc := range []struct {
+		size int
+		ok   bool
+	}{{1 << 20, true}, {maxChunkSize, true}, {0, false}, {-1, false}, {maxChunkSize + 1, false}} {
+		*defaultChunkSize = tc.size
+		if err := validateChunkSizeFlag(); (err == nil) != tc.ok {
+			t.Errorf("chunk size %d: unexpected result: %v", tc.size, err)
+		}
+	}
+}
+
+// recvChunks retrieves (at most max, if not 0) chunks of the results of a
+// collection, retrying while the results are being retrieved by a previous
+// (e.g., broken) transfer
+func recvChunks(t *testing.T, cli pb.KubebenchMonitorClient, conf *pb.CollectionResultsConf, max int) ([]*pb.File, error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stream, err := cli.GetCollectionResults(ctx, conf)
+		if err != nil {
+			return nil, err
+		}
+		var ret []*pb.File
+		for max == 0 || len(ret) < max {
+			f, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if status.Code(err) == codes.Unavailable && len(ret) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+				stream = nil
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, f)
+		}
+		if stream != nil {
+			return ret, nil
+		}
+	}
+}
+
+func TestResultsResume(t *testing.T) {
+	cli, _ := newTestMonitor(t, newFakeRunner())
+	sentResultsRetention = time.Hour
+	ctx := context.Background()
+
+	_, err := cli.StartCollection(ctx, &pb.CollectionConf{
+		CollectionId: "c1",
+		Collectors:   []*pb.CollectorConf{{Name: "perf-stat"}, {Name: "bcc", Params: []*pb.Param{{Name: "tool", Value: "tcplife"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.StopCollection(ctx, &pb.CollectionRef{CollectionId: "c1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = recvChunks(t, cli, &pb.CollectionResultsConf{CollectionId: "c1", ChunkSize: maxChunkSize + 1}, 0)
+	checkCode(t, "invalid chunk size", err, codes.InvalidArgument)
+
+	// the first transfer breaks after 3 chunks
+	chunks, err := recvChunks(t, cli, &pb.CollectionResultsConf{CollectionId: "c1", ChunkSize: 16}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	for i, c := range chunks {
+		if c.Offset != int64(16*i) || len(c.Data) != 16 || c.Name != "c1-collection.tar.gz" {
+			t.Fatalf("unexpected chunk %d: %v", i, c)
+		}
+		data = append(data, c.Data...)
+	}
+
+	// ... and is resumed
+	chunks, err = recvChunks(t, cli, &pb.CollectionResultsConf{CollectionId: "c1", Offset: 48, ChunkSize: 16}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks[0].Offset != 48 {
+		t.Fatalf("unexpected offset of resumed transfer: %d", chunks[0].Offset)
+	}
+	for _, c := range chunks {
+		data = append(data, c.Data...)
+	}
+	last := chunks[len(chunks)-1]
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != last.Size || hex.EncodeToString(sum[:]) != last.Sha256 {
+		t.Fatalf("invalid data (size: %d, expected: %d)", len(data), last.Size)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(gr); err != nil {
+		t.Fatal(err)
+	}
+
+	// sent results are kept for a while
+	chunks, err = recvChunks(t, cli, &pb.CollectionResultsConf{CollectionId: "c1", Offset: last.Size}, 0)
+	if err != nil || len(chunks) != 1 || len(chunks[0].Data) != 0 {
+		t.Fatalf("unexpected chunks at end of results: %v (err: %v)", chunks, err)
+	}
+	_, err = recvChunks(t, cli, &pb.CollectionResultsConf{CollectionId: "c1", Offset: last.Size + 1}, 0)
+	checkCode(t, "offset after end", err, codes.OutOfRange)
+
+	sentResultsRetention = 10 * time.Millisecond
+	if _, err := recvChunks(t, cli, &pb.CollectionResultsConf{CollectionId: "c1"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err = recvChunks(t, cli, &pb.CollectionResultsConf{CollectionId: "c1"}, 0)
+		if status.Code(err) == codes.NotFound {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("results not removed after retention (err: %v)", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(collectionArchive("c1")); !os.IsNotExist(err) {
+		t.Errorf("archive of removed collection exists (err: %v)", err)
+	}
+}
+
+func TestCountJSONArray(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		n    int64
+		ok   bool
+	}{
+		{`[]`, 0, true},
+		{`[{"key": ["0x01", "0x02"], "value": ["0x00"]}, {"key": [], "value": []}]`, 2, true},
+		{"[\n  1,\n  \"a\",\n  [2, 3]\n]\n", 3, true},
+		{`{"error": "no such map"}`, 0, false},
+		{`[{"key": [], "value": []}, {"key": `, 0, false},
+	} {
+		n, err := countJSONArray(strings.NewReader(tc.data))
+		if (err == nil) != tc.ok || n != tc.n {
+			t.Errorf("%q: got %d (error: %v) while expected %d", tc.data, n, err, tc.n)
+		}
+	}
+}
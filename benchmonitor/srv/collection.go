@@ -0,0 +1,349 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// directory for collection data and archives
+var collectionsDir = "/tmp"
+
+// name of the manifest in collection archives
+const collectionManifestName = "manifest.json"
+
+// collectionManifest describes the contents of a collection archive. The files
+// of every collector are in a directory with the name of the collector.
+type collectionManifest struct {
+	CollectionID string              `json:"collection_id"`
+	Node         string              `json:"node"`
+	StartTime    time.Time           `json:"start_time"`
+	EndTime      time.Time           `json:"end_time"`
+	Collectors   []collectorManifest `json:"collectors"`
+}
+
+type collectorManifest struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params"`
+	// files of the collector, relative to the archive root
+	Files []string `json:"files"`
+	Error string   `json:"error,omitempty"`
+}
+
+// errCollectionCancelled is the error of cancelled collections
+var errCollectionCancelled = errors.New("collection cancelled")
+
+// collection is a set of collectors that run for the same period
+type collection struct {
+	id         string
+	collectors []collector
+	runner     cmdRunner
+	manifest   collectionManifest
+
+	mu        sync.Mutex
+	state     pb.CollectionState
+	err       error
+	cancelled bool
+	// stop ends the collectors
+	stop context.CancelFunc
+	// done is closed when the collection is done or failed
+	done chan struct{}
+}
+
+// newCollection returns a collection with the given collectors, or the default
+// collectors if there are none
+func newCollection(id string, confs []*pb.CollectorConf, runner cmdRunner) (*collection, error) {
+	if len(confs) == 0 {
+		confs = defaultCollectors
+	}
+
+	c := &collection{
+		id:     id,
+		runner: runner,
+		state:  pb.CollectionState_COLLECTION_PENDING,
+		stop:   func() {},
+		done:   make(chan struct{}),
+	}
+	c.manifest.CollectionID = id
+	c.manifest.Node, _ = os.Hostname()
+	seen := make(map[string]bool)
+	for _, conf := range confs {
+		if seen[conf.Name] {
+			return nil, fmt.Errorf("collector %s given multiple times", conf.Name)
+		}
+		seen[conf.Name] = true
+		col, params, err := newCollector(conf)
+		if err != nil {
+			return nil, err
+		}
+		c.collectors = append(c.collectors, col)
+		c.manifest.Collectors = append(c.manifest.Collectors, collectorManifest{
+			Name:   conf.Name,
+			Params: params,
+		})
+	}
+	return c, nil
+}
+
+func collectionDataDir(id string) string {
+	return filepath.Join(collectionsDir, fmt.Sprintf("knb-collection-%s", id))
+}
+
+func collectionArchive(id string) string {
+	return filepath.Join(collectionsDir, fmt.Sprintf("%s-collection.tar.gz", id))
+}
+
+// parseCollectionDuration parses the duration of a collection: either a
+// number of seconds (e.g., "5") or a duration (e.g., "500ms"). An empty string
+// means no duration (0).
+func parseCollectionDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if secs, err := strconv.Atoi(s); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// start runs the collection in the background, until it is stopped or until
+// duration expires (if not 0)
+func (c *collection) start(duration time.Duration) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if duration > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), duration)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	c.mu.Lock()
+	c.stop = cancel
+	c.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := c.run(ctx)
+
+		c.mu.Lock()
+		if c.cancelled {
+			err = errCollectionCancelled
+		}
+		c.err = err
+		if err != nil {
+			c.state = pb.CollectionState_COLLECTION_FAILED
+		} else {
+			c.state = pb.CollectionState_COLLECTION_DONE
+		}
+		c.mu.Unlock()
+		close(c.done)
+	}()
+}
+
+// end stops the collectors, and waits until the collection is done (or
+// failed). If cancel is true, the results are discarded.
+func (c *collection) end(ctx context.Context, cancel bool) error {
+	c.mu.Lock()
+	c.cancelled = c.cancelled || cancel
+	stop := c.stop
+	c.mu.Unlock()
+
+	stop()
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if cancel {
+		// the collection might have been done before it was cancelled
+		c.mu.Lock()
+		c.state = pb.CollectionState_COLLECTION_FAILED
+		c.err = errCollectionCancelled
+		c.mu.Unlock()
+		os.Remove(collectionArchive(c.id))
+	}
+	return nil
+}
+
+// status returns the status of the collection. The error of a collection
+// that is done has the errors of the collectors that failed, if any.
+func (c *collection) status() *pb.CollectionStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := &pb.CollectionStatus{
+		CollectionId: c.id,
+		State:        c.state,
+	}
+	var colErrs []string
+	for _, m := range c.manifest.Collectors {
+		ret.Collectors = append(ret.Collectors, m.Name)
+		if m.Error != "" {
+			colErrs = append(colErrs, fmt.Sprintf("%s: %s", m.Name, m.Error))
+		}
+	}
+	if c.err != nil {
+		ret.Error = c.err.Error()
+	} else if len(colErrs) > 0 {
+		ret.Error = fmt.Sprintf("collector(s) failed: %s", strings.Join(colErrs, "; "))
+	}
+	if !c.manifest.StartTime.IsZero() {
+		ret.StartTime = c.manifest.StartTime.UnixNano()
+	}
+	if !c.manifest.EndTime.IsZero() {
+		ret.EndTime = c.manifest.EndTime.UnixNano()
+	}
+	return ret
+}
+
+// run executes the collectors until ctx is done, and creates the collection
+// archive (unless the collection is cancelled). Collector errors are recorded
+// in the manifest (and reported in the status): the returned error is about the
+// collection itself (e.g., the archive could not be created).
+func (c *collection) run(ctx context.Context) error {
+	dir := collectionDataDir(c.id)
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	c.mu.Lock()
+	c.state = pb.CollectionState_COLLECTION_RUNNING
+	c.manifest.StartTime = time.Now().UTC()
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := range c.collectors {
+		m := &c.manifest.Collectors[i]
+		cdir := filepath.Join(dir, m.Name)
+		if err := os.MkdirAll(cdir, 0755); err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func(col collector) {
+			defer wg.Done()
+			if err := col.collect(ctx, c.runner, cdir); err != nil {
+				c.mu.Lock()
+				m.Error = err.Error()
+				c.mu.Unlock()
+			}
+		}(c.collectors[i])
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	c.manifest.EndTime = time.Now().UTC()
+	cancelled := c.cancelled
+	c.mu.Unlock()
+	if cancelled {
+		return errCollectionCancelled
+	}
+
+	for i := range c.manifest.Collectors {
+		m := &c.manifest.Collectors[i]
+		files, err := listFiles(dir, m.Name)
+		if err != nil {
+			return err
+		}
+		m.Files = files
+	}
+	if err := writeJSONFile(filepath.Join(dir, collectionManifestName), &c.manifest); err != nil {
+		return err
+	}
+
+	return writeArchive(collectionArchive(c.id), dir)
+}
+
+// listFiles returns the (regular) files under dir/sub, relative to dir
+func listFiles(dir string, sub string) ([]string, error) {
+	ret := []string{}
+	err := filepath.Walk(filepath.Join(dir, sub), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, rel)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// writeArchive writes the (regular) files under dir in a gzipped tar archive
+func writeArchive(fname string, dir string) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write archive %s: %w", fname, err)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func writeJSONFile(fname string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, append(data, '\n'), 0644)
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+var (
+	scriptsDir   = "/scripts"
+	bccToolsDir  = "/usr/share/bcc/tools"
+	procSoftirqs = "/proc/softirqs"
+	sysClassNet  = "/sys/class/net"
+)
+
+// collector collects data on the node until ctx is done, and writes its
+// results in dir
+type collector interface {
+	collect(ctx context.Context, r cmdRunner, dir string) error
+}
+
+// collectorDesc describes a collector of the registry
+type collectorDesc struct {
+	descr string
+	// parameters, and their default values
+	params map[string]string
+	// new returns a collector for the given parameters (defaults included)
+	new func(params map[string]string) (collector, error)
+}
+
+// collectorRegistry are the available collectors, by name
+var collectorRegistry = map[string]collectorDesc{
+	"perf-record": {
+		descr: "record a profile of all cpus with perf record, and archive it with the debug symbols (perf archive)",
+		params: map[string]string{
+			"freq":       "", // sampling frequency (default: the perf default)
+			"call-graph": "fp",
+			"events":     "",
+		},
+		new: newPerfRecord,
+	},
+	"perf-stat": {
+		descr: "count events of all cpus with perf stat",
+		params: map[string]string{
+			"events":   "",
+			"per-cpu":  "false",
+			"interval": "", // print counts at this interval (e.g., 1s)
+		},
+		new: newPerfStat,
+	},
+	"bpftrace": {
+		descr: "run a bpftrace program (or a script in /scripts), and store its output",
+		params: map[string]string{
+			"program": "",
+			"script":  "",
+		},
+		new: newBpftrace,
+	},
+	"bcc": {
+		descr: "run a bcc tool (e.g., tcpretrans), and store its output",
+		params: map[string]string{
+			"tool": "",
+			"args": "",
+		},
+		new: newBcc,
+	},
+	"ss": {
+		descr: "take ss -tin snapshots of the TCP sockets at an interval",
+		params: map[string]string{
+			"interval": "1s",
+			"filter":   "", // ss filter (e.g., "dport = :8080")
+		},
+		new: newSS,
+	},
+	"ethtool": {
+		descr: "NIC statistics (ethtool -S) before and after the collection, and their difference",
+		params: map[string]string{
+			"devices": "", // comma-separated (default: all physical devices)
+		},
+		new: newEthtool,
+	},
+	"softirqs": {
+		descr:  "softirq counters (/proc/softirqs) before and after the collection, and their difference",
+		params: map[string]string{},
+		new:    newSoftirqs,
+	},
+}
+
+// defaultCollectors are used if a collection does not specify collectors
+var defaultCollectors = []*pb.CollectorConf{{Name: "perf-record"}}
+
+// collectorNames returns the (sorted) names of the registry collectors
+func collectorNames() []string {
+	ret := make([]string, 0, len(collectorRegistry))
+	for name := range collectorRegistry {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// logCollectors logs the registry collectors and their parameters
+func logCollectors() {
+	for _, name := range collectorNames() {
+		desc := collectorRegistry[name]
+		params := make([]string, 0, len(desc.params))
+		for p, def := range desc.params {
+			params = append(params, fmt.Sprintf("%s=%q", p, def))
+		}
+		sort.Strings(params)
+		log.Printf("collector %s: %s (params: %s)", name, desc.descr, strings.Join(params, " "))
+	}
+}
+
+// newCollector returns the collector of conf, and its parameters
+func newCollector(conf *pb.CollectorConf) (collector, map[string]string, error) {
+	desc, ok := collectorRegistry[conf.Name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown collector: %q (available: %s)", conf.Name, strings.Join(collectorNames(), ", "))
+	}
+
+	params := make(map[string]string, len(desc.params))
+	for name, def := range desc.params {
+		params[name] = def
+	}
+	for _, p := range conf.Params {
+		if _, ok := desc.params[p.Name]; !ok {
+			return nil, nil, fmt.Errorf("unknown parameter %q for collector %s", p.Name, conf.Name)
+		}
+		params[p.Name] = p.Value
+	}
+
+	c, err := desc.new(params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("collector %s: %w", conf.Name, err)
+	}
+	return c, params, nil
+}
+
+// runToFile runs a command in dir (see cmdRunner.run), with its output going
+// to dir/fname
+func runToFile(ctx context.Context, r cmdRunner, dir string, fname string, name string, args ...string) error {
+	f, err := os.Create(filepath.Join(dir, fname))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.run(ctx, dir, f, name, args...)
+}
+
+// perf record
+
+type perfRecord struct {
+	args []string
+}
+
+func newPerfRecord(params map[string]string) (collector, error) {
+	args := []string{"record", "-a", "-o", "perf.data"}
+	if f := params["freq"]; f != "" {
+		args = append(args, "-F", f)
+	}
+	if cg := params["call-graph"]; cg != "" && cg != "none" {
+		args = append(args, "--call-graph", cg)
+	}
+	if ev := params["events"]; ev != "" {
+		args = append(args, "-e", ev)
+	}
+	return &perfRecord{args: args}, nil
+}
+
+func (c *perfRecord) collect(ctx context.Context, r cmdRunner, dir string) error {
+	if err := runToFile(ctx, r, dir, "perf-record.log", "perf", c.args...); err != nil {
+		return err
+	}
+
+	// perf archive creates perf.data.tar.bz2 with the objects that have
+	// samples, so that the profile can be analyzed on another machine
+	err := runToFile(context.Background(), r, dir, "perf-archive.log", filepath.Join(scriptsDir, "perf-archive.sh"), "perf.data")
+	if err != nil {
+		log.Printf("perf archive failed (see perf-archive.log): %s", err)
+	}
+	return nil
+}
+
+// perf stat
+
+type perfStat struct {
+	args []string
+}
+
+func newPerfStat(params map[string]string) (collector, error) {
+	args := []string{"stat", "-a", "-o", "perf-stat.txt"}
+	if ev := params["events"]; ev != "" {
+		args = append(args, "-e", ev)
+	}
+	perCPU, err := strconv.ParseBool(params["per-cpu"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid per-cpu value: %w", err)
+	}
+	if perCPU {
+		args = append(args, "-A")
+	}
+	if i := params["interval"]; i != "" {
+		d, err := time.ParseDuration(i)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		args = append(args, "-I", strconv.FormatInt(d.Milliseconds(), 10))
+	}
+	return &perfStat{args: args}, nil
+}
+
+func (c *perfStat) collect(ctx context.Context, r cmdRunner, dir string) error {
+	return runToFile(ctx, r, dir, "perf-stat.log", "perf", c.args...)
+}
+
+// bpftrace and bcc tools
+
+type toolCollector struct {
+	output string
+	name   string
+	args   []string
+}
+
+func (c *toolCollector) collect(ctx context.Context, r cmdRunner, dir string) error {
+	return runToFile(ctx, r, dir, c.output, c.name, c.args...)
+}
+
+func newBpftrace(params map[string]string) (collector, error) {
+	prog, script := params["program"], params["script"]
+	switch {
+	case prog != "" && script != "":
+		return nil, fmt.Errorf("only one of program and script can be given")
+	case prog != "":
+		return &toolCollector{output: "bpftrace.txt", name: "bpftrace", args: []string{"-e", prog}}, nil
+	case script != "":
+		if !filepath.IsAbs(script) {
+			script = filepath.Join(scriptsDir, script)
+		}
+		return &toolCollector{output: "bpftrace.txt", name: "bpftrace", args: []string{script}}, nil
+	default:
+		return nil, fmt.Errorf("program or script is required")
+	}
+}
+
+func newBcc(params map[string]string) (collector, error) {
+	tool := params["tool"]
+	if tool == "" || strings.Contains(tool, "/") {
+		return nil, fmt.Errorf("invalid tool: %q", tool)
+	}
+	return &toolCollector{
+		output: tool + ".txt",
+		name:   filepath.Join(bccToolsDir, tool),
+		args:   strings.Fields(params["args"]),
+	}, nil
+}
+
+// ss snapshots
+
+type ssSnapshots struct {
+	interval time.Duration
+	filter   []string
+}
+
+func newSS(params map[string]string) (collector, error) {
+	d, err := time.ParseDuration(params["interval"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %w", err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("invalid interval: %s", d)
+	}
+	return &ssSnapshots{interval: d, filter: strings.Fields(params["filter"])}, nil
+}
+
+// collect writes a snapshot every interval (and one at the start and the end)
+// in ss.txt, each preceded by a line with its time
+func (c *ssSnapshots) collect(ctx context.Context, r cmdRunner, dir string) error {
+	f, err := os.Create(filepath.Join(dir, "ss.txt"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	snapshot := func() error {
+		out, err := r.output("ss", append([]string{"-tin"}, c.filter...)...)
+		if err != nil {
+			return fmt.Errorf("ss failed: %w", err)
+		}
+		fmt.Fprintf(f, "# %s\n", time.Now().UTC().Format(time.RFC3339Nano))
+		_, err = f.Write(out)
+		return err
+	}
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if err := snapshot(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return snapshot()
+		case <-ticker.C:
+		}
+	}
+}
+
+// counter snapshots (ethtool, softirqs)
+
+// counterSnapshots are counters (by group, e.g., device or softirq) before and
+// after a collection
+type counterSnapshots struct {
+	Before map[string]map[string]int64 `json:"before"`
+	After  map[string]map[string]int64 `json:"after"`
+	// Delta is After - Before, for the counters of both snapshots
+	Delta map[string]map[string]int64 `json:"delta"`
+}
+
+func newCounterSnapshots(before, after map[string]map[string]int64) *counterSnapshots {
+	delta := make(map[string]map[string]int64)
+	for group, a := range after {
+		b, ok := before[group]
+		if !ok {
+			continue
+		}
+		d := make(map[string]int64)
+		for name, v := range a {
+			if bv, ok := b[name]; ok {
+				d[name] = v - bv
+			}
+		}
+		delta[group] = d
+	}
+	return &counterSnapshots{Before: before, After: after, Delta: delta}
+}
+
+// snapshotCollector takes a snapshot at the start and the end of the
+// collection, and writes them (and their difference) in fname
+type snapshotCollector struct {
+	fname    string
+	snapshot func(r cmdRunner) (map[string]map[string]int64, error)
+}
+
+func (c *snapshotCollector) collect(ctx context.Context, r cmdRunner, dir string) error {
+	before, err := c.snapshot(r)
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	after, err := c.snapshot(r)
+	if err != nil {
+		return err
+	}
+	return writeJSONFile(filepath.Join(dir, c.fname), newCounterSnapshots(before, after))
+}
+
+// physicalDevices returns the network devices that have an underlying
+// device (i.e., that are not virtual)
+func physicalDevices() ([]string, error) {
+	devs, err := filepath.Glob(filepath.Join(sysClassNet, "*", "device"))
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, d := range devs {
+		ret = append(ret, filepath.Base(filepath.Dir(d)))
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// parseEthtoolStats parses the output of ethtool -S (a header line, and
+// "name: value" lines)
+func parseEthtoolStats(out []byte) map[string]int64 {
+	ret := make(map[string]int64)
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil {
+			continue
+		}
+		ret[strings.TrimSpace(kv[0])] = v
+	}
+	return ret
+}
+
+func newEthtool(params map[string]string) (collector, error) {
+	var devs []string
+	for _, d := range strings.Split(params["devices"], ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			devs = append(devs, d)
+		}
+	}
+
+	snapshot := func(r cmdRunner) (map[string]map[string]int64, error) {
+		devices := devs
+		if len(devices) == 0 {
+			var err error
+			devices, err = physicalDevices()
+			if err != nil {
+				return nil, err
+			}
+		}
+		ret := make(map[string]map[string]int64)
+		for _, dev := range devices {
+			out, err := r.output("ethtool", "-S", dev)
+			if err != nil {
+				return nil, fmt.Errorf("ethtool -S %s failed: %w", dev, err)
+			}
+			ret[dev] = parseEthtoolStats(out)
+		}
+		return ret, nil
+	}
+	return &snapshotCollector{fname: "ethtool.json", snapshot: snapshot}, nil
+}
+
+// softirqsSnapshot returns the softirq counters by softirq (e.g., NET_RX) and
+// cpu (e.g., CPU0). The first line has the cpus, and every other line the
+// name of a softirq followed by its counters.
+func softirqsSnapshot(fname string) (map[string]map[string]int64, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]map[string]int64)
+	var cpus []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if cpus == nil {
+			cpus = fields
+			continue
+		}
+		name := strings.TrimSuffix(fields[0], ":")
+		counters := make(map[string]int64)
+		for i, field := range fields[1:] {
+			if i >= len(cpus) {
+				break
+			}
+			v, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %w", field, name, err)
+			}
+			counters[cpus[i]] = v
+		}
+		ret[name] = counters
+	}
+	return ret, nil
+}
+
+func newSoftirqs(params map[string]string) (collector, error) {
+	return &snapshotCollector{
+		fname:    "softirqs.json",
+		snapshot: func(cmdRunner) (map[string]map[string]int64, error) { return softirqsSnapshot(procSoftirqs) },
+	}, nil
+}
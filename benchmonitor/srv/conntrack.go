@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// NB: the monitor uses the host network namespace, so the files below are the
+// ones of the host
+var (
+	nfConntrackCount = "/proc/sys/net/netfilter/nf_conntrack_count"
+	nfConntrackMax   = "/proc/sys/net/netfilter/nf_conntrack_max"
+	nfConntrackStat  = "/proc/net/stat/nf_conntrack"
+	// conntrack maps of eBPF datapaths (e.g., cilium_ct4_global). The root
+	// of the host is mounted on /host.
+	bpfCtMapsGlob = "/host/sys/fs/bpf/tc/globals/cilium_ct*"
+)
+
+func readIntFile(fname string) (int64, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// nfConntrackStats returns the per-cpu netfilter conntrack statistics (e.g.,
+// insert_failed, drop, early_drop), summed over all cpus. The first line has
+// the names, and every other line the (hex) values of a cpu.
+func nfConntrackStats(fname string) (map[string]int64, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ret := make(map[string]int64)
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if names == nil {
+			names = fields
+			continue
+		}
+		for i, field := range fields {
+			// the number of entries is global, not per-cpu
+			if i >= len(names) || names[i] == "entries" {
+				continue
+			}
+			v, err := strconv.ParseInt(field, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %w", field, names[i], err)
+			}
+			ret[names[i]] += v
+		}
+	}
+	return ret, scanner.Err()
+}
+
+// countJSONArray returns the number of elements of the JSON array read from
+// r, without keeping the elements in memory
+func countJSONArray(r io.Reader) (int64, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil {
+		return 0, err
+	} else if tok != json.Delim('[') {
+		return 0, fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+
+	var n int64
+	for dec.More() {
+		var elem json.RawMessage
+		if err := dec.Decode(&elem); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if _, err := dec.Token(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// bpfMapEntries returns the number of entries of a pinned eBPF map. The dump
+// of large maps (e.g., conntrack maps with millions of entries) is counted
+// while it is read.
+func bpfMapEntries(ctx context.Context, path string) (int64, error) {
+	cmd := exec.CommandContext(ctx, "bpftool", "-j", "map", "dump", "pinned", path)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	n, err := countJSONArray(out)
+	if err != nil {
+		// NB: bpftool would block writing the rest of the dump
+		cmd.Process.Kill()
+	}
+	if werr := cmd.Wait(); err == nil {
+		err = werr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// conntrackCounters returns the connection tracking counters of the host.
+// Counters that are not available (e.g., nf_conntrack is not loaded) are
+// omitted.
+func conntrackCounters(ctx context.Context) []*pb.Counter {
+	var ret []*pb.Counter
+	add := func(name string, v int64) {
+		ret = append(ret, &pb.Counter{Name: name, Value: v})
+	}
+
+	if v, err := readIntFile(nfConntrackCount); err == nil {
+		add("nf_conntrack_count", v)
+	}
+	if v, err := readIntFile(nfConntrackMax); err == nil {
+		add("nf_conntrack_max", v)
+	}
+	if stats, err := nfConntrackStats(nfConntrackStat); err == nil {
+		for name, v := range stats {
+			add("nf_conntrack_"+name, v)
+		}
+	}
+
+	maps, _ := filepath.Glob(bpfCtMapsGlob)
+	for _, m := range maps {
+		if v, err := bpfMapEntries(ctx, m); err == nil {
+			add("bpf_"+filepath.Base(m), v)
+		}
+	}
+
+	return ret
+}
+
+func (*monitorSrv) GetConntrackStats(
+	ctx context.Context,
+	_ *pb.Empty,
+) (*pb.ConntrackStats, error) {
+	counters := conntrackCounters(ctx)
+	if len(counters) == 0 {
+		return nil, fmt.Errorf("no conntrack counters available")
+	}
+	return &pb.ConntrackStats{Counters: counters}, nil
+}
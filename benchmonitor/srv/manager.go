@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// time to keep the results of a collection after they are sent
+var sentResultsRetention = time.Minute
+
+// collectionManager keeps track of the collections of the monitor.
+//
+// A collection is pending when it is started, running while its collectors
+// execute, and done (or failed) after it is stopped, cancelled, or its duration
+// expires. Stopping (or cancelling) a collection waits until it is done or
+// failed. Retrieving the results of a pending or running collection fails
+// without affecting it. A collection is removed after its failure is reported,
+// or sentResultsRetention after its results are sent: until then, its results
+// can be retrieved again (e.g., to resume a transfer whose last chunks were
+// lost).
+//
+// Errors are gRPC status errors, so that clients can tell them apart (e.g.,
+// codes.FailedPrecondition for collections that are still running).
+type collectionManager struct {
+	runner cmdRunner
+
+	mu          sync.Mutex
+	collections map[string]*collection
+	// collections whose results are being retrieved
+	retrieving map[string]bool
+	// collections whose results were sent
+	sent map[string]bool
+}
+
+func newCollectionManager(runner cmdRunner) *collectionManager {
+	return &collectionManager{
+		runner:      runner,
+		collections: make(map[string]*collection),
+		retrieving:  make(map[string]bool),
+		sent:        make(map[string]bool),
+	}
+}
+
+// start creates a collection, and starts its collectors
+func (m *collectionManager) start(id string, duration time.Duration, confs []*pb.CollectorConf) error {
+	if id == "" {
+		return status.Error(codes.InvalidArgument, "empty collection id")
+	}
+	c, err := newCollection(id, confs, m.runner)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.collections[id]; ok {
+		return status.Errorf(codes.AlreadyExists, "id %s already exists", id)
+	}
+	// NB: the collection is started before it is visible, so that stopping
+	// it always stops its collectors
+	c.start(duration)
+	m.collections[id] = c
+	return nil
+}
+
+func (m *collectionManager) get(id string) (*collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.collections[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "invalid collection id %s", id)
+	}
+	return c, nil
+}
+
+// end stops (or cancels) a collection, and returns its status when it is done
+// or failed
+func (m *collectionManager) end(ctx context.Context, id string, cancel bool) (*pb.CollectionStatus, error) {
+	c, err := m.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.end(ctx, cancel); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return c.status(), nil
+}
+
+// stop stops the collectors of a collection, and returns when its results are
+// available
+func (m *collectionManager) stop(ctx context.Context, id string) (*pb.CollectionStatus, error) {
+	return m.end(ctx, id, false)
+}
+
+// cancel stops the collectors of a collection, and discards its results
+func (m *collectionManager) cancel(ctx context.Context, id string) (*pb.CollectionStatus, error) {
+	return m.end(ctx, id, true)
+}
+
+// list returns the status of all collections, sorted by id
+func (m *collectionManager) list() []*pb.CollectionStatus {
+	m.mu.Lock()
+	colls := make([]*collection, 0, len(m.collections))
+	for _, c := range m.collections {
+		colls = append(colls, c)
+	}
+	m.mu.Unlock()
+
+	ret := make([]*pb.CollectionStatus, 0, len(colls))
+	for _, c := range colls {
+		ret = append(ret, c.status())
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].CollectionId < ret[j].CollectionId
+	})
+	return ret
+}
+
+// results returns the archive of a done collection. The caller needs to call
+// release when it is done with the archive: if sent is true (or the results
+// were sent before) the collection (and its archive) is removed after
+// sentResultsRetention. Failed collections are removed, and their error is
+// returned.
+func (m *collectionManager) results(id string) (string, func(sent bool), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.collections[id]
+	if !ok {
+		return "", nil, status.Errorf(codes.NotFound, "invalid collection id %s", id)
+	}
+
+	st := c.status()
+	switch st.State {
+	case pb.CollectionState_COLLECTION_PENDING, pb.CollectionState_COLLECTION_RUNNING:
+		return "", nil, status.Errorf(codes.FailedPrecondition, "collection %s is still running (state: %s)", id, st.State)
+	case pb.CollectionState_COLLECTION_FAILED:
+		delete(m.collections, id)
+		return "", nil, status.Errorf(codes.Aborted, "collection %s failed: %s", id, st.Error)
+	}
+
+	if m.retrieving[id] {
+		return "", nil, status.Errorf(codes.Unavailable, "results of collection %s are being retrieved", id)
+	}
+	m.retrieving[id] = true
+
+	fname := collectionArchive(id)
+	release := func(sent bool) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.retrieving, id)
+		m.sent[id] = m.sent[id] || sent
+		if !m.sent[id] {
+			return
+		}
+		if sentResultsRetention <= 0 {
+			m.remove(id, c)
+			return
+		}
+		time.AfterFunc(sentResultsRetention, func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.remove(id, c)
+		})
+	}
+	return fname, release, nil
+}
+
+// remove removes a collection whose results were sent, unless they are being
+// retrieved again (release is called when done). Must be called with mu held.
+func (m *collectionManager) remove(id string, c *collection) {
+	if m.collections[id] != c || m.retrieving[id] {
+		return
+	}
+	delete(m.collections, id)
+	delete(m.sent, id)
+	os.Remove(collectionArchive(id))
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// NB: the monitor uses the host network namespace, so the files below are the
+// ones of the host
+var (
+	procStat    = "/proc/stat"
+	procNetDev  = "/proc/net/dev"
+	procNetSnmp = "/proc/net/snmp"
+)
+
+const (
+	defaultMetricsInterval = time.Second
+	minMetricsInterval     = 100 * time.Millisecond
+)
+
+// cpuTimes are the times (in USER_HZ) of a cpu, in the order of /proc/stat:
+// user, nice, system, idle, iowait, irq, softirq, steal
+type cpuTimes [8]uint64
+
+func (t *cpuTimes) total() uint64 {
+	var ret uint64
+	for _, v := range t {
+		ret += v
+	}
+	return ret
+}
+
+// readCPUTimes returns the times of every cpu (e.g., cpu0), and of all cpus
+// (cpu), in the order of /proc/stat
+func readCPUTimes(fname string) ([]string, map[string]cpuTimes, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var names []string
+	ret := make(map[string]cpuTimes)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		var t cpuTimes
+		for i := range t {
+			if i+1 >= len(fields) {
+				break
+			}
+			v, err := strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid value %q for %s: %w", fields[i+1], fields[0], err)
+			}
+			t[i] = v
+		}
+		names = append(names, fields[0])
+		ret[fields[0]] = t
+	}
+	return names, ret, scanner.Err()
+}
+
+// cpuStats returns the utilization of every cpu between two readings of
+// readCPUTimes. Cpus that are missing in prev (e.g., they went online) are
+// omitted.
+func cpuStats(names []string, prev, cur map[string]cpuTimes) []*pb.CPUStats {
+	var ret []*pb.CPUStats
+	for _, name := range names {
+		p, ok := prev[name]
+		if !ok {
+			continue
+		}
+		c := cur[name]
+		var d cpuTimes
+		for i := range c {
+			// counters may go backwards (e.g., iowait)
+			if c[i] > p[i] {
+				d[i] = c[i] - p[i]
+			}
+		}
+		total := d.total()
+		pct := func(i int) float64 {
+			if total == 0 {
+				return 0
+			}
+			return 100 * float64(d[i]) / float64(total)
+		}
+		ret = append(ret, &pb.CPUStats{
+			Cpu:     name,
+			User:    pct(0),
+			Nice:    pct(1),
+			System:  pct(2),
+			Idle:    pct(3),
+			Iowait:  pct(4),
+			Irq:     pct(5),
+			Softirq: pct(6),
+			Steal:   pct(7),
+		})
+	}
+	return ret
+}
+
+// nicStats returns the counters of every network interface. After two header
+// lines, every line of /proc/net/dev has the name of the interface followed by
+// 8 receive and 8 transmit counters.
+func nicStats(fname string) ([]*pb.NICStats, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ret []*pb.NICStats
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		sep := strings.Index(line, ":")
+		if sep < 0 {
+			continue
+		}
+		fields := strings.Fields(line[sep+1:])
+		if len(fields) < 16 {
+			continue
+		}
+		vals := make([]uint64, 16)
+		for i := range vals {
+			v, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q in %s: %w", fields[i], fname, err)
+			}
+			vals[i] = v
+		}
+		ret = append(ret, &pb.NICStats{
+			Name:      strings.TrimSpace(line[:sep]),
+			RxBytes:   vals[0],
+			RxPackets: vals[1],
+			RxErrors:  vals[2],
+			RxDropped: vals[3],
+			TxBytes:   vals[8],
+			TxPackets: vals[9],
+			TxErrors:  vals[10],
+			TxDropped: vals[11],
+		})
+	}
+	return ret, scanner.Err()
+}
+
+// snmpStats returns the counters of a protocol (e.g., Tcp) in /proc/net/snmp,
+// where every protocol has a line with the names and a line with the values
+func snmpStats(fname string, proto string) ([]*pb.Counter, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prefix := proto + ":"
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != prefix {
+			continue
+		}
+		if names == nil {
+			names = fields[1:]
+			continue
+		}
+		var ret []*pb.Counter
+		for i, field := range fields[1:] {
+			if i >= len(names) {
+				break
+			}
+			v, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %w", field, names[i], err)
+			}
+			ret = append(ret, &pb.Counter{Name: names[i], Value: v})
+		}
+		return ret, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no %s counters in %s", proto, fname)
+}
+
+// nodeSampler samples the metrics of the node. CPU utilization is relative to
+// the previous sample.
+type nodeSampler struct {
+	cpuTimes map[string]cpuTimes
+}
+
+func newNodeSampler() (*nodeSampler, error) {
+	_, times, err := readCPUTimes(procStat)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeSampler{cpuTimes: times}, nil
+}
+
+func (s *nodeSampler) sample(now time.Time) (*pb.NodeMetrics, error) {
+	names, times, err := readCPUTimes(procStat)
+	if err != nil {
+		return nil, err
+	}
+	nics, err := nicStats(procNetDev)
+	if err != nil {
+		return nil, err
+	}
+	tcp, err := snmpStats(procNetSnmp, "Tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &pb.NodeMetrics{
+		Timestamp: now.UnixNano(),
+		Cpus:      cpuStats(names, s.cpuTimes, times),
+		Nics:      nics,
+		Tcp:       tcp,
+	}
+	s.cpuTimes = times
+	return ret, nil
+}
+
+func parseMetricsDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// StreamNodeMetrics sends a sample of the node metrics every interval, until
+// the duration expires or the client cancels the call
+func (*monitorSrv) StreamNodeMetrics(
+	conf *pb.NodeMetricsConf,
+	stream pb.KubebenchMonitor_StreamNodeMetricsServer,
+) error {
+	interval, err := parseMetricsDuration(conf.Interval, defaultMetricsInterval)
+	if err != nil {
+		return err
+	}
+	if interval < minMetricsInterval {
+		return fmt.Errorf("interval %s is less than the minimum (%s)", interval, minMetricsInterval)
+	}
+	duration, err := parseMetricsDuration(conf.Duration, 0)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	var deadline <-chan time.Time
+	if duration > 0 {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	sampler, err := newNodeSampler()
+	if err != nil {
+		return err
+	}
+
+	// NB: the headers let the client know that the stream started, before
+	// the first sample (see streamNodeMetricsNode)
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline:
+			return nil
+		case now := <-ticker.C:
+			sample, err := sampler.sample(now)
+			if err != nil {
+				return fmt.Errorf("failed to sample node metrics: %w", err)
+			}
+			if err := stream.Send(sample); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// time to wait for a collector command to exit after it is interrupted, before
+// killing it
+var collectorStopTimeout = 30 * time.Second
+
+// cmdRunner executes the commands of collectors, so that tests can replace
+// them with fake commands
+type cmdRunner interface {
+	// run executes a command in dir, with its output (stdout and stderr)
+	// going to out, until it exits or until ctx is done. In the latter case,
+	// the command is interrupted, and its exit status is not an error.
+	run(ctx context.Context, dir string, out io.Writer, name string, args ...string) error
+	// output executes a command, and returns its standard output
+	output(name string, args ...string) ([]byte, error)
+}
+
+// execRunner executes commands on the host
+type execRunner struct{}
+
+func (execRunner) run(ctx context.Context, dir string, out io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return runUntilDone(ctx, cmd)
+}
+
+func (execRunner) output(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %w (%s)", cmd, err, exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("%s: %w", cmd, err)
+	}
+	return out, nil
+}
+
+// runUntilDone runs cmd until it exits, or until ctx is done. In the latter
+// case, cmd is interrupted (so that tools such as perf or bpftrace write their
+// results), and killed if it does not exit after collectorStopTimeout.
+func runUntilDone(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		if err != nil {
+			return fmt.Errorf("%s: %w", cmd, err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	cmd.Process.Signal(syscall.SIGINT)
+	select {
+	case err := <-exited:
+		// the command was interrupted, so its exit status is not an error
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return err
+		}
+		return nil
+	case <-time.After(collectorStopTimeout):
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("%s: killed after not exiting for %s", cmd, collectorStopTimeout)
+	}
+}
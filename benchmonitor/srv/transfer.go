@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// maxChunkSize is the maximum size of the data of File messages. It is lower
+// than the (default) maximum message size of gRPC clients (4MiB).
+const maxChunkSize = 3 << 20
+
+var (
+	defaultChunkSize = flag.Int("chunk-size", 1<<20, "Size of file chunks sent to clients (bytes)")
+)
+
+type FileSender interface {
+	Send(*pb.File) error
+}
+
+// validateChunkSizeFlag checks the -chunk-size flag
+func validateChunkSizeFlag() error {
+	if *defaultChunkSize <= 0 || *defaultChunkSize > maxChunkSize {
+		return fmt.Errorf("invalid -chunk-size %d (must be between 1 and %d)", *defaultChunkSize, maxChunkSize)
+	}
+	return nil
+}
+
+// chunkSize returns the chunk size to use for a request of size bytes (0 for
+// the default)
+func chunkSize(size int64) (int64, error) {
+	if size == 0 {
+		size = int64(*defaultChunkSize)
+	}
+	if size < 0 || size > maxChunkSize {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid chunk size %d (maximum: %d)", size, maxChunkSize)
+	}
+	return size, nil
+}
+
+// sendFile sends the data of r (named name, with the given size) starting at
+// offset, in chunks of chunk bytes
+func sendFile(stream FileSender, name string, r io.ReadSeeker, size int64, offset int64, chunk int64) error {
+	if chunk <= 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid chunk size %d", chunk)
+	}
+	if offset < 0 || offset > size {
+		return status.Errorf(codes.OutOfRange, "invalid offset %d for %s (size: %d)", offset, name, size)
+	}
+
+	h := sha256.New()
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("io error: %w", err)
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("io error: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("io error: %w", err)
+	}
+
+	buff := make([]byte, chunk)
+	for {
+		n, err := io.ReadFull(r, buff)
+		if err == io.EOF && offset < size {
+			return fmt.Errorf("io error: %s truncated at %d bytes (size: %d)", name, offset, size)
+		} else if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("io error: %w", err)
+		}
+
+		err = stream.Send(&pb.File{
+			Data:   buff[:n],
+			Name:   name,
+			Size:   size,
+			Offset: offset,
+			Sha256: sum,
+		})
+		if err != nil {
+			return fmt.Errorf("io error: %w", err)
+		}
+
+		offset += int64(n)
+		if offset >= size {
+			return nil
+		}
+	}
+}
+
+// copyFileToStream sends the contents of fname, starting at offset
+func copyFileToStream(fname string, offset int64, chunk int64, stream FileSender) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return sendFile(stream, filepath.Base(fname), f, info.Size(), offset, chunk)
+}
+
+// copyDataToStream sends data as a file named name
+func copyDataToStream(name string, data []byte, stream FileSender) error {
+	chunk, err := chunkSize(0)
+	if err != nil {
+		return err
+	}
+	return sendFile(stream, name, bytes.NewReader(data), int64(len(data)), 0, chunk)
+}
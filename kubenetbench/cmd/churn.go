@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var (
+	churnRate        int
+	churnConcurrency int
+	churnSrvImage    string
+	churnCliImage    string
+	churnArgs        []string
+)
+
+var churnCmd = &cobra.Command{
+	Use:   "churn",
+	Short: "connection churn benchmark run (short-lived connections via a service)",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runChurn()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func getChurnBench() *core.ChurnConf {
+	cnf := core.ChurnConfDefault()
+	cnf.Timeout = benchmarkDuration
+	cnf.QPS = churnRate
+	cnf.Connections = churnConcurrency
+	cnf.Image = churnSrvImage
+	cnf.CliImage = churnCliImage
+	cnf.MoreArgs = churnArgs
+	return &cnf
+}
+
+func runChurn() error {
+	runctx, err := getRunBenchCtxWith("churn", "churn", getChurnBench(), false)
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+	// the conntrack snapshots are the point of the benchmark
+	runctx.SetConntrackSnapshots(true)
+
+	st := newServiceSt(runctx)
+	err = st.Validate()
+	if err != nil {
+		return err
+	}
+	err = runctx.MakeDir()
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+
+	err = st.Execute()
+	if err != nil {
+		return fmt.Errorf("churn execution failed: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	def := core.ChurnConfDefault()
+	addRunFlags(churnCmd)
+	addServiceFlags(churnCmd)
+	churnCmd.Flags().IntVar(&churnRate, "churn-rate", def.QPS, "new connections per second of each client pod (0: no rate limiting)")
+	churnCmd.Flags().IntVar(&churnConcurrency, "churn-concurrency", def.Connections, "number of concurrent connections of each client pod")
+	churnCmd.Flags().StringVar(&churnSrvImage, "churn-srv-image", def.Image, "image for the server (fortio)")
+	churnCmd.Flags().StringVar(&churnCliImage, "churn-cli-image", def.CliImage, "image for the clients (needs fortio and the kubenetbench scripts)")
+	churnCmd.Flags().StringArrayVar(&churnArgs, "churn-args", []string{}, "additional load generator (fortio load) arguments")
+}
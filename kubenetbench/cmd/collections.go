@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var collectionsCancel string
+
+var collectionsCmd = &cobra.Command{
+	Use:   "collections",
+	Short: "list (or cancel) the collections of the monitors",
+	Long: `List the collections (e.g., perf profiles) of the monitors of all nodes, and
+their state (pending, running, done, or failed).
+
+Collections are normally stopped when the clients of a run finish, and removed
+when their results are retrieved. Use --cancel to cancel the collection with
+the given id on all nodes (e.g., after kubenetbench was killed during a run).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sess := getSession()
+		collections, err := sess.ListCollections()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if collectionsCancel == "" {
+			if len(collections) == 0 {
+				fmt.Println("no collections found")
+				return
+			}
+			if err := core.WriteCollections(os.Stdout, collections); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		n := 0
+		for _, c := range collections {
+			if c.Status.CollectionId != collectionsCancel {
+				continue
+			}
+			if _, err := sess.CancelCollection(c.Node, collectionsCancel); err != nil {
+				log.Fatal(err)
+			}
+			n++
+		}
+		fmt.Printf("cancelled collection %s on %d node(s)\n", collectionsCancel, n)
+	},
+}
+
+func init() {
+	collectionsCmd.Flags().StringVar(&collectionsCancel, "cancel", "", "cancel the collection with this id")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var compareConf = core.CompareConf{}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <A> [<B>]",
+	Short: "compare the results of two runs or sessions (A is the baseline), or of a session against its node2node runs",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		// a single session is compared against its host baseline
+		if len(args) == 1 {
+			args = append(args, args[0])
+			compareConf.HostBaseline = true
+		}
+
+		runsA, err := core.LoadRuns(args[0])
+		if err != nil {
+			log.Fatal(fmt.Errorf("failed to load runs from %s: %w", args[0], err))
+		}
+
+		runsB, err := core.LoadRuns(args[1])
+		if err != nil {
+			log.Fatal(fmt.Errorf("failed to load runs from %s: %w", args[1], err))
+		}
+
+		res := core.CompareRuns(runsA, runsB, &compareConf)
+		err = res.WriteTable(os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if n := res.Regressions(); n > 0 {
+			log.Fatalf("%d regression(s) detected", n)
+		}
+	},
+}
+
+func init() {
+	compareCmd.Flags().Float64Var(&compareConf.Threshold, "threshold", 5.0, "change (percentage) in the wrong direction that is considered a regression")
+	compareCmd.Flags().Float64Var(&compareConf.Alpha, "alpha", 0.05, "significance level for the Mann-Whitney U test (applied when there are enough repetitions to reach it)")
+	compareCmd.Flags().BoolVar(&compareConf.HostBaseline, "host-baseline", false, "compare the runs of B against the node2node runs of A (the default if B is omitted, or if A only has node2node runs and B has none)")
+	compareCmd.Flags().BoolVar(&compareConf.AllMetrics, "all-metrics", false, "also show metrics where it is not known whether higher is better")
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var (
+	dnsQPS           int
+	dnsClients       int
+	dnsQueryTimeout  int
+	dnsQueryType     string
+	dnsNames         []string
+	dnsNdots         int
+	dnsSearch        []string
+	dnsServer        string
+	dnsClusterDomain string
+	dnsImage         string
+	dnsArgs          []string
+)
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "DNS resolution benchmark run (dnsperf against the cluster DNS)",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runDNS()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func getDNSBench() *core.DNSPerfConf {
+	cnf := core.DNSPerfConfDefault()
+	cnf.Timeout = benchmarkDuration
+	cnf.QPS = dnsQPS
+	cnf.Clients = dnsClients
+	cnf.QueryTimeout = dnsQueryTimeout
+	cnf.QueryType = dnsQueryType
+	cnf.Names = dnsNames
+	cnf.Ndots = dnsNdots
+	if len(dnsSearch) > 0 {
+		cnf.Search = dnsSearch
+	}
+	cnf.Image = dnsImage
+	cnf.MoreArgs = dnsArgs
+	return &cnf
+}
+
+func runDNS() error {
+	cnf := getDNSBench()
+	runctx, err := getRunBenchCtxWith("dns", "dns", cnf, true)
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+	st := core.DNSSt{
+		RunBenchCtx:   runctx,
+		Conf:          cnf,
+		DNSServer:     dnsServer,
+		ClusterDomain: dnsClusterDomain,
+	}
+	err = st.Execute()
+	if err != nil {
+		return fmt.Errorf("dns execution failed: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	def := core.DNSPerfConfDefault()
+	addRunFlags(dnsCmd)
+	dnsCmd.Flags().IntVar(&dnsQPS, "dns-qps", def.QPS, "DNS queries per second (0: no rate limiting)")
+	dnsCmd.Flags().IntVar(&dnsClients, "dns-clients", def.Clients, "number of concurrent dnsperf clients (sockets) per client pod")
+	dnsCmd.Flags().IntVar(&dnsQueryTimeout, "dns-query-timeout", def.QueryTimeout, "time (sec) after which a query is considered lost")
+	dnsCmd.Flags().StringVar(&dnsQueryType, "dns-type", def.QueryType, "DNS query type")
+	dnsCmd.Flags().StringArrayVar(&dnsNames, "dns-names", []string{}, "names to resolve (default: a service of the run, and kubernetes.default)")
+	dnsCmd.Flags().IntVar(&dnsNdots, "dns-ndots", def.Ndots, "ndots resolver option used to expand the names")
+	dnsCmd.Flags().StringArrayVar(&dnsSearch, "dns-search", []string{}, "search domains used to expand the names (default: the search domains of pods in the run namespace)")
+	dnsCmd.Flags().StringVar(&dnsServer, "dns-server", "", "DNS server address (default: the cluster IP of the kube-dns service)")
+	dnsCmd.Flags().StringVar(&dnsClusterDomain, "cluster-domain", "cluster.local", "cluster domain")
+	dnsCmd.Flags().StringVar(&dnsImage, "dns-image", def.Image, "image for the DNS load generator (dnsperf)")
+	dnsCmd.Flags().StringArrayVar(&dnsArgs, "dns-args", []string{}, "additional dnsperf arguments")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var (
+	gcDryRun bool
+	gcMinAge time.Duration
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "delete leftover objects of dead runs and sessions",
+	Long: `Find and delete leftover objects (i.e., objects with a knb-runid or knb-sessid
+label) of runs and sessions that are no longer active.
+
+A run is dead if its run.json (under the session base directory) shows that it
+finished, or that the process executing it does not exist anymore. Sessions
+are active as long as their directory exists (use "done" to terminate them).
+Objects of runs and sessions that cannot be found under the session base
+directory are deleted only if they are older than --min-age.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cli := getKubeClient()
+		conf := &core.GCConf{
+			SessDirBase: sessDirBase,
+			MinAge:      gcMinAge,
+		}
+
+		leftovers, err := core.FindLeftovers(cli, conf)
+		if err != nil {
+			log.Fatal(fmt.Errorf("failed to find leftover objects: %w", err))
+		}
+
+		if len(leftovers) == 0 {
+			fmt.Println("no leftover objects found")
+			return
+		}
+
+		err = core.WriteLeftovers(os.Stdout, leftovers)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if gcDryRun {
+			return
+		}
+
+		err = core.DeleteLeftovers(cli, leftovers)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("deleted %d object(s)\n", len(leftovers))
+	},
+}
+
+func init() {
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "only list the leftover objects")
+	gcCmd.Flags().DurationVar(&gcMinAge, "min-age", time.Hour, "minimum age of objects of unknown runs and sessions")
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+// host network scenarios (see core.HostScenario)
+func newHostScenarioCmd(scenario string, short string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   scenario,
+		Short: short,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := runHostScenario(scenario)
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+	addBenchmarkFlags(cmd)
+	return cmd
+}
+
+var (
+	pod2nodeCmd  = newHostScenarioCmd(core.ScenarioPod2Node, "pod-to-node network benchmark run (server on the host network)")
+	node2podCmd  = newHostScenarioCmd(core.ScenarioNode2Pod, "node-to-pod network benchmark run (client on the host network)")
+	node2nodeCmd = newHostScenarioCmd(core.ScenarioNode2Node, "node-to-node network benchmark run (client and server on the host network)")
+)
+
+func runHostScenario(scenario string) error {
+	if cliHost || srvHost {
+		return fmt.Errorf("--cli-on-host and --srv-on-host are implied by %s", scenario)
+	}
+
+	runctx, err := getRunBenchCtx(scenario, scenario, true)
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+	st := core.Pod2PodSt{
+		RunBenchCtx: runctx,
+	}
+	err = st.Execute()
+	if err != nil {
+		return fmt.Errorf("%s execution failed: %w", scenario, err)
+	}
+	return nil
+}
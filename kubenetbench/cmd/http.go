@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var (
+	httpQPS          int
+	httpConnections  int
+	httpResponseSize int
+	httpPayloadSize  int
+	httpImage        string
+	httpArgs         []string
+)
+
+func addHTTPFlags(cmd *cobra.Command) {
+	def := core.HTTPConfDefault()
+	cmd.Flags().IntVar(&httpQPS, "http-qps", def.QPS, "HTTP requests per second (0: no rate limiting)")
+	cmd.Flags().IntVar(&httpConnections, "http-connections", def.Connections, "number of HTTP connections")
+	cmd.Flags().IntVar(&httpResponseSize, "http-response-size", 0, "HTTP response size (bytes)")
+	cmd.Flags().IntVar(&httpPayloadSize, "http-payload-size", 0, "HTTP request payload size (bytes), >0 uses POST requests")
+	cmd.Flags().StringVar(&httpImage, "http-image", def.Image, "image for the HTTP server and load generator (fortio)")
+	cmd.Flags().StringArrayVar(&httpArgs, "http-args", []string{}, "additional load generator (fortio load) arguments")
+}
+
+func getHTTPBench() core.Benchmark {
+	cnf := core.HTTPConfDefault()
+	cnf.Timeout = benchmarkDuration
+	cnf.QPS = httpQPS
+	cnf.Connections = httpConnections
+	cnf.ResponseSize = httpResponseSize
+	cnf.PayloadSize = httpPayloadSize
+	cnf.Image = httpImage
+	cnf.MoreArgs = httpArgs
+	return &cnf
+}
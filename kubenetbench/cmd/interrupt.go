@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interrupt context, initialized by getInterruptContext
+var interruptCtx context.Context
+
+// getInterruptContext returns a context that is done when the program
+// receives SIGINT or SIGTERM. Runs use it to stop and perform cleanup. After
+// the first signal, the default signal behavior is restored, so that a second
+// signal terminates the program immediately.
+func getInterruptContext() context.Context {
+	if interruptCtx != nil {
+		return interruptCtx
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Printf("interrupted: cleaning up (interrupt again to exit immediately)")
+	}()
+
+	interruptCtx = ctx
+	return ctx
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var (
+	iperfUDP      bool
+	iperfParallel int
+	iperfReverse  bool
+	iperfBitrate  string
+	iperfWindow   string
+	iperfArgs     []string
+)
+
+func addIperfFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&iperfUDP, "iperf-udp", false, "use UDP instead of TCP")
+	cmd.Flags().IntVar(&iperfParallel, "iperf-parallel", 1, "number of parallel client streams")
+	cmd.Flags().BoolVar(&iperfReverse, "iperf-reverse", false, "reverse mode (server sends, client receives)")
+	cmd.Flags().StringVar(&iperfBitrate, "iperf-bitrate", "", "target bitrate in bits/sec (e.g., 100M, 0 for unlimited)")
+	cmd.Flags().StringVar(&iperfWindow, "iperf-window", "", "socket buffer (window) size (e.g., 256K)")
+	cmd.Flags().StringArrayVar(&iperfArgs, "iperf-args", []string{}, "additional iperf3 client arguments")
+}
+
+func getIperfBench() core.Benchmark {
+	cnf := core.IperfConfDefault()
+	cnf.Timeout = benchmarkDuration
+	cnf.UDP = iperfUDP
+	cnf.Parallel = iperfParallel
+	cnf.Reverse = iperfReverse
+	cnf.Bitrate = iperfBitrate
+	cnf.Window = iperfWindow
+	cnf.MoreArgs = iperfArgs
+	return &cnf
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var matrixDryRun bool
+
+// scenarios that can be used in a matrix plan
+var matrixScenarios = map[string]struct {
+	cmd *cobra.Command
+	run func() error
+}{
+	"pod2pod": {pod2podCmd, runPod2Pod},
+	"service": {serviceCmd, runService},
+	"pairs":   {pairsCmd, runPairs},
+	"dns":     {dnsCmd, runDNS},
+	"churn":   {churnCmd, runChurn},
+
+	core.ScenarioPod2Node:  {pod2nodeCmd, func() error { return runHostScenario(core.ScenarioPod2Node) }},
+	core.ScenarioNode2Pod:  {node2podCmd, func() error { return runHostScenario(core.ScenarioNode2Pod) }},
+	core.ScenarioNode2Node: {node2nodeCmd, func() error { return runHostScenario(core.ScenarioNode2Node) }},
+}
+
+var matrixCmd = &cobra.Command{
+	Use:   "matrix",
+	Short: "benchmark matrix commands",
+}
+
+var matrixRunCmd = &cobra.Command{
+	Use:   "run <plan.yaml>",
+	Short: "execute all the combinations of a benchmark matrix plan",
+	Long: `Execute all the combinations of a benchmark matrix plan (YAML or JSON).
+
+Combinations that already have a successful run in the session are skipped, so
+an interrupted matrix can be resumed by executing the same plan again.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		plan, err := core.LoadMatrixPlan(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		combs, err := plan.Expand()
+		if err != nil {
+			log.Fatal(fmt.Errorf("failed to expand plan: %w", err))
+		}
+
+		// validate all combinations before executing anything
+		for _, c := range combs {
+			if _, ok := matrixScenarios[c.Scenario()]; !ok {
+				log.Fatalf("combination %s: invalid scenario: %q", c.Label, c.Scenario())
+			}
+		}
+
+		if matrixDryRun {
+			for _, c := range combs {
+				fmt.Printf("%s: %s\n", c.Label, matrixParamsString(c))
+			}
+			return
+		}
+
+		// initialize session (directory, logging)
+		getSession()
+		sessDir := core.SessionDir(sessDirBase, sessID)
+		runs, err := core.LoadSessionRuns(sessDir)
+		if err != nil {
+			log.Fatal(fmt.Errorf("failed to load session runs: %w", err))
+		}
+
+		status := make([]*core.MatrixStatus, 0, len(combs))
+		for _, c := range combs {
+			st := &core.MatrixStatus{MatrixCombination: *c, Status: core.MatrixPending}
+			if core.HasSuccessfulRun(runs, c.Label) {
+				st.Status = core.MatrixDone
+			}
+			status = append(status, st)
+		}
+
+		statusFname := core.MatrixStatusFname(sessDir, plan)
+		saveStatus := func() {
+			if err := core.SaveMatrixStatus(statusFname, status); err != nil {
+				log.Printf("failed to save matrix status: %s", err)
+			}
+		}
+		saveStatus()
+
+		failed := 0
+		for i, st := range status {
+			if st.Status == core.MatrixDone {
+				log.Printf("matrix %s [%d/%d]: skipping %s (successful run exists)", plan.Name, i+1, len(status), st.Label)
+				continue
+			}
+
+			log.Printf("matrix %s [%d/%d]: running %s: %s", plan.Name, i+1, len(status), st.Label, matrixParamsString(&st.MatrixCombination))
+			err := runMatrixCombination(&st.MatrixCombination)
+			if err != nil {
+				log.Printf("matrix %s: %s failed: %s", plan.Name, st.Label, err)
+				st.Status = core.MatrixFailed
+				st.Error = err.Error()
+				failed++
+			} else {
+				st.Status = core.MatrixSuccess
+			}
+			saveStatus()
+
+			if errors.Is(err, core.ErrInterrupted) {
+				log.Fatalf("matrix %s: interrupted (execute the plan again to resume)", plan.Name)
+			}
+		}
+
+		if failed > 0 {
+			log.Fatalf("matrix %s: %d combination(s) failed (see %s)", plan.Name, failed, statusFname)
+		}
+	},
+}
+
+func matrixParamsString(c *core.MatrixCombination) string {
+	// NB: fmt prints maps sorted by key
+	return strings.TrimPrefix(fmt.Sprintf("%v", c.Params), "map")
+}
+
+// resetFlags resets all the flags of a command to their default values
+func resetFlags(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			err = sv.Replace([]string{})
+		} else {
+			err = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	return err
+}
+
+func setFlag(cmd *cobra.Command, name string, vals []string) error {
+	f := cmd.Flags().Lookup(name)
+	if f == nil {
+		return fmt.Errorf("unknown parameter %q for %s", name, cmd.Name())
+	}
+
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(vals)
+	}
+
+	if len(vals) != 1 {
+		return fmt.Errorf("parameter %q does not accept multiple values", name)
+	}
+	return cmd.Flags().Set(name, vals[0])
+}
+
+// runMatrixCombination executes the scenario of the combination, after setting
+// the scenario command flags based on the combination parameters
+func runMatrixCombination(c *core.MatrixCombination) error {
+	sc := matrixScenarios[c.Scenario()]
+	if err := resetFlags(sc.cmd); err != nil {
+		return fmt.Errorf("failed to reset flags: %w", err)
+	}
+
+	for name, v := range c.Params {
+		if name == core.MatrixScenarioParam {
+			continue
+		}
+		vals, err := core.MatrixParamValues(v)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", name, err)
+		}
+		if err := setFlag(sc.cmd, name, vals); err != nil {
+			return err
+		}
+	}
+
+	if err := setFlag(sc.cmd, "run-label", []string{c.Label}); err != nil {
+		return err
+	}
+
+	return sc.run()
+}
+
+func init() {
+	matrixRunCmd.Flags().BoolVar(&matrixDryRun, "dry-run", false, "only print the combinations of the plan")
+	matrixCmd.AddCommand(matrixRunCmd)
+}
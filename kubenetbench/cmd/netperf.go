@@ -14,45 +14,46 @@ var netperfTy string
 var netperfArgs []string
 var netperfBenchArgs []string
 var netperfNStreams int
+var netperfDataPort uint16
 
 var netperfBenchMap = map[string]func() core.Benchmark{
 	"tcp_rr": func() core.Benchmark {
-		cnf := core.NetperfRRConf{core.NetperfConfDefault("tcp_rr", netperfArgs, netperfBenchArgs)}
+		cnf := core.NetperfRRConf{NetperfConf: core.NetperfConfDefault("tcp_rr", netperfArgs, netperfBenchArgs)}
 		cnf.Timeout = benchmarkDuration
 		handle_nstreams(&cnf.NetperfConf)
 		return &cnf
 	},
 
 	"tcp_crr": func() core.Benchmark {
-		cnf := core.NetperfRRConf{core.NetperfConfDefault("tcp_crr", netperfArgs, netperfBenchArgs)}
+		cnf := core.NetperfRRConf{NetperfConf: core.NetperfConfDefault("tcp_crr", netperfArgs, netperfBenchArgs)}
 		cnf.Timeout = benchmarkDuration
 		handle_nstreams(&cnf.NetperfConf)
 		return &cnf
 	},
 
 	"udp_rr": func() core.Benchmark {
-		cnf := core.NetperfRRConf{core.NetperfConfDefault("udp_rr", netperfArgs, netperfBenchArgs)}
+		cnf := core.NetperfRRConf{NetperfConf: core.NetperfConfDefault("udp_rr", netperfArgs, netperfBenchArgs)}
 		cnf.Timeout = benchmarkDuration
 		handle_nstreams(&cnf.NetperfConf)
 		return &cnf
 	},
 
 	"tcp_stream": func() core.Benchmark {
-		cnf := core.NetperfStreamConf{core.NetperfConfDefault("tcp_stream", netperfArgs, netperfBenchArgs)}
+		cnf := core.NetperfStreamConf{NetperfConf: core.NetperfConfDefault("tcp_stream", netperfArgs, netperfBenchArgs)}
 		cnf.Timeout = benchmarkDuration
 		handle_nstreams(&cnf.NetperfConf)
 		return &cnf
 	},
 
 	"tcp_maerts": func() core.Benchmark {
-		cnf := core.NetperfStreamConf{core.NetperfConfDefault("tcp_maerts", netperfArgs, netperfBenchArgs)}
+		cnf := core.NetperfStreamConf{NetperfConf: core.NetperfConfDefault("tcp_maerts", netperfArgs, netperfBenchArgs)}
 		cnf.Timeout = benchmarkDuration
 		handle_nstreams(&cnf.NetperfConf)
 		return &cnf
 	},
 
 	"udp_stream": func() core.Benchmark {
-		cnf := core.NetperfStreamConf{core.NetperfConfDefault("udp_stream", netperfArgs, netperfBenchArgs)}
+		cnf := core.NetperfStreamConf{NetperfConf: core.NetperfConfDefault("udp_stream", netperfArgs, netperfBenchArgs)}
 		cnf.Timeout = benchmarkDuration
 		handle_nstreams(&cnf.NetperfConf)
 		return &cnf
@@ -71,6 +72,7 @@ func addNetperfFlags(cmd *cobra.Command) {
 	cmd.Flags().StringArrayVar(&netperfArgs, "netperf-args", []string{}, "netperf arguments")
 	cmd.Flags().StringArrayVar(&netperfBenchArgs, "netperf-bench-args", []string{}, "netperf benchmark arguments (after --)")
 	cmd.Flags().IntVar(&netperfNStreams, "netperf-nstreams", 0, ">0 value enables using duper_netperf script for multiple streams")
+	cmd.Flags().Uint16Var(&netperfDataPort, "netperf-data-port", 8000, "netperf data port (for NodePort services, it needs to be in the node port range)")
 }
 
 func handle_nstreams(conf *core.NetperfConf) {
@@ -82,16 +84,23 @@ func handle_nstreams(conf *core.NetperfConf) {
 		conf.CliCommand = "scripts/duper_netperf"
 		conf.PreArgs = append(conf.PreArgs, fmt.Sprintf("%d", netperfNStreams))
 	} else {
-		log.Fatal("cannot use multiple streams with CliCommand=%s", conf.CliCommand)
+		log.Fatalf("cannot use multiple streams with CliCommand=%s", conf.CliCommand)
 	}
 }
 
 // TODO: parse options to support other netperf configurations here
-func getNetperfBench() core.Benchmark {
+func getNetperfBench() (core.Benchmark, error) {
 
 	initFn, ok := netperfBenchMap[netperfTy]
 	if !ok {
-		panic(fmt.Sprintf("Invalid netperf type: %s", netperfTy))
+		return nil, fmt.Errorf("invalid netperf type: %s", netperfTy)
 	}
-	return initFn()
+	bench := initFn()
+	switch cnf := bench.(type) {
+	case *core.NetperfRRConf:
+		cnf.DataPort = netperfDataPort
+	case *core.NetperfStreamConf:
+		cnf.DataPort = netperfDataPort
+	}
+	return bench, nil
 }
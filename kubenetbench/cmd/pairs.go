@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var pairsCmd = &cobra.Command{
+	Use:   "pairs",
+	Short: "benchmark run of independent client/server pod pairs (the number of pairs is --clients)",
+	Run: func(cmd *cobra.Command, args []string) {
+		err := runPairs()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func runPairs() error {
+	if clientJob {
+		return fmt.Errorf("--client-job is not supported for pairs")
+	}
+
+	runctx, err := getRunBenchCtx(core.ScenarioPairs, core.ScenarioPairs, true)
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+	st := core.PairsSt{
+		RunBenchCtx: runctx,
+	}
+	err = st.Execute()
+	if err != nil {
+		return fmt.Errorf("pairs execution failed: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	addBenchmarkFlags(pairsCmd)
+}
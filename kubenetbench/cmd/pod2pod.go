@@ -1,39 +1,53 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/cilium/kubenetbench/kubenetbench/core"
 )
 
-var policyArg string
+var (
+	policyArg     string
+	policySizeArg int
+)
 
 var pod2podCmd = &cobra.Command{
 	Use:   "pod2pod",
 	Short: "pod-to-pod network benchmark run",
 	Run: func(cmd *cobra.Command, args []string) {
-		if policyArg != "" && policyArg != "port" {
-			log.Fatal("invalid policy: ", policyArg)
-		}
-
-		runctx, err := getRunBenchCtx("pod2pod", true)
-		if err != nil {
-			log.Fatal("initializing run context failed:", err)
-		}
-		st := core.Pod2PodSt{
-			RunBenchCtx: runctx,
-			Policy:      policyArg,
-		}
-		err = st.Execute()
+		err := runPod2Pod()
 		if err != nil {
-			log.Fatal("pod2pod execution failed:", err)
+			log.Fatal(err)
 		}
 	},
 }
 
+func runPod2Pod() error {
+	// name the scenario based on whether the client/server run on the host
+	// network, so that results can be compared against node2node
+	scenario := core.HostScenario(cliHost, srvHost)
+	runctx, err := getRunBenchCtx(scenario, "pod2pod", true)
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+	st := core.Pod2PodSt{
+		RunBenchCtx: runctx,
+		Policy:      policyArg,
+		PolicySize:  policySizeArg,
+	}
+	err = st.Execute()
+	if err != nil {
+		return fmt.Errorf("pod2pod execution failed: %w", err)
+	}
+	return nil
+}
+
 func init() {
 	addBenchmarkFlags(pod2podCmd)
-	pod2podCmd.Flags().StringVar(&policyArg, "policy", "", "isolation policy (empty or \"port\")")
+	pod2podCmd.Flags().StringVar(&policyArg, "policy", "", fmt.Sprintf("isolation policy (empty, or one of: %s)", strings.Join(core.PolicyKinds, ", ")))
+	pod2podCmd.Flags().IntVar(&policySizeArg, "policy-size", 0, "number of policy rules (policies, CIDRs, selectors, port ranges, HTTP rules) in addition to the rule that allows the benchmark traffic")
 }
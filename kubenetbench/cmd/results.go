@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cilium/kubenetbench/kubenetbench/core"
+)
+
+var (
+	resultsFormat string
+	resultsOutput string
+)
+
+var resultsWriters = map[string]func(io.Writer, []*core.RunInfo) error{
+	"table": core.WriteRunsTable,
+	"csv":   core.WriteRunsCSV,
+	"json":  core.WriteRunsJSON,
+}
+
+var resultsCmd = &cobra.Command{
+	Use:   "results [session-dir]",
+	Short: "show the results of the session runs",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		writeFn, ok := resultsWriters[resultsFormat]
+		if !ok {
+			log.Fatal("invalid format: ", resultsFormat)
+		}
+
+		var sessDir string
+		if len(args) > 0 {
+			sessDir = args[0]
+		} else {
+			checkSessionID()
+			sessDir = core.SessionDir(sessDirBase, sessID)
+		}
+
+		runs, err := core.LoadSessionRuns(sessDir)
+		if err != nil {
+			log.Fatal(fmt.Errorf("failed to load session results: %w", err))
+		}
+
+		var w io.Writer = os.Stdout
+		if resultsOutput != "" {
+			f, err := os.Create(resultsOutput)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		err = writeFn(w, runs)
+		if err != nil {
+			log.Fatal(fmt.Errorf("failed to write results: %w", err))
+		}
+	},
+}
+
+func init() {
+	resultsCmd.Flags().StringVar(&resultsFormat, "format", "table", "output format (table, csv, json)")
+	resultsCmd.Flags().StringVarP(&resultsOutput, "output", "o", "", "output file (default: stdout)")
+}
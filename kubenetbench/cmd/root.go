@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cilium/kubenetbench/kubenetbench/core"
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
 )
 
 var (
@@ -17,6 +18,9 @@ var (
 	sessID          string
 	sessDirBase     string
 	sessPortForward bool
+	kubeconfig      string
+	namespace       string
+	namespacePer    string
 )
 
 // var noCleanup bool
@@ -30,9 +34,11 @@ var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "initalize a seasson",
 	Run: func(cmd *cobra.Command, args []string) {
-		sess, err := core.InitSession(sessID, sessDirBase, sessPortForward)
+		checkSessionID()
+		checkNamespacePer()
+		sess, err := core.InitSession(sessID, sessDirBase, sessPortForward, getKubeClient(), namespacePer)
 		if err != nil {
-			log.Fatal(fmt.Sprintf("error initializing session: %w", err))
+			log.Fatal(fmt.Errorf("error initializing session: %w", err))
 		}
 		InitLog(sess)
 		log.Printf("Starting session monitor")
@@ -62,36 +68,84 @@ var doneCmd = &cobra.Command{
 }
 
 func init() {
+	// NB: session-id is required for all commands that operate on a session
+	// (see getSession), but not for commands such as compare.
 	rootCmd.PersistentFlags().StringVarP(&sessID, "session-id", "s", "", "session id")
-	rootCmd.MarkPersistentFlagRequired("session-id")
 	rootCmd.PersistentFlags().StringVarP(&sessDirBase, "session-base-dir", "d", ".", "base directory to store session data")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "quiet output")
 	rootCmd.PersistentFlags().BoolVarP(&sessPortForward, "port-forward", "", false, "use port-forward to connect to monitor")
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "kubeconfig file (default: $KUBECONFIG or ~/.kube/config)")
+	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "namespace (default: the namespace of the session, or of the kubeconfig context for new sessions)")
+	rootCmd.PersistentFlags().StringVar(&namespacePer, "namespace-per", "", "create a dedicated namespace per \"session\" or per \"run\" (only used when the session is created)")
 
 	// session commands
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(doneCmd)
+	rootCmd.AddCommand(gcCmd)
+	rootCmd.AddCommand(collectionsCmd)
 
 	// benchmark commands
 	rootCmd.AddCommand(pod2podCmd)
 	rootCmd.AddCommand(serviceCmd)
+	rootCmd.AddCommand(pairsCmd)
+	rootCmd.AddCommand(pod2nodeCmd)
+	rootCmd.AddCommand(node2podCmd)
+	rootCmd.AddCommand(node2nodeCmd)
+	rootCmd.AddCommand(dnsCmd)
+	rootCmd.AddCommand(churnCmd)
+	rootCmd.AddCommand(matrixCmd)
+
+	// results commands
+	rootCmd.AddCommand(resultsCmd)
+	rootCmd.AddCommand(compareCmd)
 }
 
+func checkSessionID() {
+	if sessID == "" {
+		log.Fatal("session id is required (use --session-id)")
+	}
+}
+
+func checkNamespacePer() {
+	if err := core.ValidateNamespacePer(namespacePer); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// return a kubernetes client based on the given flags
+func getKubeClient() kube.Client {
+	cli, err := kube.NewClient(kubeconfig, namespace)
+	if err != nil {
+		log.Fatal(fmt.Errorf("error creating kubernetes client: %w", err))
+	}
+	return cli
+}
+
+// session, initialized by getSession
+var session *core.Session
+
 // return a session based on the given flags
 func getSession() *core.Session {
-	sess, err := core.NewSession(sessID, sessDirBase, sessPortForward)
+	if session != nil {
+		return session
+	}
+
+	checkSessionID()
+	checkNamespacePer()
+	sess, err := core.NewSession(sessID, sessDirBase, sessPortForward, getKubeClient(), namespace, namespacePer)
 	if err != nil {
 		log.Fatal(fmt.Errorf("error creating session: %w", err))
 	}
 
 	InitLog(sess)
+	session = sess
 	return sess
 }
 
 func InitLog(sess *core.Session) {
 	f, err := sess.OpenLog()
 	if err != nil {
-		log.Fatal(fmt.Sprintf("error openning session log file: %w", err))
+		log.Fatal(fmt.Errorf("error openning session log file: %w", err))
 	}
 
 	if quiet {
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -16,62 +17,131 @@ var (
 	srvAffinity       string
 	noCleanup         bool
 	collectPerf       bool
+	collectorNames    []string
+	collectorParams   []string
 	cliHost           bool
 	srvHost           bool
+	repeat            int
+	warmup            int
+	clients           int
+	clientJob         bool
+	clientStartDelay  time.Duration
+	conntrackSnaps    bool
+	nodeMetrics       time.Duration
 )
 
 // add common benchmark flags
 func addBenchmarkFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&benchmark, "benchmark", "b", "netperf", "benchmark program to use")
+	cmd.Flags().StringVarP(&benchmark, "benchmark", "b", "netperf", "benchmark program to use (netperf, iperf3, http)")
+	addRunFlags(cmd)
+	addNetperfFlags(cmd)
+	addIperfFlags(cmd)
+	addHTTPFlags(cmd)
+}
+
+// add common run flags (i.e., flags that do not depend on the benchmark)
+func addRunFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&runLabel, "run-label", "l", "", "benchmark run label")
 	cmd.Flags().IntVarP(&benchmarkDuration, "duration", "t", 30, "benchmark duration (sec)")
 	cmd.Flags().BoolVar(&noCleanup, "no-cleanup", false, "do not perform cleanup (delete created k8s resources, etc.)")
 	cmd.Flags().StringVar(&cliAffinity, "client-affinity", "different", "client affinity (different: different than server, same: same as server, host=XXXX)")
 	cmd.Flags().StringVar(&srvAffinity, "server-affinity", "none", "server affinity (none, host=XXXX)")
 	cmd.Flags().BoolVar(&collectPerf, "collect-perf", false, "collect performance data using perf")
+	cmd.Flags().StringSliceVar(&collectorNames, "collector", nil, "monitor collector to run on the nodes of the run while the clients execute (perf-record, perf-stat, bpftrace, bcc, ss, ethtool, softirqs)")
+	cmd.Flags().StringArrayVar(&collectorParams, "collector-param", nil, "collector parameter (<collector>.<param>=<value>, e.g., perf-stat.events=cycles)")
 	cmd.Flags().BoolVar(&cliHost, "cli-on-host", false, "run client on host (enables: HostNetwork, HostIPC, HostPID)")
 	cmd.Flags().BoolVar(&srvHost, "srv-on-host", false, "run server on host (enables: HostNetwork, HostIPC, HostPID)")
-	addNetperfFlags(cmd)
+	cmd.Flags().IntVar(&repeat, "repeat", 1, "number of times to execute the client (the server is reused across executions)")
+	cmd.Flags().IntVar(&warmup, "warmup", 0, "number of warmup client executions (results are not aggregated)")
+	cmd.Flags().IntVar(&clients, "clients", 1, "number of concurrent client pods")
+	cmd.Flags().BoolVar(&clientJob, "client-job", false, "use a Job with the clients as parallelism to create the client pods")
+	cmd.Flags().DurationVar(&clientStartDelay, "client-start-delay", time.Second, "delay between releasing the start barrier of the clients and their start time")
+	cmd.Flags().BoolVar(&conntrackSnaps, "conntrack-snapshots", false, "snapshot the conntrack counters of every node (via the monitor) before and after the clients execute")
+	cmd.Flags().DurationVar(&nodeMetrics, "node-metrics-interval", 0, "stream the metrics (cpu, NIC, and TCP counters) of the nodes hosting the run's pods (via the monitor) while the clients execute, sampled at this interval (0: disabled)")
 }
 
-func getRunBenchCtx(defaultRunLabel string, mkdir bool) (*core.RunBenchCtx, error) {
+func getRunBenchCtx(scenario string, defaultRunLabel string, mkdir bool) (*core.RunBenchCtx, error) {
 	var bench core.Benchmark
+	var err error
 
 	switch benchmark {
 	case "netperf":
-		bench = getNetperfBench()
-	case "ipperf":
-		return nil, fmt.Errorf("benchmark NYI: %s", benchmark)
+		bench, err = getNetperfBench()
+		if err != nil {
+			return nil, err
+		}
+	case "iperf3":
+		bench = getIperfBench()
+	case "http":
+		bench = getHTTPBench()
 	default:
 		return nil, fmt.Errorf("unknown benchmark: %s", benchmark)
 	}
 
+	return getRunBenchCtxWith(scenario, defaultRunLabel, bench, mkdir)
+}
+
+// getRunBenchCtxWith returns a run context that uses the given benchmark
+func getRunBenchCtxWith(scenario string, defaultRunLabel string, bench core.Benchmark, mkdir bool) (*core.RunBenchCtx, error) {
 	if runLabel == "" {
 		runLabel = defaultRunLabel
 	}
 
+	if repeat < 1 {
+		return nil, fmt.Errorf("invalid --repeat %d: at least one client execution is needed", repeat)
+	}
+	if warmup < 0 {
+		return nil, fmt.Errorf("invalid --warmup %d: cannot be negative", warmup)
+	}
+	if clients < 1 {
+		return nil, fmt.Errorf("invalid --clients %d: at least one client is needed", clients)
+	}
+	if nodeMetrics < 0 {
+		return nil, fmt.Errorf("invalid --node-metrics-interval %s: cannot be negative", nodeMetrics)
+	}
+	if nodeMetrics > 0 && nodeMetrics < core.MinNodeMetricsInterval {
+		return nil, fmt.Errorf("invalid --node-metrics-interval %s: the minimum is %s", nodeMetrics, core.MinNodeMetricsInterval)
+	}
+
+	collectors, err := core.ParseCollectors(collectorNames, collectorParams)
+	if err != nil {
+		return nil, err
+	}
+
 	var cliSpec, srvSpec core.ContainerSpec
 
+	// host network scenarios determine where the client and server run
+	useCliHost, useSrvHost := cliHost, srvHost
+	if c, s, ok := core.HostScenarioSpec(scenario); ok {
+		useCliHost, useSrvHost = c, s
+	}
+
 	cliSpec.Affinity = cliAffinity
-	if cliHost {
+	if useCliHost {
 		cliSpec.SetHostAll()
 	}
 	srvSpec.Affinity = srvAffinity
-	if srvHost {
+	if useSrvHost {
 		srvSpec.SetHostAll()
 	}
 
 	sess := getSession()
 	ctx := core.NewRunBenchCtx(
 		sess,
+		scenario,
 		runLabel,
 		&cliSpec,
 		&srvSpec,
 		!noCleanup,
 		bench,
 		collectPerf)
+	ctx.SetRepetitions(repeat, warmup)
+	ctx.SetClients(clients, clientJob, clientStartDelay)
+	ctx.SetCollectors(collectors)
+	ctx.SetConntrackSnapshots(conntrackSnaps)
+	ctx.SetNodeMetrics(nodeMetrics)
+	ctx.SetInterruptContext(getInterruptContext())
 
-	var err error = nil
 	if mkdir {
 		err = ctx.MakeDir()
 		if err != nil {
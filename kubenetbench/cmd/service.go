@@ -1,40 +1,85 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/cilium/kubenetbench/kubenetbench/core"
 )
 
-var serviceTypeArg string
+var (
+	serviceTypeArg     string
+	nodePortTargetArg  string
+	lbIPArg            string
+	replicasArg        int
+	sessionAffinityArg string
+	externalTrafficArg string
+	internalTrafficArg string
+)
 
 var serviceCmd = &cobra.Command{
 	Use:   "service",
 	Short: "service network benchmark run",
 	Run: func(cmd *cobra.Command, args []string) {
-
-		if serviceTypeArg != "ClusterIP" {
-			log.Fatal("invalid policy: ", serviceTypeArg)
-		}
-
-		runctx, err := getRunBenchCtx(serviceTypeArg, true)
-		if err != nil {
-			log.Fatal("initializing run context failed:", err)
-		}
-		st := core.ServiceSt{
-			RunBenchCtx: runctx,
-			ServiceType: serviceTypeArg,
-		}
-		err = st.Execute()
+		err := runService()
 		if err != nil {
-			log.Fatal("service execution failed:", err)
+			log.Fatal(err)
 		}
 	},
 }
 
+// newServiceSt returns the service scenario state for the given run context,
+// based on the service flags
+func newServiceSt(runctx *core.RunBenchCtx) core.ServiceSt {
+	return core.ServiceSt{
+		RunBenchCtx:           runctx,
+		ServiceType:           serviceTypeArg,
+		NodePortTarget:        nodePortTargetArg,
+		LoadBalancerIP:        lbIPArg,
+		Replicas:              replicasArg,
+		SessionAffinity:       sessionAffinityArg,
+		ExternalTrafficPolicy: externalTrafficArg,
+		InternalTrafficPolicy: internalTrafficArg,
+	}
+}
+
+func runService() error {
+	runctx, err := getRunBenchCtx("service", serviceTypeArg, false)
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+	st := newServiceSt(runctx)
+	err = st.Validate()
+	if err != nil {
+		return err
+	}
+	err = runctx.MakeDir()
+	if err != nil {
+		return fmt.Errorf("initializing run context failed: %w", err)
+	}
+
+	err = st.Execute()
+	if err != nil {
+		return fmt.Errorf("service execution failed: %w", err)
+	}
+	return nil
+}
+
+// add service flags
+func addServiceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&serviceTypeArg, "type", "ClusterIP", fmt.Sprintf("service type (%s)", strings.Join(core.ServiceTypes, ", ")))
+	cmd.Flags().StringVar(&nodePortTargetArg, "nodeport-node", "backend", "node that clients target for NodePort services (backend: node of the backend, other: a different node, host=XXXX)")
+	cmd.Flags().StringVar(&lbIPArg, "lb-ip", "", "address of LoadBalancer services (default: the address allocated by the load balancer)")
+	cmd.Flags().IntVar(&replicasArg, "replicas", 1, "number of service backends")
+	cmd.Flags().StringVar(&sessionAffinityArg, "session-affinity", "None", "service session affinity (None, ClientIP)")
+	cmd.Flags().StringVar(&externalTrafficArg, "external-traffic-policy", "", "external traffic policy of NodePort and LoadBalancer services (Cluster, Local)")
+	cmd.Flags().StringVar(&internalTrafficArg, "internal-traffic-policy", "", "internal traffic policy of the service (Cluster, Local)")
+}
+
 func init() {
 	addBenchmarkFlags(serviceCmd)
-	serviceCmd.Flags().StringVar(&serviceTypeArg, "type", "ClusterIP", "service type (ClusterIP)")
+	addServiceFlags(serviceCmd)
 }
@@ -7,14 +7,16 @@ import (
 	"github.com/cilium/kubenetbench/utils"
 )
 
-// client on the same node as the server
-func cliAffinitySame(pw *utils.PrefixWriter) {
+// podAffinityTerm writes a required pod (anti-)affinity term for the server
+// pods. match are additional label matches (key, value pairs) for the server
+// pods (e.g., to select the server of a client/server pair).
+func podAffinityTerm(pw *utils.PrefixWriter, affinityType string, match ...string) {
 	l := func(s string) {
 		pw.AppendNewLineOrDie(s)
 	}
 
 	l(`affinity:`)
-	l(`   podAffinity:`)
+	l(fmt.Sprintf(`   %s:`, affinityType))
 	l(`       requiredDuringSchedulingIgnoredDuringExecution:`)
 	l(`       - labelSelector:`)
 	l(`            matchExpressions:`)
@@ -22,25 +24,23 @@ func cliAffinitySame(pw *utils.PrefixWriter) {
 	l(`              operator: In`)
 	l(`              values:`)
 	l(`              - srv`)
+	for i := 0; i+1 < len(match); i += 2 {
+		l(fmt.Sprintf(`            - key: %s`, match[i]))
+		l(`              operator: In`)
+		l(`              values:`)
+		l(fmt.Sprintf(`              - "%s"`, match[i+1]))
+	}
 	l(`         topologyKey: "kubernetes.io/hostname"`)
 }
 
 // client on the same node as the server
-func cliAffinityOther(pw *utils.PrefixWriter) {
-	l := func(s string) {
-		pw.AppendNewLineOrDie(s)
-	}
+func cliAffinitySame(pw *utils.PrefixWriter, match ...string) {
+	podAffinityTerm(pw, "podAffinity", match...)
+}
 
-	l(`affinity:`)
-	l(`   podAntiAffinity:`)
-	l(`       requiredDuringSchedulingIgnoredDuringExecution:`)
-	l(`       - labelSelector:`)
-	l(`            matchExpressions:`)
-	l(`            - key: role`)
-	l(`              operator: In`)
-	l(`              values:`)
-	l(`              - srv`)
-	l(`         topologyKey: "kubernetes.io/hostname"`)
+// client on a different node than the server
+func cliAffinityOther(pw *utils.PrefixWriter, match ...string) {
+	podAffinityTerm(pw, "podAntiAffinity", match...)
 }
 
 //
@@ -49,35 +49,66 @@ func affinityHost(host string, pw *utils.PrefixWriter) {
 	pw.AppendNewLineOrDie(fmt.Sprintf(`     kubernetes.io/hostname: %s`, host))
 }
 
-func (c *RunBenchCtx) cliAffinityWrite(pw *utils.PrefixWriter, params map[string]interface{}) {
+// cliAffinityWrite writes the client affinity. If params include a pair
+// index (see PairsSt), the affinity is relative to the server of the pair.
+func (c *RunBenchCtx) cliAffinityWrite(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	var match []string
+	if pair, ok := params["pairIdx"]; ok {
+		match = []string{runIdLabel, c.runid, pairIdxLabel, fmt.Sprintf("%v", pair)}
+	}
+
 	cliAffinity := c.cliSpec.Affinity
 	switch {
 	case cliAffinity == "none":
-		return
+		return nil
 	case cliAffinity == "same":
-		cliAffinitySame(pw)
+		cliAffinitySame(pw, match...)
 	case cliAffinity == "different":
-		cliAffinityOther(pw)
+		cliAffinityOther(pw, match...)
 	case strings.HasPrefix(cliAffinity, "host="):
 		host := strings.TrimPrefix(cliAffinity, "host=")
 		affinityHost(host, pw)
 
 	default:
-		panic(fmt.Sprintf("Unrecognized client affinity: %s", cliAffinity))
+		return fmt.Errorf("unrecognized client affinity: %s", cliAffinity)
 	}
+	return nil
 }
 
-func (c *RunBenchCtx) srvAffinityWrite(pw *utils.PrefixWriter, params map[string]interface{}) {
+func (c *RunBenchCtx) srvAffinityWrite(pw *utils.PrefixWriter, params map[string]interface{}) error {
 	srvAffinity := c.srvSpec.Affinity
 
 	switch {
 	case srvAffinity == "none":
-		return
+		return nil
 	case strings.HasPrefix(srvAffinity, "host="):
 		host := strings.TrimPrefix(srvAffinity, "host=")
 		affinityHost(host, pw)
 
 	default:
-		panic(fmt.Sprintf("Unrecognized server affinity: %s", srvAffinity))
+		return fmt.Errorf("unrecognized server affinity: %s", srvAffinity)
+	}
+	return nil
+}
+
+// validateAffinity checks that the client and server affinities are valid, so
+// that invalid affinities are reported before creating any objects
+func (c *RunBenchCtx) validateAffinity() error {
+	cliAffinity := c.cliSpec.Affinity
+	switch {
+	case cliAffinity == "none", cliAffinity == "same", cliAffinity == "different":
+	case strings.HasPrefix(cliAffinity, "host="):
+	default:
+		return fmt.Errorf("unrecognized client affinity: %q", cliAffinity)
+	}
+
+	srvAffinity := c.srvSpec.Affinity
+	switch {
+	case srvAffinity == "none":
+	case strings.HasPrefix(srvAffinity, "host="):
+	default:
+		return fmt.Errorf("unrecognized server affinity: %q", srvAffinity)
 	}
+
+	return nil
 }
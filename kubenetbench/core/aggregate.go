@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// iteration directories are: iter-NN (warmup-NN for warmup iterations)
+var iterDirRegEx = regexp.MustCompile(`^iter-(\d+)$`)
+
+func iterDirName(warmup bool, i int) string {
+	if warmup {
+		return fmt.Sprintf("warmup-%02d", i)
+	}
+	return fmt.Sprintf("iter-%02d", i)
+}
+
+// MetricSummary summarizes the values of a metric across iterations
+// Stddev and the confidence interval are nil if there are not enough values.
+type MetricSummary struct {
+	Unit   string   `json:"unit,omitempty"`
+	N      int      `json:"n"`
+	Mean   float64  `json:"mean"`
+	Median float64  `json:"median"`
+	Min    float64  `json:"min"`
+	Max    float64  `json:"max"`
+	Stddev *float64 `json:"stddev,omitempty"`
+	CI95Lo *float64 `json:"ci95_lo,omitempty"`
+	CI95Hi *float64 `json:"ci95_hi,omitempty"`
+}
+
+// AggregateResult is the aggregation of the results of multiple iterations
+type AggregateResult struct {
+	Tool       string                    `json:"tool"`
+	Test       string                    `json:"test,omitempty"`
+	Iterations []string                  `json:"iterations"`
+	Metrics    map[string]*MetricSummary `json:"metrics"`
+}
+
+func floatOrNil(v float64) *float64 {
+	if math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
+
+func summarize(vals []float64, unit string) *MetricSummary {
+	s := append([]float64(nil), vals...)
+	sort.Float64s(s)
+	lo, hi := utils.MeanCI95(s)
+	return &MetricSummary{
+		Unit:   unit,
+		N:      len(s),
+		Mean:   utils.Mean(s),
+		Median: utils.Median(s),
+		Min:    s[0],
+		Max:    s[len(s)-1],
+		Stddev: floatOrNil(utils.Stddev(s)),
+		CI95Lo: floatOrNil(lo),
+		CI95Hi: floatOrNil(hi),
+	}
+}
+
+// AggregateResults aggregates the results of multiple iterations
+func AggregateResults(names []string, results []*BenchResult) *AggregateResult {
+	ret := &AggregateResult{
+		Iterations: names,
+		Metrics:    make(map[string]*MetricSummary),
+	}
+
+	samples := make(map[string]*metricSamples)
+	for _, res := range results {
+		ret.Tool = res.Tool
+		ret.Test = res.Test
+		for name, m := range res.Metrics {
+			ms, ok := samples[name]
+			if !ok {
+				ms = &metricSamples{unit: m.Unit}
+				samples[name] = ms
+			}
+			ms.vals = append(ms.vals, m.Value)
+		}
+	}
+
+	for name, ms := range samples {
+		ret.Metrics[name] = summarize(ms.vals, ms.unit)
+	}
+	return ret
+}
+
+// meanResult returns a result with the mean values of the aggregated metrics
+func (agg *AggregateResult) meanResult() *BenchResult {
+	res := NewBenchResult(agg.Tool, agg.Test)
+	res.Info["iterations"] = strconv.Itoa(len(agg.Iterations))
+	for name, ms := range agg.Metrics {
+		res.Metrics[name] = Metric{Value: ms.Mean, Unit: ms.Unit}
+	}
+	return res
+}
+
+// saveAggregate aggregates the results of the given iteration directories.
+// It writes the aggregation in aggregate.json, and a result with the mean
+// values of the metrics in result.json.
+func (r *RunBenchCtx) saveAggregate(iterDirs []string) error {
+	var names []string
+	var results []*BenchResult
+	for _, dir := range iterDirs {
+		res, err := LoadBenchResult(filepath.Join(dir, "result.json"))
+		if err != nil {
+			log.Printf("ignoring iteration %s: %s", dir, err)
+			continue
+		}
+		names = append(names, filepath.Base(dir))
+		results = append(results, res)
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no iteration results to aggregate")
+	}
+
+	agg := AggregateResults(names, results)
+	fname := fmt.Sprintf("%s/aggregate.json", r.getDir())
+	log.Printf("Writing %s", fname)
+	err := writeJSONFile(fname, agg)
+	if err != nil {
+		return err
+	}
+
+	return writeJSONFile(fmt.Sprintf("%s/result.json", r.getDir()), agg.meanResult())
+}
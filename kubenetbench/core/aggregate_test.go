@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestAggregateResults(t *testing.T) {
+	var results []*BenchResult
+	for _, v := range []float64{100, 110, 90, 120} {
+		res := NewBenchResult("netperf", "tcp_rr")
+		res.Metrics[MetricThroughput] = Metric{v, "Trans/s"}
+		results = append(results, res)
+	}
+	// missing in the last iteration
+	results[3].Metrics[MetricP90Latency] = Metric{10, "us"}
+
+	agg := AggregateResults([]string{"iter-00", "iter-01", "iter-02", "iter-03"}, results)
+	tput := agg.Metrics[MetricThroughput]
+	if tput.N != 4 || tput.Mean != 105 || tput.Median != 105 || tput.Min != 90 || tput.Max != 120 {
+		t.Errorf("unexpected throughput summary: %+v", tput)
+	}
+	if tput.Stddev == nil || tput.CI95Lo == nil || *tput.CI95Lo >= 105 || *tput.CI95Hi <= 105 {
+		t.Errorf("unexpected throughput stddev/ci: %+v", tput)
+	}
+
+	lat := agg.Metrics[MetricP90Latency]
+	if lat.N != 1 || lat.Stddev != nil || lat.CI95Lo != nil {
+		t.Errorf("unexpected latency summary: %+v", lat)
+	}
+
+	res := agg.meanResult()
+	if v, _ := res.GetMetric(MetricThroughput); v != 105 || res.Info["iterations"] != "4" {
+		t.Errorf("unexpected mean result: %+v", res)
+	}
+}
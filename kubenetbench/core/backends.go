@@ -0,0 +1,218 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// SrvConnCounter is implemented by benchmarks that can count the client
+// connections (tests) that a server handled, based on the server logs
+type SrvConnCounter interface {
+	CountSrvConnections(r io.Reader) (int, error)
+}
+
+// Backends of benchmarks that do not implement SrvConnCounter (e.g., netperf,
+// whose server does not log connections) have a sidecar that reports the TCP
+// connections accepted in the network namespace of the pod (see
+// knb-tcp-stats.sh). The sidecar is queried (via port-forward) before and
+// after the clients execute.
+const (
+	tcpStatsContainer = "tcp-stats"
+	tcpStatsPort      = 7071
+)
+
+// timeout for querying the tcp-stats sidecar of a backend. NB: the sidecar is
+// queried before the clients start, so this is kept short.
+var tcpStatsTimeout = 5 * time.Second
+
+// BackendInfo describes a backend (server pod) of a run, and the traffic it
+// handled. It is stored in backends.json.
+type BackendInfo struct {
+	Pod  string `json:"pod"`
+	Node string `json:"node,omitempty"`
+	IP   string `json:"ip,omitempty"`
+	Log  string `json:"log"`
+	// number of client connections handled by the backend (nil if the
+	// benchmark cannot determine it from the server logs)
+	Connections *int `json:"connections,omitempty"`
+	// number of TCP connections accepted by the backend while the clients
+	// executed (nil if the backend has no tcp-stats sidecar). NB: a netperf
+	// TCP test uses two connections (control and data).
+	TCPAccepted *int64 `json:"tcp_accepted,omitempty"`
+}
+
+// useTCPStats returns true if backends have a tcp-stats sidecar: when the
+// benchmark cannot count connections based on the server logs, and the server
+// has its own network namespace
+func (r *RunBenchCtx) useTCPStats() bool {
+	_, ok := r.benchmark.(SrvConnCounter)
+	return !ok && !r.srvSpec.HostNetwork
+}
+
+// tcpStatsWrite writes the tcp-stats sidecar of backends (if enabled)
+func (r *RunBenchCtx) tcpStatsWrite(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	if !r.useTCPStats() {
+		return nil
+	}
+
+	pw.AppendNewLineOrDie(fmt.Sprintf(`- name: %s`, tcpStatsContainer))
+	pw.AppendNewLineOrDie(`  image: cilium/kubenetbench`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  command: ["socat", "TCP-LISTEN:%d,reuseaddr,fork", "EXEC:scripts/knb-tcp-stats.sh"]`, tcpStatsPort))
+	return nil
+}
+
+// podTCPAccepted returns the number of TCP connections accepted by a backend,
+// as reported by its tcp-stats sidecar. It includes the connection of the
+// query.
+func (r *RunBenchCtx) podTCPAccepted(pod *kube.Pod) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tcpStatsTimeout)
+	defer cancel()
+
+	lport, err := r.kube.PortForward(ctx, pod.Name, tcpStatsPort)
+	if err != nil {
+		return 0, fmt.Errorf("pod %s: port-forward failed: %w", pod.Name, err)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("localhost:%d", lport))
+	if err != nil {
+		return 0, fmt.Errorf("pod %s: failed to connect to %s: %w", pod.Name, tcpStatsContainer, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return 0, fmt.Errorf("pod %s: failed to read TCP stats: %w", pod.Name, err)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("pod %s: invalid TCP stats %q", pod.Name, line)
+	}
+	return n, nil
+}
+
+// backendsTCPAccepted returns the number of TCP connections accepted by each
+// of the given backends (by pod name), if they have a tcp-stats sidecar
+func (r *RunBenchCtx) backendsTCPAccepted(pods []kube.Pod) map[string]int64 {
+	if !r.useTCPStats() {
+		return nil
+	}
+
+	ret := make(map[string]int64, len(pods))
+	for i := range pods {
+		n, err := r.podTCPAccepted(&pods[i])
+		if err != nil {
+			log.Printf("failed to get TCP stats of backend: %s", err)
+			continue
+		}
+		ret[pods[i].Name] = n
+	}
+	return ret
+}
+
+// srvContainer returns the name of the server container of a backend: the
+// first container of the pod (see genSrvYaml), which may have sidecars
+func srvContainer(pod *kube.Pod) string {
+	if len(pod.ContainerNames) == 0 {
+		return ""
+	}
+	return pod.ContainerNames[0]
+}
+
+// backendLogName returns the name of the log file of backend i. A single
+// backend uses srv.log.
+func backendLogName(i int, n int) string {
+	if n == 1 {
+		return "srv.log"
+	}
+	return fmt.Sprintf("srv-%02d.log", i)
+}
+
+// saveBackends saves the logs of every backend (server pod) that matches the
+// selector, and writes backends.json with the number of connections that each
+// backend handled (if supported by the benchmark, see SrvConnCounter), or the
+// number of TCP connections it accepted since tcpBefore was taken (see
+// backendsTCPAccepted).
+func (r *RunBenchCtx) saveBackends(selector string, tcpBefore map[string]int64) error {
+	ctx, cancel := kubeCtx()
+	pods, err := r.kube.GetPods(ctx, selector, "")
+	cancel()
+	if err != nil {
+		return fmt.Errorf("failed to get backend pods: %w", err)
+	}
+
+	counter, _ := r.benchmark.(SrvConnCounter)
+	var tcpAfter map[string]int64
+	if tcpBefore != nil {
+		tcpAfter = r.backendsTCPAccepted(pods)
+	}
+	backends := make([]BackendInfo, 0, len(pods))
+	for i := range pods {
+		b := BackendInfo{
+			Pod:  pods[i].Name,
+			Node: pods[i].Node,
+			IP:   pods[i].IP,
+			Log:  backendLogName(i, len(pods)),
+		}
+		logFname := filepath.Join(r.getDir(), b.Log)
+		if err := r.KubeSavePodLogs(b.Pod, srvContainer(&pods[i]), logFname); err != nil {
+			log.Printf("failed to save backend logs: %s", err)
+			continue
+		}
+
+		if counter != nil {
+			f, err := os.Open(logFname)
+			if err != nil {
+				return err
+			}
+			n, err := counter.CountSrvConnections(f)
+			f.Close()
+			if err != nil {
+				log.Printf("failed to count connections of backend %s: %s", b.Pod, err)
+			} else {
+				b.Connections = &n
+				log.Printf("backend %s (node: %s): %d connection(s)", b.Pod, b.Node, n)
+			}
+		}
+
+		before, ok1 := tcpBefore[b.Pod]
+		after, ok2 := tcpAfter[b.Pod]
+		if ok1 && ok2 {
+			// NB: the query of tcpAfter is not a client connection
+			n := after - before - 1
+			b.TCPAccepted = &n
+			log.Printf("backend %s (node: %s): %d TCP connection(s)", b.Pod, b.Node, n)
+		}
+		backends = append(backends, b)
+	}
+
+	fname := fmt.Sprintf("%s/backends.json", r.getDir())
+	log.Printf("Writing %s", fname)
+	return writeJSONFile(fname, backends)
+}
+
+// countLinesWithPrefix counts the lines of r that start with prefix
+func countLinesWithPrefix(r io.Reader, prefix string) (int, error) {
+	n := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if strings.HasPrefix(strings.TrimSpace(scanner.Text()), prefix) {
+			n++
+		}
+	}
+	return n, scanner.Err()
+}
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// Client containers that use a start barrier execute their command with the
+// knb-barrier wrapper, which an init container installs in a volume of the
+// pod (so that it is available regardless of the client image). The wrapper
+// blocks until the coordinator (kubenetbench) connects to it and sends the
+// common start time. It waits until the start time, replies with the time it
+// executes the client command, and executes it. These exec times are recorded
+// in the run manifest (see ClientStart).
+//
+// NB: clients (e.g., on the host network) on the same node need different
+// barrier ports, so the client index is added to the barrier port.
+const (
+	barrierContainer = "install-barrier"
+	barrierVolume    = "knb-barrier"
+	barrierDir       = "/knb"
+	barrierPort      = 7070
+)
+
+// NB: this is a variable so that tests can modify it
+var (
+	// time for connecting to the barriers and for the release replies
+	barrierTimeout = time.Minute
+)
+
+// ClientStart is the start time of a client pod, as reported by its start
+// barrier (i.e., the time the barrier executed the client command)
+type ClientStart struct {
+	Iteration string    `json:"iteration,omitempty"`
+	Pod       string    `json:"pod"`
+	Node      string    `json:"node,omitempty"`
+	Start     time.Time `json:"start"`
+	// difference between the start time of the pod and the earliest start
+	// time of all the client pods (ms)
+	Skew float64 `json:"skew_ms"`
+}
+
+// useBarrier returns true if clients use a start barrier: when there are
+// multiple clients (so that they start at the same time), or when using
+// monitor collections (e.g., perf), conntrack snapshots, or node metrics (so
+// that the client starts after the collection, the snapshot, or the node
+// metrics streams start).
+func (r *RunBenchCtx) useBarrier() bool {
+	return r.multiClient() || r.collecting() || r.conntrack || r.nodeMetrics > 0
+}
+
+// barrierPortFor returns the barrier port for the client with the given
+// parameters (or the given pod labels)
+func barrierPortFor(params map[string]interface{}) int {
+	if idx, ok := params["clientIdx"].(int); ok {
+		return barrierPort + idx
+	}
+	return barrierPort
+}
+
+func podBarrierPort(pod *kube.Pod) int {
+	if idx, err := strconv.Atoi(pod.Labels[clientIdxLabel]); err == nil {
+		return barrierPort + idx
+	}
+	return barrierPort
+}
+
+// cliBarrierWrite writes the volume and the init container of the client
+// that install the start barrier wrapper (if enabled)
+func cliBarrierWrite(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	if barrier, _ := params["barrier"].(bool); !barrier {
+		return nil
+	}
+
+	pw.AppendNewLineOrDie(`volumes:`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`- name: %s`, barrierVolume))
+	pw.AppendNewLineOrDie(`  emptyDir: {}`)
+	pw.AppendNewLineOrDie(`initContainers:`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`- name: %s`, barrierContainer))
+	pw.AppendNewLineOrDie(`  image: cilium/kubenetbench`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  command: ["cp", "/usr/bin/knb-barrier", "%s/"]`, barrierDir))
+	pw.AppendNewLineOrDie(`  volumeMounts:`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  - name: %s`, barrierVolume))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`    mountPath: %s`, barrierDir))
+	return nil
+}
+
+// cliCommandWrite writes the command of a client container. Clients that use
+// a start barrier execute the command with the barrier wrapper (see
+// cliBarrierWrite).
+func cliCommandWrite(pw *utils.PrefixWriter, params map[string]interface{}, command string) {
+	if barrier, _ := params["barrier"].(bool); !barrier {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`command: ["%s"]`, command))
+		return
+	}
+
+	pw.AppendNewLineOrDie(`volumeMounts:`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`- name: %s`, barrierVolume))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  mountPath: %s`, barrierDir))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`command: ["%s/knb-barrier", "-port", "%d", "--", "%s"]`,
+		barrierDir, barrierPortFor(params), command))
+}
+
+// podAtBarrier returns true if the client of the pod is blocked at the start
+// barrier: the client container (i.e., the barrier wrapper) is running
+func podAtBarrier(pod *kube.Pod) bool {
+	return pod.Phase == "Running"
+}
+
+// waitForBarrier waits until all the clients are blocked at the start
+// barrier, and returns the client pods
+func (r *RunBenchCtx) waitForBarrier() ([]kube.Pod, error) {
+	pods, err := r.waitForPods(r.getCliSelector(), "clients at start barrier", r.numClients(), podStartTimeout, podAtBarrier)
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return pods, nil
+}
+
+// barrierConn is a connection to the start barrier of a client pod
+type barrierConn struct {
+	pod  *kube.Pod
+	conn net.Conn
+}
+
+// connectBarrier connects to the start barrier of a pod (via port-forward)
+func (r *RunBenchCtx) connectBarrier(ctx context.Context, pod *kube.Pod) (*barrierConn, error) {
+	lport, err := r.kube.PortForward(ctx, pod.Name, podBarrierPort(pod))
+	if err != nil {
+		return nil, fmt.Errorf("pod %s: port-forward failed: %w", pod.Name, err)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("localhost:%d", lport))
+	if err != nil {
+		return nil, fmt.Errorf("pod %s: failed to connect to start barrier: %w", pod.Name, err)
+	}
+	return &barrierConn{pod: pod, conn: conn}, nil
+}
+
+// release sends the start time to the barrier, and returns the time the
+// barrier executed the client command
+func (bc *barrierConn) release(start time.Time, deadline time.Time) (time.Time, error) {
+	bc.conn.SetDeadline(deadline)
+	_, err := fmt.Fprintf(bc.conn, "%d\n", start.UnixNano())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("pod %s: failed to release start barrier: %w", bc.pod.Name, err)
+	}
+
+	line, err := bufio.NewReader(bc.conn).ReadString('\n')
+	if err != nil {
+		return time.Time{}, fmt.Errorf("pod %s: failed to read start time: %w", bc.pod.Name, err)
+	}
+	ns, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("pod %s: invalid start time %q", bc.pod.Name, line)
+	}
+	return time.Unix(0, ns), nil
+}
+
+// releaseBarrier releases the start barrier of the given client pods. It first
+// connects to all the barriers, and then sends them a common start time
+// (startDelay after the connections are established). It returns the actual
+// start times of the clients (see recordClientStarts).
+func (r *RunBenchCtx) releaseBarrier(pods []kube.Pod) ([]ClientStart, error) {
+	ctx, cancel := context.WithTimeout(r.interruptCtx, barrierTimeout)
+	defer cancel()
+
+	conns := make([]*barrierConn, len(pods))
+	errs := make([]error, len(pods))
+	var wg sync.WaitGroup
+	for i := range pods {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i], errs[i] = r.connectBarrier(ctx, &pods[i])
+		}(i)
+	}
+	wg.Wait()
+	defer func() {
+		for _, bc := range conns {
+			if bc != nil {
+				bc.conn.Close()
+			}
+		}
+	}()
+	for _, err := range errs {
+		if err != nil {
+			return nil, r.barrierError(err)
+		}
+	}
+
+	start := time.Now().Add(r.startDelay)
+	deadline, _ := ctx.Deadline()
+	log.Printf("releasing start barrier of %d client(s) (start time: %s)", len(pods), start.Format(time.RFC3339Nano))
+	execs := make([]time.Time, len(pods))
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			execs[i], errs[i] = conns[i].release(start, deadline)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, r.barrierError(err)
+		}
+	}
+
+	var minExec, maxExec time.Time
+	for i := range execs {
+		if i == 0 || execs[i].Before(minExec) {
+			minExec = execs[i]
+		}
+		if i == 0 || execs[i].After(maxExec) {
+			maxExec = execs[i]
+		}
+	}
+	log.Printf("clients released (start skew: %s)", maxExec.Sub(minExec))
+
+	starts := make([]ClientStart, len(pods))
+	for i := range pods {
+		starts[i] = ClientStart{
+			Pod:   pods[i].Name,
+			Node:  pods[i].Node,
+			Start: execs[i],
+			Skew:  float64(execs[i].Sub(minExec)) / float64(time.Millisecond),
+		}
+	}
+	return starts, nil
+}
+
+// recordClientStarts records the given client starts (see releaseBarrier) in
+// the run manifest. dir is the directory of the client (iteration).
+func (r *RunBenchCtx) recordClientStarts(starts []ClientStart, dir string) {
+	if r.manifest == nil {
+		return
+	}
+
+	iteration := ""
+	if dir != r.getDir() {
+		iteration = filepath.Base(dir)
+	}
+	for _, cs := range starts {
+		cs.Iteration = iteration
+		r.manifest.ClientStarts = append(r.manifest.ClientStarts, cs)
+	}
+
+	if err := r.writeManifest(); err != nil {
+		log.Printf("failed to write run manifest: %s", err)
+	}
+}
+
+// barrierError maps barrier errors to ErrClientFailed (or ErrInterrupted)
+func (r *RunBenchCtx) barrierError(err error) error {
+	if ierr := r.interrupted(); ierr != nil {
+		return ierr
+	}
+	return fmt.Errorf("%w: start barrier: %s", ErrClientFailed, err)
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+// setAtBarrier sets the state of a client pod that blocks at the start
+// barrier: the barrier wrapper is running in the client container
+func setAtBarrier(pod *kube.Pod) {
+	pod.Phase = "Running"
+	pod.InitContainers = []kube.ContainerStatus{{Name: barrierContainer, Terminated: "Completed"}}
+	pod.Containers = []kube.ContainerStatus{{Name: "cli", Running: true}}
+}
+
+// cliBarrierHook is a pod hook for clients that block at the start barrier
+func cliBarrierHook(pod *kube.Pod) string {
+	if pod.Labels["role"] == "cli" {
+		setAtBarrier(pod)
+		return netperfRROutput
+	}
+	return ""
+}
+
+// fakeBarrier emulates the start barrier of the client pods: port-forwards
+// return the port of a listener that behaves like the barrier wrapper, and the
+// pod terminates after it is released. Other port-forwards (e.g., to tcp-stats
+// sidecars) are handled by the previous hook.
+func fakeBarrier(t *testing.T, fake *kube.FakeClient) {
+	next := fake.PortForwardHook
+	fake.PortForwardHook = func(podName string, port int) (int, error) {
+		if port == tcpStatsPort && next != nil {
+			return next(podName, port)
+		}
+		l, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			return 0, err
+		}
+		go func() {
+			defer l.Close()
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil {
+				return
+			}
+			ns, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+			if err != nil {
+				return
+			}
+			time.Sleep(time.Until(time.Unix(0, ns)))
+			fmt.Fprintf(conn, "%d\n", time.Now().UnixNano())
+
+			fake.UpdatePod("role=cli", func(pod *kube.Pod) {
+				if pod.Name == podName {
+					pod.Containers = []kube.ContainerStatus{{Name: "cli", Terminated: "Completed"}}
+					pod.Phase = "Succeeded"
+				}
+			})
+		}()
+		return l.Addr().(*net.TCPAddr).Port, nil
+	}
+}
+
+// newTestBarrierPod2Pod creates a pod2pod run whose clients block at the
+// start barrier
+func newTestBarrierPod2Pod(t *testing.T) (Pod2PodSt, *kube.FakeClient) {
+	st, fake := newTestPod2Pod(t, cliBarrierHook)
+	fakeBarrier(t, fake)
+	return st, fake
+}
+
+func TestStartBarrier(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	st, _ := newTestBarrierPod2Pod(t)
+	st.RunBenchCtx.SetClients(3, false, 50*time.Millisecond)
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadRunManifest(st.RunBenchCtx.getDir() + "/run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.ClientStarts) != 3 {
+		t.Fatalf("got %d client starts while expected 3", len(m.ClientStarts))
+	}
+	minSkew := math.Inf(1)
+	for _, cs := range m.ClientStarts {
+		if cs.Pod == "" || cs.Start.IsZero() {
+			t.Errorf("invalid client start: %+v", cs)
+		}
+		if cs.Skew < 0 || cs.Skew > 1000 {
+			t.Errorf("unexpected skew: %+v", cs)
+		}
+		minSkew = math.Min(minSkew, cs.Skew)
+	}
+	// the skew is relative to the earliest client
+	if minSkew != 0 {
+		t.Errorf("got minimum skew %f while expected 0", minSkew)
+	}
+
+	// clients execute their command with the barrier wrapper, which listens
+	// on a different port for every client
+	data, err := ioutil.ReadFile(st.RunBenchCtx.getDir() + "/client.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(string(data), "---\n")
+	if len(docs) != 3 {
+		t.Fatalf("got %d client objects while expected 3", len(docs))
+	}
+	for i, doc := range docs {
+		pod := &corev1.Pod{}
+		if err := yaml.Unmarshal([]byte(doc), pod); err != nil {
+			t.Fatalf("failed to parse client %d: %s", i, err)
+		}
+		expected := []string{barrierDir + "/knb-barrier", "-port", strconv.Itoa(barrierPort + i), "--", "netperf"}
+		if len(pod.Spec.InitContainers) != 1 || len(pod.Spec.Volumes) != 1 || len(pod.Spec.Containers) != 1 {
+			t.Fatalf("client %d: unexpected pod spec: %+v", i, pod.Spec)
+		}
+		cli := &pod.Spec.Containers[0]
+		if !reflect.DeepEqual(cli.Command, expected) || len(cli.VolumeMounts) != 1 || cli.VolumeMounts[0].Name != pod.Spec.Volumes[0].Name {
+			t.Errorf("client %d: unexpected client container: %+v", i, cli)
+		}
+	}
+}
+
+func TestStartBarrierFailure(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	st, fake := newTestPod2Pod(t, cliBarrierHook)
+	fake.PortForwardHook = func(podName string, port int) (int, error) {
+		return 0, fmt.Errorf("port-forward to %s:%d failed", podName, port)
+	}
+	st.RunBenchCtx.SetClients(2, false, 0)
+
+	err := st.Execute()
+	if !errors.Is(err, ErrClientFailed) {
+		t.Fatalf("got error %v while expected %v", err, ErrClientFailed)
+	}
+	if !strings.Contains(err.Error(), "start barrier") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
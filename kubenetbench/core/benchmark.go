@@ -1,17 +1,33 @@
 package core
 
 import (
+	"errors"
+	"io"
+
 	"github.com/cilium/kubenetbench/utils"
 )
 
+// errServerIPUndefined is returned when writing a client without a server
+// address (see Benchmark.WriteCliContainerYaml)
+var errServerIPUndefined = errors.New("serverIP undefined")
+
 // Benchmark interface
 type Benchmark interface {
 	// write container server YAML
-	WriteSrvContainerYaml(pw *utils.PrefixWriter, params map[string]interface{})
+	WriteSrvContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error
 	// write container client YAML
-	WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{})
+	WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error
 	// write server ports section
-	WriteSrvPortsYaml(pw *utils.PrefixWriter, params map[string]interface{})
+	WriteSrvPortsYaml(pw *utils.PrefixWriter, params map[string]interface{}) error
 
 	GetTimeout() int
+	// benchmark name (e.g., netperf)
+	GetName() string
+	// benchmark test name (e.g., tcp_rr)
+	GetTestName() string
+
+	// parse the client output
+	// Problems in the output should be reported as warnings in the
+	// result. An error is returned only if the output could not be read.
+	ParseCliOutput(r io.Reader) (*BenchResult, error)
 }
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// ChurnConf is the configuration of the connection churn benchmark. It creates
+// short-lived connections at a constant rate (to stress connection tracking
+// and NAT tables): the client is the fortio load generator without keepalive
+// (i.e., every request uses a new connection), and the server is a fortio
+// server (see HTTPConf). QPS is the number of new connections per second, and
+// Connections the number of concurrent connections.
+//
+// The client uses a wrapper script (knb-churn.sh) that reports the TCP
+// counters of the pod (e.g., SYN retransmits) before and after the load, so
+// the client image needs to include both the script and fortio.
+type ChurnConf struct {
+	HTTPConf
+	CliImage string `json:"cli_image"`
+}
+
+// ChurnConfDefault returns a ChurnConf with the default values
+func ChurnConfDefault() ChurnConf {
+	return ChurnConf{
+		HTTPConf: HTTPConf{
+			Timeout:     60,
+			Port:        8080,
+			Image:       "fortio/fortio",
+			QPS:         1000,
+			Connections: 32,
+		},
+		CliImage: "cilium/kubenetbench",
+	}
+}
+
+// GetName returns the benchmark name
+func (cnf *ChurnConf) GetName() string {
+	return "churn"
+}
+
+// GetTestName returns the test name
+func (cnf *ChurnConf) GetTestName() string {
+	return "tcp_churn"
+}
+
+// WriteCliContainerYaml writes the client yaml
+func (cnf *ChurnConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	pw.AppendNewLineOrDie(`name: churn-cli`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`image: %s`, cnf.CliImage))
+	cliCommandWrite(pw, params, "scripts/knb-churn.sh")
+	pw.AppendNewLineOrDie(`args : [`)
+	pw.PushPrefix("    ")
+	pw.AppendNewLineOrDie(`"-keepalive=false", # new connection for every request`)
+	if err := cnf.writeLoadArgs(pw, params); err != nil {
+		return err
+	}
+	pw.PopPrefix()
+	pw.AppendNewLineOrDie(`]`)
+	return nil
+}
+
+// prefix of the lines with the TCP counters of the client (see knb-churn.sh)
+const churnCountersPrefix = "knb-tcp-counters"
+
+// TCP counters reported by knb-churn.sh, and the corresponding metrics
+var churnCounterMetrics = map[string]string{
+	"ActiveOpens":   "tcp_active_opens",
+	"AttemptFails":  "tcp_attempt_fails",
+	"TCPSynRetrans": "syn_retransmits",
+}
+
+// parseChurnCounters parses the TCP counter lines of the client output (e.g.,
+// "knb-tcp-counters before ActiveOpens=3 AttemptFails=0 TCPSynRetrans=0"),
+// and returns the counters before and after the load
+func parseChurnCounters(r io.Reader) (before, after map[string]float64, err error) {
+	before = make(map[string]float64)
+	after = make(map[string]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != churnCountersPrefix {
+			continue
+		}
+
+		var counters map[string]float64
+		switch fields[1] {
+		case "before":
+			counters = before
+		case "after":
+			counters = after
+		default:
+			continue
+		}
+		for _, f := range fields[2:] {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			if v, err := strconv.ParseFloat(kv[1], 64); err == nil {
+				counters[kv[0]] = v
+			}
+		}
+	}
+	return before, after, scanner.Err()
+}
+
+// parseChurnOutput parses the output of the churn client. The fortio result
+// provides the connection rate and latencies (every request uses a new
+// connection), and the TCP counters provide the kernel view (e.g., SYN
+// retransmits).
+func parseChurnOutput(r io.Reader, test string) (*BenchResult, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := parseHTTPOutput(bytes.NewReader(data), test)
+	if err != nil {
+		return nil, err
+	}
+	res.Tool = "churn"
+
+	// every request is a connection
+	if m, ok := res.Metrics[MetricThroughput]; ok {
+		res.Metrics[MetricThroughput] = Metric{m.Value, "conn/s"}
+	}
+	if m, ok := res.Metrics["requested_qps"]; ok {
+		delete(res.Metrics, "requested_qps")
+		res.Metrics["requested_rate"] = Metric{m.Value, "conn/s"}
+	}
+	if m, ok := res.Metrics["requests"]; ok {
+		delete(res.Metrics, "requests")
+		res.Metrics["connections"] = m
+	}
+
+	// fortio uses -1 as the code of requests that failed because of socket
+	// errors (e.g., connection failures)
+	if out := findFortioOutput(data); out != nil {
+		var total int64
+		for _, n := range out.RetCodes {
+			total += n
+		}
+		failures := out.RetCodes["-1"]
+		res.Metrics["connection_failures"] = Metric{Value: float64(failures)}
+		if total > 0 {
+			res.Metrics["failure_percent"] = Metric{100 * float64(failures) / float64(total), "%"}
+		}
+	}
+
+	before, after, err := parseChurnCounters(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for counter, name := range churnCounterMetrics {
+		b, okb := before[counter]
+		a, oka := after[counter]
+		if !okb || !oka {
+			res.addWarning(0, name, fmt.Sprintf("missing TCP counter %s", counter))
+			continue
+		}
+		res.Metrics[name] = Metric{Value: a - b}
+	}
+
+	return res, nil
+}
+
+// ParseCliOutput parses the client output
+func (cnf *ChurnConf) ParseCliOutput(r io.Reader) (*BenchResult, error) {
+	return parseChurnOutput(r, cnf.GetTestName())
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+var churnOutput = "knb-tcp-counters before ActiveOpens=3 AttemptFails=0 TCPSynRetrans=1\n" +
+	fortioLoadOutput +
+	"knb-tcp-counters after ActiveOpens=29993 AttemptFails=5 TCPSynRetrans=13\n"
+
+func TestParseChurn(t *testing.T) {
+	cnf := ChurnConfDefault()
+	res, err := cnf.ParseCliOutput(strings.NewReader(churnOutput))
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	metrics := map[string]Metric{
+		MetricThroughput:      {999.5, "conn/s"},
+		"requested_rate":      {1000, "conn/s"},
+		"connections":         {Value: 29985},
+		"connection_failures": {Value: 5},
+		"syn_retransmits":     {Value: 12},
+		"tcp_active_opens":    {Value: 29990},
+		"tcp_attempt_fails":   {Value: 5},
+	}
+	for name, expected := range metrics {
+		m, ok := res.Metrics[name]
+		if !ok || m != expected {
+			t.Errorf("metric %s: got %v (exists:%t) while expected %v", name, m, ok, expected)
+		}
+	}
+	if _, ok := res.Metrics["requests"]; ok {
+		t.Errorf("unexpected requests metric")
+	}
+	if res.Tool != "churn" || res.Test != "tcp_churn" {
+		t.Errorf("unexpected tool/test: %s/%s", res.Tool, res.Test)
+	}
+
+	// no TCP counters
+	res, err = cnf.ParseCliOutput(strings.NewReader(fortioLoadOutput))
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	n := 0
+	for _, w := range res.Warnings {
+		if strings.HasPrefix(w.Message, "missing TCP counter") {
+			n++
+		}
+	}
+	if n != len(churnCounterMetrics) {
+		t.Errorf("got warnings %v while expected missing TCP counter warnings", res.Warnings)
+	}
+}
+
+func TestExecuteChurn(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+
+	// every snapshot of a node adds 100 conntrack entries (NB: nodes are
+	// queried concurrently)
+	var mu sync.Mutex
+	snaps := make(map[string]int)
+	origGetConntrack := getConntrackStatsNode
+	getConntrackStatsNode = func(s *Session, nodeName string) (map[string]int64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		snaps[nodeName]++
+		return map[string]int64{"nf_conntrack_count": int64(100 * snaps[nodeName]), "nf_conntrack_max": 262144}, nil
+	}
+	t.Cleanup(func() { getConntrackStatsNode = origGetConntrack })
+
+	cnf := ChurnConfDefault()
+	cnf.Timeout = 0
+	st := ServiceSt{ServiceType: ServiceClusterIP}
+	fake := newTestService(t, &st, &cnf, "")
+	fake.PodHook = func(pod *kube.Pod) string {
+		if pod.Labels["role"] == "cli" {
+			setAtBarrier(pod)
+			return churnOutput
+		}
+		return ""
+	}
+	fakeBarrier(t, fake)
+	st.RunBenchCtx.SetConntrackSnapshots(true)
+
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := st.RunBenchCtx.getDir()
+	yaml, err := ioutil.ReadFile(dir + "/client.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"-keepalive=false"`, `scripts/knb-churn.sh`, `:8080/echo"`} {
+		if !strings.Contains(string(yaml), s) {
+			t.Errorf("client yaml does not contain %q", s)
+		}
+	}
+
+	res, err := LoadBenchResult(dir + "/result.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := res.GetMetric("syn_retransmits"); v != 12 {
+		t.Errorf("got %f syn retransmits while expected 12", v)
+	}
+
+	data, err := ioutil.ReadFile(dir + "/conntrack.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := &ConntrackSnapshots{}
+	if err := json.Unmarshal(data, ct); err != nil {
+		t.Fatal(err)
+	}
+	if len(ct.Delta) != 2 {
+		t.Fatalf("got conntrack deltas for %d nodes while expected 2: %+v", len(ct.Delta), ct)
+	}
+	for node, d := range ct.Delta {
+		if d["nf_conntrack_count"] != 100 || d["nf_conntrack_max"] != 0 {
+			t.Errorf("%s: unexpected conntrack delta: %v", node, d)
+		}
+	}
+}
+
+func TestConntrackDelta(t *testing.T) {
+	before := map[string]map[string]int64{
+		"node1": {"a": 1, "b": 5},
+		"node2": {"a": 1},
+	}
+	after := map[string]map[string]int64{
+		"node1": {"a": 4, "b": 2, "c": 7},
+		"node3": {"a": 1},
+	}
+	d := conntrackDelta(before, after)
+	if len(d) != 1 || len(d["node1"]) != 2 || d["node1"]["a"] != 3 || d["node1"]["b"] != -3 {
+		t.Errorf("unexpected delta: %v", d)
+	}
+}
@@ -0,0 +1,327 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/template"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// client directories (when using multiple clients) are: client-NN
+func clientDirName(i int) string {
+	return fmt.Sprintf("client-%02d", i)
+}
+
+// clientPodDirName returns the directory of the i-th client pod: the client
+// index label is used if present, so that the directory matches the client
+// (e.g., its barrier port) regardless of the pod order
+func clientPodDirName(pod *kube.Pod, i int) string {
+	if idx, err := strconv.Atoi(pod.Labels[clientIdxLabel]); err == nil {
+		return clientDirName(idx)
+	}
+	return clientDirName(i)
+}
+
+// metrics that are summed (instead of averaged) across clients to compute the
+// total result
+var additiveMetrics = map[string]struct{}{
+	MetricThroughput:      {},
+	"sender_throughput":   {},
+	"bytes_sent":          {},
+	"bytes_received":      {},
+	"retransmits":         {},
+	"packets":             {},
+	"lost_packets":        {},
+	"requested_qps":       {},
+	"requests":            {},
+	"errors":              {},
+	"queries":             {},
+	"queries_completed":   {},
+	"lost_queries":        {},
+	"connections":         {},
+	"connection_failures": {},
+	"syn_retransmits":     {},
+	"tcp_active_opens":    {},
+	"tcp_attempt_fails":   {},
+}
+
+// validateClients checks whether the benchmark supports the configured
+// clients (see SetClients)
+func (r *RunBenchCtx) validateClients() error {
+	if r.clients < 0 {
+		return fmt.Errorf("invalid number of clients: %d", r.clients)
+	}
+	if !r.multiClient() {
+		return nil
+	}
+
+	// NB: netperf clients of the same server use different data ports (see
+	// NetperfConf.dataPort), which is not possible for the pods of a job
+	if r.clientJob && r.benchmark.GetName() == "netperf" {
+		return fmt.Errorf("client jobs are not supported for netperf")
+	}
+
+	// NB: an iperf3 server serves a single client at a time, so every client
+	// needs its own server (see PairsSt)
+	if r.benchmark.GetName() == "iperf3" && r.scenario != ScenarioPairs {
+		return fmt.Errorf("multiple clients of the same iperf3 server are not supported (use %s)", ScenarioPairs)
+	}
+
+	// NB: the pods of a job use the same barrier port (see barrierPortFor),
+	// which conflicts for pods on the host network of the same node
+	if r.clientJob && r.cliSpec.HostNetwork {
+		return fmt.Errorf("client jobs are not supported for clients on the host network")
+	}
+	return nil
+}
+
+var multiCliPodTemplate = template.Must(template.New("cli").Parse(`apiVersion: v1
+kind: Pod
+metadata:
+  name: {{.cliName}}
+  labels : {
+     {{.runLabel}},
+     role: cli,
+     {{.cliIdxLabel}},
+  }
+spec:
+  restartPolicy: Never
+  {{.cliHost}}
+  {{.cliAffinity}}
+  {{.cliBarrier}}
+  containers:
+  - {{.cliContainer}}
+`))
+
+var multiCliJobTemplate = template.Must(template.New("cli").Parse(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{.cliName}}
+  labels : {
+     {{.runLabel}},
+     role: cli,
+  }
+spec:
+  parallelism: {{.clients}}
+  completions: {{.clients}}
+  backoffLimit: 0
+  template:
+    metadata:
+      labels : {
+        {{.runLabel}},
+        role: cli,
+      }
+    spec:
+      restartPolicy: Never
+      {{.cliHost}}
+      {{.cliAffinity}}
+      {{.cliBarrier}}
+      containers:
+      - {{.cliContainer}}
+`))
+
+// genClientsYaml generates the yaml for multiple clients: either one pod per
+// client, or a job whose parallelism is the number of clients (in which case
+// there can only be a single server). Client i uses server i (modulo the number
+// of servers). All clients use a start barrier (see releaseBarrier).
+func (r *RunBenchCtx) genClientsYaml(dir string, serverIPs []string) (string, error) {
+	if len(serverIPs) == 0 {
+		return "", errServerIPUndefined
+	}
+	if r.clientJob && len(serverIPs) > 1 {
+		return "", fmt.Errorf("client jobs are not supported for multiple servers")
+	}
+	for _, ip := range serverIPs {
+		if ip == "" {
+			return "", errServerIPUndefined
+		}
+	}
+
+	yaml := fmt.Sprintf("%s/client.yaml", dir)
+	log.Printf("Generating %s", yaml)
+	f, err := os.Create(yaml)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	templates := map[string]utils.PrefixRenderer{
+		"netperfContainer": r.benchmark.WriteCliContainerYaml,
+		"cliAffinity":      r.cliAffinityWrite,
+		"cliHost":          r.cliSpec.hostOptsWrite,
+		"cliBarrier":       cliBarrierWrite,
+	}
+
+	vals := func(name string, serverIP string) map[string]interface{} {
+		return map[string]interface{}{
+			"cliName":      name,
+			"runLabel":     r.getRunLabel(": "),
+			"serverIP":     serverIP,
+			"srvPorts":     r.srvPorts,
+			"barrier":      true,
+			"cliContainer": "{{template \"netperfContainer\"}}",
+			"cliAffinity":  "{{template \"cliAffinity\"}}",
+			"cliHost":      "{{template \"cliHost\"}}",
+			"cliBarrier":   "{{template \"cliBarrier\"}}",
+		}
+	}
+
+	if r.clientJob {
+		v := vals(r.objName("cli"), serverIPs[0])
+		v["clients"] = r.numClients()
+		err = utils.RenderTemplate(multiCliJobTemplate, v, templates, f)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate %s: %w", yaml, err)
+		}
+		return yaml, nil
+	}
+
+	for i := 0; i < r.numClients(); i++ {
+		idx := fmt.Sprintf("%02d", i)
+		v := vals(r.objName("cli-"+idx), serverIPs[i%len(serverIPs)])
+		v["cliIdxLabel"] = fmt.Sprintf("%s: \"%s\"", clientIdxLabel, idx)
+		v["clientIdx"] = i
+		if len(serverIPs) > 1 {
+			v["pairIdx"] = fmt.Sprintf("%02d", i%len(serverIPs))
+		}
+
+		if i > 0 {
+			if _, err := f.WriteString("---\n"); err != nil {
+				return "", err
+			}
+		}
+		err = utils.RenderTemplate(multiCliPodTemplate, v, templates, f)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate %s: %w", yaml, err)
+		}
+	}
+	return yaml, nil
+}
+
+// saveClientsLogsAndResults saves the logs and the parsed results of each
+// client pod in its own sub-directory of dir (see clientDirName), and the
+// total result of all clients in dir (see saveClientsResult).
+func (r *RunBenchCtx) saveClientsLogsAndResults(dir string) {
+	ctx, cancel := kubeCtx()
+	pods, err := r.kube.GetPods(ctx, r.getCliSelector(), "")
+	cancel()
+	if err != nil {
+		log.Printf("failed to get client pods: %s", err)
+		return
+	}
+
+	// NB: the names of client pods include the client index, so sorting by
+	// name keeps the client order for pods without the client index label
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	var cliDirs []string
+	for i := range pods {
+		cliDir := filepath.Join(dir, clientPodDirName(&pods[i], i))
+		if err := os.MkdirAll(cliDir, 0755); err != nil {
+			log.Printf("failed to create client directory: %s", err)
+			continue
+		}
+
+		err := r.KubeSavePodLogs(pods[i].Name, "", filepath.Join(cliDir, "cli.log"))
+		if err != nil {
+			log.Printf("failed to save client logs: %s", err)
+			continue
+		}
+
+		err = r.saveResult(cliDir)
+		if err != nil {
+			log.Printf("failed to save results: %s", err)
+			continue
+		}
+		cliDirs = append(cliDirs, cliDir)
+	}
+
+	err = saveClientsResult(dir, cliDirs)
+	if err != nil {
+		log.Printf("failed to save clients result: %s", err)
+	}
+}
+
+// latency metrics whose total is the maximum across clients: percentiles of
+// different clients cannot be combined, so the total is the worst client value
+var maxMetrics = map[string]struct{}{
+	MetricP50Latency:  {},
+	MetricP90Latency:  {},
+	MetricP99Latency:  {},
+	MetricP999Latency: {},
+	"max_latency":     {},
+}
+
+// clientsTotalResult returns the total result of multiple clients.
+// Additive metrics (e.g., throughput) are summed, latency percentiles are the
+// maximum across clients, minimum latencies are the minimum, and the mean is
+// used for the rest (e.g., mean latencies). The client results are included as
+// streams.
+func clientsTotalResult(agg *AggregateResult, results []*BenchResult) *BenchResult {
+	res := NewBenchResult(agg.Tool, agg.Test)
+	res.Info["clients"] = strconv.Itoa(len(results))
+	for name, ms := range agg.Metrics {
+		if ms.N != len(results) {
+			res.addWarning(0, name, fmt.Sprintf("metric missing from %d client(s)", len(results)-ms.N))
+		}
+		v := ms.Mean
+		if _, ok := additiveMetrics[name]; ok {
+			v = ms.Mean * float64(ms.N)
+		} else if _, ok := maxMetrics[name]; ok {
+			v = ms.Max
+		} else if name == "min_latency" {
+			v = ms.Min
+		}
+		res.Metrics[name] = Metric{Value: v, Unit: ms.Unit}
+	}
+	if _, ok := res.Metrics[MetricP99Latency]; ok {
+		res.Info["latency_percentiles"] = "maximum across clients"
+	}
+
+	for i, cres := range results {
+		if cres.Info == nil {
+			cres.Info = make(map[string]string)
+		}
+		cres.Info["client"] = agg.Iterations[i]
+		res.Streams = append(res.Streams, cres)
+	}
+	return res
+}
+
+// saveClientsResult aggregates the results of the given client directories.
+// It writes the distribution of the client metrics in clients.json (using the
+// aggregate.json format), and the total result in result.json.
+func saveClientsResult(dir string, cliDirs []string) error {
+	var names []string
+	var results []*BenchResult
+	for _, cliDir := range cliDirs {
+		res, err := LoadBenchResult(filepath.Join(cliDir, "result.json"))
+		if err != nil {
+			log.Printf("ignoring client %s: %s", cliDir, err)
+			continue
+		}
+		names = append(names, filepath.Base(cliDir))
+		results = append(results, res)
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no client results to aggregate")
+	}
+
+	agg := AggregateResults(names, results)
+	fname := fmt.Sprintf("%s/clients.json", dir)
+	log.Printf("Writing %s", fname)
+	err := writeJSONFile(fname, agg)
+	if err != nil {
+		return err
+	}
+
+	fname = fmt.Sprintf("%s/result.json", dir)
+	log.Printf("Writing %s", fname)
+	return writeJSONFile(fname, clientsTotalResult(agg, results))
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+func netperfRRThroughput(t *testing.T) float64 {
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	res, err := cnf.ParseCliOutput(strings.NewReader(netperfRROutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := res.GetMetric(MetricThroughput)
+	if !ok {
+		t.Fatal("no throughput in netperf output")
+	}
+	return v
+}
+
+// checkClientsResult checks the total result of a multi-client run
+func checkClientsResult(t *testing.T, dir string, clients int, throughput float64) {
+	res, err := LoadBenchResult(dir + "/result.json")
+	if err != nil {
+		t.Fatalf("failed to load result: %s", err)
+	}
+	if res.Info["clients"] != strconv.Itoa(clients) {
+		t.Errorf("got clients info %q while expected %d", res.Info["clients"], clients)
+	}
+	if len(res.Streams) != clients {
+		t.Errorf("got %d client results while expected %d", len(res.Streams), clients)
+	}
+	expected := float64(clients) * throughput
+	if v, _ := res.GetMetric(MetricThroughput); math.Abs(v-expected) > 1e-6 {
+		t.Errorf("got total throughput %f while expected %f", v, expected)
+	}
+
+	data, err := ioutil.ReadFile(dir + "/clients.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agg := &AggregateResult{}
+	if err := json.Unmarshal(data, agg); err != nil {
+		t.Fatalf("failed to parse clients.json: %s", err)
+	}
+	if ms := agg.Metrics[MetricThroughput]; ms == nil || ms.N != clients || math.Abs(ms.Mean-throughput) > 1e-6 {
+		t.Errorf("unexpected client throughput distribution: %+v", ms)
+	}
+
+	for i := 0; i < clients; i++ {
+		if _, err := LoadBenchResult(dir + "/" + clientDirName(i) + "/result.json"); err != nil {
+			t.Errorf("client %d: %s", i, err)
+		}
+	}
+}
+
+func TestExecuteFanIn(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	throughput := netperfRRThroughput(t)
+
+	for _, useJob := range []bool{false, true} {
+		st, fake := newTestBarrierPod2Pod(t)
+		st.RunBenchCtx.SetClients(3, useJob, 0)
+		if err := st.Execute(); err != nil {
+			t.Fatalf("job:%t: %s", useJob, err)
+		}
+
+		dir := st.RunBenchCtx.getDir()
+		checkClientsResult(t, dir, 3, throughput)
+
+		yaml, err := ioutil.ReadFile(dir + "/client.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(yaml), barrierDir+"/knb-barrier") {
+			t.Errorf("job:%t: missing start barrier in client yaml", useJob)
+		}
+		// netperf clients use different data ports
+		if !useJob && !strings.Contains(string(yaml), `",8002"`) {
+			t.Errorf("missing data port of client 2 in client yaml")
+		}
+
+		kind := "pod"
+		if useJob {
+			kind = "job"
+		}
+		n := 0
+		for _, obj := range fake.Applied {
+			if strings.HasPrefix(obj, kind+"/knb-cli-") {
+				n++
+			}
+		}
+		if (useJob && n != 1) || (!useJob && n != 3) {
+			t.Errorf("job:%t: got %d applied client %ss (%v)", useJob, n, kind, fake.Applied)
+		}
+	}
+}
+
+func TestValidateClients(t *testing.T) {
+	st, _ := newTestPod2Pod(t, nil)
+	r := st.RunBenchCtx
+	iperf := IperfConfDefault()
+	hostSpec := &ContainerSpec{Affinity: "different"}
+	hostSpec.SetHostAll()
+
+	tests := []struct {
+		name     string
+		scenario string
+		cliSpec  *ContainerSpec
+		bench    Benchmark
+		clients  int
+		job      bool
+		ok       bool
+	}{
+		{"negative clients", "pod2pod", r.cliSpec, r.benchmark, -1, false, false},
+		{"netperf fan-in", "pod2pod", r.cliSpec, r.benchmark, 2, false, true},
+		{"netperf job", "pod2pod", r.cliSpec, r.benchmark, 2, true, false},
+		{"iperf3 single client", "pod2pod", r.cliSpec, &iperf, 1, false, true},
+		{"iperf3 fan-in", "pod2pod", r.cliSpec, &iperf, 2, false, false},
+		{"iperf3 job", "pod2pod", r.cliSpec, &iperf, 1, true, false},
+		{"iperf3 pairs", ScenarioPairs, r.cliSpec, &iperf, 2, false, true},
+		{"host network job", ScenarioNode2Pod, hostSpec, &DNSPerfConf{}, 2, true, false},
+	}
+	for i, tt := range tests {
+		r2 := NewRunBenchCtx(r.session, tt.scenario, fmt.Sprintf("bar%d", i), tt.cliSpec, r.srvSpec, true, tt.bench, false)
+		r2.SetClients(tt.clients, tt.job, 0)
+		if err := r2.MakeDir(); (err == nil) != tt.ok {
+			t.Errorf("%s: unexpected result: %v", tt.name, err)
+		}
+	}
+}
+
+func TestGenClientsYamlErrors(t *testing.T) {
+	st, _ := newTestPod2Pod(t, nil)
+	r := st.RunBenchCtx
+	dir := r.getDir()
+
+	if _, err := r.genCliYaml(dir, ""); err != errServerIPUndefined {
+		t.Errorf("unexpected error for missing server address: %v", err)
+	}
+	if _, err := r.genClientsYaml(dir, nil); err != errServerIPUndefined {
+		t.Errorf("unexpected error for missing server addresses: %v", err)
+	}
+	r.clientJob = true
+	if _, err := r.genClientsYaml(dir, []string{"10.0.0.1", "10.0.0.2"}); err == nil {
+		t.Errorf("expected error for client job with multiple servers")
+	}
+	r.clientJob = false
+
+	// renderer errors are returned
+	r.cliSpec = &ContainerSpec{Affinity: "foo"}
+	_, err := r.genCliYaml(dir, "10.0.0.1")
+	if err == nil || !strings.Contains(err.Error(), "unrecognized client affinity: foo") {
+		t.Errorf("unexpected error for invalid affinity: %v", err)
+	}
+}
+
+func TestExecutePairs(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	p2p, fake := newTestBarrierPod2Pod(t)
+	r := p2p.RunBenchCtx
+	r.SetClients(2, false, 0)
+
+	st := PairsSt{RunBenchCtx: r}
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	checkClientsResult(t, r.getDir(), 2, netperfRRThroughput(t))
+
+	srvs := 0
+	for _, obj := range fake.Applied {
+		if strings.HasPrefix(obj, "pod/knb-srv-") {
+			srvs++
+		}
+	}
+	if srvs != 2 {
+		t.Errorf("got %d server pods while expected 2 (%v)", srvs, fake.Applied)
+	}
+
+	// each client is anti-affine to the server of its pair
+	yaml, err := ioutil.ReadFile(r.getDir() + "/client.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`- key: knb-pair`, `- "00"`, `- "01"`} {
+		if !strings.Contains(string(yaml), s) {
+			t.Errorf("client yaml does not contain %q", s)
+		}
+	}
+}
+
+func TestClientsTotalResult(t *testing.T) {
+	mkres := func(tput float64, lat float64) *BenchResult {
+		res := NewBenchResult("netperf", "tcp_rr")
+		res.Metrics[MetricThroughput] = Metric{tput, "Trans/s"}
+		if lat != 0 {
+			res.Metrics[MetricP99Latency] = Metric{lat, "us"}
+			res.Metrics[MetricMeanLatency] = Metric{lat / 2, "us"}
+			res.Metrics["min_latency"] = Metric{lat / 10, "us"}
+		}
+		return res
+	}
+
+	results := []*BenchResult{mkres(100, 10), mkres(200, 30), mkres(300, 0)}
+	names := []string{clientDirName(0), clientDirName(1), clientDirName(2)}
+	res := clientsTotalResult(AggregateResults(names, results), results)
+
+	if m := res.Metrics[MetricThroughput]; m.Value != 600 || m.Unit != "Trans/s" {
+		t.Errorf("got throughput %v while expected 600", m)
+	}
+	if m := res.Metrics[MetricP99Latency]; m.Value != 30 {
+		t.Errorf("got p99 latency %v while expected 30 (max)", m)
+	}
+	if m := res.Metrics[MetricMeanLatency]; m.Value != 10 {
+		t.Errorf("got mean latency %v while expected 10 (mean)", m)
+	}
+	if m := res.Metrics["min_latency"]; m.Value != 1 {
+		t.Errorf("got min latency %v while expected 1 (min)", m)
+	}
+	if res.Info["latency_percentiles"] == "" {
+		t.Errorf("total latency percentiles are not labeled: %v", res.Info)
+	}
+	if len(res.Warnings) != 3 {
+		t.Errorf("unexpected warnings: %v", res.Warnings)
+	}
+	if res.Streams[2].Info["client"] != "client-02" {
+		t.Errorf("unexpected client info: %v", res.Streams[2].Info)
+	}
+}
+
+func TestClientPodDirName(t *testing.T) {
+	pod := &kube.Pod{Name: "knb-cli-1", Labels: map[string]string{clientIdxLabel: "1"}}
+	if name := clientPodDirName(pod, 0); name != "client-01" {
+		t.Errorf("got %q while expected client-01 (from the label)", name)
+	}
+	pod = &kube.Pod{Name: "knb-cli"}
+	if name := clientPodDirName(pod, 2); name != "client-02" {
+		t.Errorf("got %q while expected client-02 (from the index)", name)
+	}
+}
@@ -0,0 +1,156 @@
+package core
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// CollectorPerfRecord is the collector of --collect-perf
+const CollectorPerfRecord = "perf-record"
+
+// CollectorSpec is a collector of the monitor (e.g., perf-record, perf-stat,
+// bpftrace, bcc, ss, ethtool, softirqs) and its parameters. Parameters that
+// are not given use the defaults of the monitor.
+type CollectorSpec struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// ParseCollectors returns the specs of the given collectors, with params in
+// the <collector>.<param>=<value> format (e.g., perf-stat.events=cycles)
+func ParseCollectors(names []string, params []string) ([]CollectorSpec, error) {
+	ret := make([]CollectorSpec, 0, len(names))
+	idx := make(map[string]int)
+	for _, name := range names {
+		if _, ok := idx[name]; ok {
+			return nil, fmt.Errorf("collector %s given multiple times", name)
+		}
+		idx[name] = len(ret)
+		ret = append(ret, CollectorSpec{Name: name})
+	}
+
+	for _, p := range params {
+		kv := strings.SplitN(p, "=", 2)
+		np := strings.SplitN(kv[0], ".", 2)
+		if len(kv) != 2 || len(np) != 2 || np[0] == "" || np[1] == "" {
+			return nil, fmt.Errorf("invalid collector parameter %q (expected: <collector>.<param>=<value>)", p)
+		}
+		i, ok := idx[np[0]]
+		if !ok {
+			return nil, fmt.Errorf("parameter %q for collector %s, which is not used", p, np[0])
+		}
+		if ret[i].Params == nil {
+			ret[i].Params = make(map[string]string)
+		}
+		ret[i].Params[np[1]] = kv[1]
+	}
+	return ret, nil
+}
+
+// collectorConfs returns the collectors of the run for the monitor
+func (r *RunBenchCtx) collectorConfs() []*pb.CollectorConf {
+	var ret []*pb.CollectorConf
+	perf := false
+	for _, c := range r.collectors {
+		perf = perf || c.Name == CollectorPerfRecord
+		conf := &pb.CollectorConf{Name: c.Name}
+		names := make([]string, 0, len(c.Params))
+		for name := range c.Params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			conf.Params = append(conf.Params, &pb.Param{Name: name, Value: c.Params[name]})
+		}
+		ret = append(ret, conf)
+	}
+	if r.collectPerf && !perf {
+		ret = append(ret, &pb.CollectorConf{Name: CollectorPerfRecord})
+	}
+	return ret
+}
+
+// collecting returns true if the run uses monitor collections
+func (r *RunBenchCtx) collecting() bool {
+	return r.collectPerf || len(r.collectors) > 0
+}
+
+// CollectionManifest describes the contents of a collection archive (see
+// manifest.json in the archive). The files of every collector are in a
+// directory with the name of the collector.
+type CollectionManifest struct {
+	CollectionID string                      `json:"collection_id"`
+	Node         string                      `json:"node"`
+	StartTime    time.Time                   `json:"start_time"`
+	EndTime      time.Time                   `json:"end_time"`
+	Collectors   []CollectionCollectorResult `json:"collectors"`
+}
+
+// CollectionCollectorResult is the result of a collector in a collection
+type CollectionCollectorResult struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params"`
+	Files  []string          `json:"files"`
+	Error  string            `json:"error,omitempty"`
+}
+
+const collectionManifestName = "manifest.json"
+
+// readCollectionManifest reads the manifest of a collection archive
+func readCollectionManifest(fname string) (*CollectionManifest, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s: no %s", fname, collectionManifestName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fname, err)
+		}
+		if hdr.Name != collectionManifestName {
+			continue
+		}
+		m := &CollectionManifest{}
+		if err := json.NewDecoder(tr).Decode(m); err != nil {
+			return nil, fmt.Errorf("%s: invalid %s: %w", fname, collectionManifestName, err)
+		}
+		return m, nil
+	}
+}
+
+// logCollectionManifest logs the collectors of a collection archive, and their
+// errors
+func logCollectionManifest(fname string) {
+	m, err := readCollectionManifest(fname)
+	if err != nil {
+		log.Printf("failed to read collection manifest: %s", err)
+		return
+	}
+	for _, c := range m.Collectors {
+		if c.Error != "" {
+			log.Printf("collector %s on %s failed: %s", c.Name, m.Node, c.Error)
+		} else {
+			log.Printf("collector %s on %s: %d file(s)", c.Name, m.Node, len(c.Files))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestParseCollectors(t *testing.T) {
+	specs, err := ParseCollectors(
+		[]string{"perf-stat", "bpftrace", "softirqs"},
+		[]string{"perf-stat.events=cycles,instructions", "bpftrace.program=kprobe:tcp_retransmit_skb { @[comm] = count(); }"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 3 {
+		t.Fatalf("got %d collectors while expected 3", len(specs))
+	}
+	if v := specs[0].Params["events"]; v != "cycles,instructions" {
+		t.Errorf("unexpected perf-stat events: %q", v)
+	}
+	if v := specs[1].Params["program"]; v != "kprobe:tcp_retransmit_skb { @[comm] = count(); }" {
+		t.Errorf("unexpected bpftrace program: %q", v)
+	}
+	if specs[2].Params != nil {
+		t.Errorf("unexpected softirqs params: %v", specs[2].Params)
+	}
+
+	for _, tc := range []struct {
+		names  []string
+		params []string
+	}{
+		{[]string{"ss", "ss"}, nil},
+		{[]string{"ss"}, []string{"ss.interval"}},
+		{[]string{"ss"}, []string{"interval=1s"}},
+		{[]string{"ss"}, []string{"ethtool.devices=eth0"}},
+	} {
+		if _, err := ParseCollectors(tc.names, tc.params); err == nil {
+			t.Errorf("%v %v: expected error", tc.names, tc.params)
+		}
+	}
+}
+
+func TestCollectorConfs(t *testing.T) {
+	r := &RunBenchCtx{collectPerf: true}
+	r.SetCollectors([]CollectorSpec{{Name: "ss", Params: map[string]string{"interval": "500ms", "filter": "dport = :8080"}}})
+
+	confs := r.collectorConfs()
+	if len(confs) != 2 || confs[0].Name != "ss" || confs[1].Name != CollectorPerfRecord {
+		t.Fatalf("unexpected collectors: %v", confs)
+	}
+	// parameters are sorted by name
+	if ps := confs[0].Params; len(ps) != 2 || ps[0].Name != "filter" || ps[1].Value != "500ms" {
+		t.Errorf("unexpected ss params: %v", ps)
+	}
+
+	// perf-record is not added twice
+	r.SetCollectors([]CollectorSpec{{Name: CollectorPerfRecord, Params: map[string]string{"freq": "99"}}})
+	if confs := r.collectorConfs(); len(confs) != 1 || confs[0].Params[0].Value != "99" {
+		t.Errorf("unexpected collectors: %v", confs)
+	}
+}
+
+func TestReadCollectionManifest(t *testing.T) {
+	f, err := ioutil.TempFile("", "knb-collection-*.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	files := []struct{ name, data string }{
+		{"softirqs/softirqs.json", `{"before": {}, "after": {}, "delta": {}}`},
+		{"manifest.json", `{"collection_id": "foo", "node": "node1", "collectors": [
+			{"name": "softirqs", "params": {}, "files": ["softirqs/softirqs.json"]},
+			{"name": "bcc", "params": {"tool": "tcpretrans"}, "files": [], "error": "exit status 1"}]}`},
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, file := range files {
+		hdr := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(file.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gw, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := readCollectionManifest(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.CollectionID != "foo" || m.Node != "node1" || len(m.Collectors) != 2 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	if c := m.Collectors[0]; c.Name != "softirqs" || len(c.Files) != 1 || c.Error != "" {
+		t.Errorf("unexpected softirqs result: %+v", c)
+	}
+	if c := m.Collectors[1]; c.Params["tool"] != "tcpretrans" || c.Error == "" {
+		t.Errorf("unexpected bcc result: %+v", c)
+	}
+}
@@ -0,0 +1,417 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// CompareConf configures the comparison of runs
+type CompareConf struct {
+	// Threshold (percentage) above which a change in the wrong direction is
+	// considered a regression
+	Threshold float64
+	// Alpha is the significance level. If there are enough repetitions for
+	// the Mann-Whitney U test to reach Alpha (e.g., 4 on each side for 0.05,
+	// see utils.MannWhitneyMinP), a change is considered a regression only
+	// if its p-value is below Alpha. Otherwise, as for single runs, the
+	// Threshold alone decides.
+	Alpha float64
+	// AllMetrics includes metrics without a known direction (i.e., where
+	// it is not known whether higher is better or worse) in the comparison
+	AllMetrics bool
+	// HostBaseline compares the runs of B against the node2node runs of A
+	// with the same benchmark and affinities (see hostBaselineKey), instead
+	// of matching runs with the same configuration. Node2node runs of B are
+	// ignored. The host baseline is also used if A only has node2node runs
+	// and B has none (see isHostBaseline).
+	HostBaseline bool
+}
+
+// MetricComparison is the comparison of a metric for runs with the same configuration
+type MetricComparison struct {
+	Config     string
+	Metric     string
+	Unit       string
+	A          []float64
+	B          []float64
+	MeanA      float64
+	MeanB      float64
+	Delta      float64 // MeanB - MeanA
+	DeltaPct   float64 // Delta as a percentage of MeanA
+	PValue     float64 // NaN if there are not enough repetitions for the test
+	Direction  int     // 1: higher is better, -1: lower is better, 0: unknown
+	Regression bool
+	// Overhead is set instead of Regression when comparing against a host
+	// baseline (see CompareConf.HostBaseline): the pod network is expected
+	// to perform worse than the host network
+	Overhead bool
+}
+
+// CompareResult is the result of comparing two sets of runs
+type CompareResult struct {
+	Metrics []*MetricComparison
+	OnlyA   []string // configurations only present in A
+	OnlyB   []string // configurations only present in B
+}
+
+// ConfigKey returns a key that identifies the configuration of a run, so
+// that runs with the same configuration can be matched.
+func (ri *RunInfo) ConfigKey() string {
+	ret := fmt.Sprintf("%s %s/%s cli=%s srv=%s cli-host=%t srv-host=%t",
+		ri.Scenario, ri.Benchmark, ri.Test,
+		ri.CliAffinity, ri.SrvAffinity,
+		ri.CliHost, ri.SrvHost)
+	return ret + ri.serviceKey() + ri.policyKey()
+}
+
+// policyKey returns the part of the configuration key for the policies of the
+// run (e.g., " policy=cidr/100")
+func (ri *RunInfo) policyKey() string {
+	if ri.Manifest == nil || ri.Manifest.Policy == nil {
+		return ""
+	}
+	p := ri.Manifest.Policy
+	return fmt.Sprintf(" policy=%s/%d", p.Kind, p.Size)
+}
+
+// serviceKey returns the part of the configuration key for the service
+// options that differ from the defaults (e.g., the service type)
+func (ri *RunInfo) serviceKey() string {
+	ret := ""
+	// NB: runs without a service type (i.e., before service types were
+	// supported) use ClusterIP services
+	if ri.ServiceType != "" && ri.ServiceType != ServiceClusterIP {
+		ret += " svc=" + ri.ServiceType
+	}
+	if ri.Manifest == nil || ri.Manifest.Service == nil {
+		return ret
+	}
+	svc := ri.Manifest.Service
+	if svc.Replicas > 1 {
+		ret += fmt.Sprintf(" replicas=%d", svc.Replicas)
+	}
+	if svc.SessionAffinity != "" && svc.SessionAffinity != "None" {
+		ret += " affinity=" + svc.SessionAffinity
+	}
+	if svc.ExternalTrafficPolicy != "" {
+		ret += " etp=" + svc.ExternalTrafficPolicy
+	}
+	if svc.InternalTrafficPolicy != "" {
+		ret += " itp=" + svc.InternalTrafficPolicy
+	}
+	return ret
+}
+
+// hostBaselineKey returns a key that identifies the configuration of a run
+// without its scenario and host network options, so that runs can be matched
+// with their node2node baseline.
+func (ri *RunInfo) hostBaselineKey() string {
+	return fmt.Sprintf("%s/%s cli=%s srv=%s",
+		ri.Benchmark, ri.Test,
+		ri.CliAffinity, ri.SrvAffinity)
+}
+
+// samples returns the results of a run that can be used for comparisons
+func (ri *RunInfo) samples() []*BenchResult {
+	if ri.Outcome != "" && ri.Outcome != OutcomeSuccess {
+		return nil
+	}
+	if len(ri.Iterations) > 0 {
+		return ri.Iterations
+	}
+	if ri.Result == nil {
+		return nil
+	}
+	return []*BenchResult{ri.Result}
+}
+
+// LoadRuns loads runs from a path, which can be either a run directory or a
+// session directory.
+func LoadRuns(path string) ([]*RunInfo, error) {
+	if isRunDir(path) {
+		ri, err := LoadRun(path)
+		if err != nil {
+			return nil, err
+		}
+		return []*RunInfo{ri}, nil
+	}
+
+	return LoadSessionRuns(path)
+}
+
+// metricDirection returns 1 if higher values of the metric are better, -1
+// if lower values are better, and 0 if unknown.
+func metricDirection(name string) int {
+	switch {
+	case name == MetricThroughput || name == "transaction_rate" || strings.HasSuffix(name, "_throughput"):
+		return 1
+	case strings.HasSuffix(name, "_latency") || strings.HasSuffix(name, "_retrans"):
+		return -1
+	default:
+		return 0
+	}
+}
+
+type metricSamples struct {
+	vals []float64
+	unit string
+}
+
+// groupSamples groups metric values by configuration (as returned by key) and
+// metric name
+func groupSamples(runs []*RunInfo, key func(*RunInfo) string) map[string]map[string]*metricSamples {
+	ret := make(map[string]map[string]*metricSamples)
+	for _, ri := range runs {
+		samples := ri.samples()
+		if len(samples) == 0 {
+			continue
+		}
+
+		k := key(ri)
+		metrics, ok := ret[k]
+		if !ok {
+			metrics = make(map[string]*metricSamples)
+			ret[k] = metrics
+		}
+
+		for _, res := range samples {
+			for name, m := range res.Metrics {
+				ms, ok := metrics[name]
+				if !ok {
+					ms = &metricSamples{unit: m.Unit}
+					metrics[name] = ms
+				}
+				ms.vals = append(ms.vals, m.Value)
+			}
+		}
+	}
+	return ret
+}
+
+func sortedKeys(m map[string]map[string]*metricSamples) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func compareMetric(config, name string, a, b *metricSamples, conf *CompareConf) *MetricComparison {
+	mc := &MetricComparison{
+		Config:    config,
+		Metric:    name,
+		Unit:      a.unit,
+		A:         a.vals,
+		B:         b.vals,
+		MeanA:     utils.Mean(a.vals),
+		MeanB:     utils.Mean(b.vals),
+		PValue:    math.NaN(),
+		Direction: metricDirection(name),
+	}
+
+	mc.Delta = mc.MeanB - mc.MeanA
+	if mc.MeanA != 0 {
+		mc.DeltaPct = 100 * mc.Delta / math.Abs(mc.MeanA)
+	} else if mc.Delta != 0 {
+		mc.DeltaPct = math.Inf(int(math.Copysign(1, mc.Delta)))
+	}
+
+	// NB: a test that cannot reach Alpha would never confirm a regression,
+	// so that adding repetitions would make regressions harder to detect
+	if utils.MannWhitneyMinP(len(a.vals), len(b.vals)) < conf.Alpha {
+		_, mc.PValue = utils.MannWhitneyU(a.vals, b.vals)
+	}
+
+	worse := -float64(mc.Direction) * mc.DeltaPct
+	significant := math.IsNaN(mc.PValue) || mc.PValue < conf.Alpha
+	mc.Regression = mc.Direction != 0 && worse > conf.Threshold && significant
+	return mc
+}
+
+// compareMetrics compares the metrics that exist in both A and B
+func (cr *CompareResult) compareMetrics(config string, metricsA, metricsB map[string]*metricSamples, conf *CompareConf) {
+	names := make([]string, 0, len(metricsA))
+	for name := range metricsA {
+		if _, ok := metricsB[name]; !ok {
+			continue
+		}
+		if !conf.AllMetrics && metricDirection(name) == 0 {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mc := compareMetric(config, name, metricsA[name], metricsB[name], conf)
+		cr.Metrics = append(cr.Metrics, mc)
+	}
+}
+
+// CompareRuns compares runs of A (baseline) with runs of B. Runs are matched
+// based on their configuration (see ConfigKey), and multiple runs with the
+// same configuration are treated as repetitions. If A is a host baseline (see
+// CompareConf.HostBaseline), the runs of B are compared against it.
+func CompareRuns(runsA, runsB []*RunInfo, conf *CompareConf) *CompareResult {
+	if conf.HostBaseline || isHostBaseline(runsA, runsB) {
+		return compareHostBaseline(runsA, runsB, conf)
+	}
+
+	ret := &CompareResult{}
+	groupsA := groupSamples(runsA, (*RunInfo).ConfigKey)
+	groupsB := groupSamples(runsB, (*RunInfo).ConfigKey)
+
+	for _, config := range sortedKeys(groupsA) {
+		metricsB, ok := groupsB[config]
+		if !ok {
+			ret.OnlyA = append(ret.OnlyA, config)
+			continue
+		}
+		ret.compareMetrics(config, groupsA[config], metricsB, conf)
+	}
+
+	for _, config := range sortedKeys(groupsB) {
+		if _, ok := groupsA[config]; !ok {
+			ret.OnlyB = append(ret.OnlyB, config)
+		}
+	}
+
+	return ret
+}
+
+// isHostBaseline returns true if runsA only has node2node runs, and runsB has
+// none: the runs cannot be matched by their configuration, so B is compared
+// against the host baseline of A
+func isHostBaseline(runsA, runsB []*RunInfo) bool {
+	if len(runsA) == 0 {
+		return false
+	}
+	for _, ri := range runsA {
+		if ri.Scenario != ScenarioNode2Node {
+			return false
+		}
+	}
+	for _, ri := range runsB {
+		if ri.Scenario == ScenarioNode2Node {
+			return false
+		}
+	}
+	return true
+}
+
+// compareHostBaseline compares the runs of B with the node2node runs of A
+// (see CompareConf.HostBaseline). The difference is the overhead of the pod
+// network, so significant changes in the wrong direction are reported as
+// overhead instead of regressions.
+func compareHostBaseline(runsA, runsB []*RunInfo, conf *CompareConf) *CompareResult {
+	var baseRuns, podRuns []*RunInfo
+	for _, ri := range runsA {
+		if ri.Scenario == ScenarioNode2Node {
+			baseRuns = append(baseRuns, ri)
+		}
+	}
+	baseKeys := make(map[string]string)
+	for _, ri := range runsB {
+		if ri.Scenario != ScenarioNode2Node {
+			podRuns = append(podRuns, ri)
+			baseKeys[ri.ConfigKey()] = ri.hostBaselineKey()
+		}
+	}
+
+	ret := &CompareResult{}
+	groupsA := groupSamples(baseRuns, (*RunInfo).hostBaselineKey)
+	groupsB := groupSamples(podRuns, (*RunInfo).ConfigKey)
+
+	used := make(map[string]bool)
+	for _, config := range sortedKeys(groupsB) {
+		baseKey := baseKeys[config]
+		metricsA, ok := groupsA[baseKey]
+		if !ok {
+			ret.OnlyB = append(ret.OnlyB, config)
+			continue
+		}
+		used[baseKey] = true
+		ret.compareMetrics(config, metricsA, groupsB[config], conf)
+	}
+
+	for _, mc := range ret.Metrics {
+		mc.Overhead, mc.Regression = mc.Regression, false
+	}
+
+	for _, baseKey := range sortedKeys(groupsA) {
+		if !used[baseKey] {
+			ret.OnlyA = append(ret.OnlyA, ScenarioNode2Node+" "+baseKey)
+		}
+	}
+
+	return ret
+}
+
+// Regressions returns the number of detected regressions
+func (cr *CompareResult) Regressions() int {
+	ret := 0
+	for _, mc := range cr.Metrics {
+		if mc.Regression {
+			ret++
+		}
+	}
+	return ret
+}
+
+func fmtSamples(vals []float64) string {
+	ret := fmt.Sprintf("%.3f", utils.Mean(vals))
+	if len(vals) > 1 {
+		ret = fmt.Sprintf("%s (n=%d)", ret, len(vals))
+	}
+	return ret
+}
+
+// WriteTable writes the comparison as a table
+func (cr *CompareResult) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONFIG\tMETRIC\tA\tB\tDELTA\tDELTA%\tP-VALUE\tSTATUS")
+	for _, mc := range cr.Metrics {
+		pval := "-"
+		if !math.IsNaN(mc.PValue) {
+			pval = fmt.Sprintf("%.4f", mc.PValue)
+		}
+
+		status := ""
+		switch {
+		case mc.Regression:
+			status = "REGRESSION"
+		case mc.Overhead:
+			status = "overhead"
+		case mc.Direction != 0 && float64(mc.Direction)*mc.DeltaPct > 0:
+			status = "improved"
+		}
+
+		name := mc.Metric
+		if mc.Unit != "" {
+			name = fmt.Sprintf("%s (%s)", name, mc.Unit)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%+.3f\t%+.2f%%\t%s\t%s\n",
+			mc.Config, name,
+			fmtSamples(mc.A), fmtSamples(mc.B),
+			mc.Delta, mc.DeltaPct, pval, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, config := range cr.OnlyA {
+		fmt.Fprintf(w, "only in A: %s\n", config)
+	}
+	for _, config := range cr.OnlyB {
+		fmt.Fprintf(w, "only in B: %s\n", config)
+	}
+	return nil
+}
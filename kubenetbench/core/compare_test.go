@@ -0,0 +1,163 @@
+package core
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func testRun(label string, tput float64, p90 float64) *RunInfo {
+	res := NewBenchResult("netperf", "tcp_rr")
+	res.Metrics[MetricThroughput] = Metric{tput, "Trans/s"}
+	res.Metrics[MetricP90Latency] = Metric{p90, "us"}
+	res.Metrics["local_send_calls"] = Metric{10, ""}
+	return &RunInfo{
+		Label:       label,
+		Scenario:    "pod2pod",
+		Benchmark:   "netperf",
+		Test:        "tcp_rr",
+		CliAffinity: "different",
+		SrvAffinity: "none",
+		Outcome:     OutcomeSuccess,
+		Result:      res,
+	}
+}
+
+func floatEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCompareRuns(t *testing.T) {
+	conf := &CompareConf{Threshold: 5, Alpha: 0.05}
+
+	// single runs: throughput dropped by 10%, latency improved
+	res := CompareRuns(
+		[]*RunInfo{testRun("a", 1000, 100)},
+		[]*RunInfo{testRun("b", 900, 90)},
+		conf,
+	)
+
+	if len(res.Metrics) != 2 {
+		t.Fatalf("got %d metrics while expected 2", len(res.Metrics))
+	}
+	lat, tput := res.Metrics[0], res.Metrics[1]
+	if tput.Metric != MetricThroughput || !tput.Regression || tput.DeltaPct != -10 || !math.IsNaN(tput.PValue) {
+		t.Errorf("unexpected throughput comparison: %+v", tput)
+	}
+	if lat.Metric != MetricP90Latency || lat.Regression {
+		t.Errorf("unexpected latency comparison: %+v", lat)
+	}
+	if res.Regressions() != 1 {
+		t.Errorf("got %d regressions while expected 1", res.Regressions())
+	}
+
+	// repetitions: the throughput drop is not significant
+	runsA := []*RunInfo{testRun("a", 1000, 100), testRun("a", 800, 100), testRun("a", 1100, 100), testRun("a", 900, 100)}
+	runsB := []*RunInfo{testRun("b", 1050, 100), testRun("b", 750, 100), testRun("b", 950, 100), testRun("b", 850, 100)}
+	res = CompareRuns(runsA, runsB, conf)
+	tput = res.Metrics[1]
+	if len(tput.A) != 4 || math.IsNaN(tput.PValue) || tput.Regression {
+		t.Errorf("unexpected throughput comparison: %+v", tput)
+	}
+
+	// few repetitions: the test cannot reach alpha, so the threshold alone
+	// decides (as for single runs)
+	runsA = []*RunInfo{testRun("a", 1000, 100), testRun("a", 1010, 100), testRun("a", 990, 100)}
+	runsB = []*RunInfo{testRun("b", 900, 100), testRun("b", 910, 100), testRun("b", 890, 100)}
+	res = CompareRuns(runsA, runsB, conf)
+	tput = res.Metrics[1]
+	if !math.IsNaN(tput.PValue) || !tput.Regression {
+		t.Errorf("unexpected throughput comparison: %+v", tput)
+	}
+
+	// enough repetitions: the drop is significant
+	runsA = append(runsA, testRun("a", 1005, 100))
+	runsB = append(runsB, testRun("b", 905, 100))
+	res = CompareRuns(runsA, runsB, conf)
+	tput = res.Metrics[1]
+	if !floatEqual(tput.PValue, 2.0/70) || !tput.Regression {
+		t.Errorf("unexpected throughput comparison: %+v", tput)
+	}
+
+	// configurations that do not match
+	other := testRun("c", 1000, 100)
+	other.CliAffinity = "same"
+	res = CompareRuns([]*RunInfo{testRun("a", 1000, 100)}, []*RunInfo{other}, conf)
+	if len(res.Metrics) != 0 || len(res.OnlyA) != 1 || len(res.OnlyB) != 1 {
+		t.Errorf("unexpected comparison result: %+v", res)
+	}
+}
+
+func TestCompareHostBaseline(t *testing.T) {
+	conf := &CompareConf{Threshold: 50, Alpha: 0.05, HostBaseline: true}
+
+	hostRun := func(scenario string, tput float64) *RunInfo {
+		ri := testRun(scenario, tput, 100)
+		ri.Scenario = scenario
+		ri.CliHost, ri.SrvHost, _ = HostScenarioSpec(scenario)
+		return ri
+	}
+	runs := []*RunInfo{
+		hostRun(ScenarioNode2Node, 1000),
+		hostRun(ScenarioPod2Node, 900),
+		hostRun(ScenarioNode2Pod, 800),
+		testRun("pod2pod", 400, 100),
+	}
+	other := testRun("pod2pod", 1000, 100)
+	other.CliAffinity = "same"
+	runs = append(runs, other)
+
+	// runs of a single session are compared against its node2node run
+	res := CompareRuns(runs, runs, conf)
+	deltas := make(map[string]float64)
+	for _, mc := range res.Metrics {
+		if mc.Metric == MetricThroughput {
+			deltas[mc.Config[:strings.Index(mc.Config, " ")]] = mc.DeltaPct
+			if mc.Regression || mc.Overhead != (mc.DeltaPct < -50) {
+				t.Errorf("%s: unexpected overhead status: %+v", mc.Config, mc)
+			}
+		}
+	}
+	expected := map[string]float64{"pod2node": -10, "node2pod": -20, "pod2pod": -60}
+	if len(deltas) != len(expected) {
+		t.Errorf("got throughput deltas %v while expected %v", deltas, expected)
+	}
+	for scenario, d := range expected {
+		if math.Abs(deltas[scenario]-d) > 1e-9 {
+			t.Errorf("%s: got throughput delta %f%% while expected %f%%", scenario, deltas[scenario], d)
+		}
+	}
+	if len(res.OnlyA) != 0 || len(res.OnlyB) != 1 || !strings.Contains(res.OnlyB[0], "cli=same") {
+		t.Errorf("unexpected unmatched configurations: %v %v", res.OnlyA, res.OnlyB)
+	}
+	// the overhead of the pod network is not a regression
+	if n := res.Regressions(); n != 0 {
+		t.Errorf("got %d regressions against the host baseline", n)
+	}
+	var buf bytes.Buffer
+	if err := res.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); strings.Contains(out, "REGRESSION") || !strings.Contains(out, "overhead") {
+		t.Errorf("unexpected table:\n%s", out)
+	}
+
+	// a node2node session is used as the host baseline of other sessions
+	res = CompareRuns(runs[:1], runs[1:], &CompareConf{Threshold: 50, Alpha: 0.05})
+	if len(res.Metrics) == 0 || len(res.OnlyA) != 0 || len(res.OnlyB) != 1 {
+		t.Errorf("unexpected comparison against node2node session: %+v", res)
+	}
+}
+
+func TestHostScenario(t *testing.T) {
+	for _, scenario := range []string{"pod2pod", ScenarioPod2Node, ScenarioNode2Pod, ScenarioNode2Node} {
+		cliHost, srvHost, ok := HostScenarioSpec(scenario)
+		if ok != (scenario != "pod2pod") {
+			t.Errorf("%s: unexpected host scenario status", scenario)
+		}
+		if s := HostScenario(cliHost, srvHost); s != scenario {
+			t.Errorf("got scenario %s while expected %s", s, scenario)
+		}
+	}
+}
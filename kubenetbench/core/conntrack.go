@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// timeout for retrieving the conntrack counters of a node from its monitor
+var conntrackTimeout = 30 * time.Second
+
+// ConntrackSnapshots are the connection tracking counters (e.g.,
+// nf_conntrack_count, nf_conntrack_insert_failed, or the number of entries of
+// eBPF conntrack maps) of each node before and after the clients execute. They
+// are stored in conntrack.json.
+type ConntrackSnapshots struct {
+	Before map[string]map[string]int64 `json:"before"`
+	After  map[string]map[string]int64 `json:"after"`
+	// Delta is After - Before, for the counters of both snapshots
+	Delta map[string]map[string]int64 `json:"delta"`
+}
+
+// getConntrackStatsNode returns the conntrack counters of a node, as reported
+// by its monitor. It is a variable so that tests can replace it.
+var getConntrackStatsNode = func(s *Session, nodeName string) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), conntrackTimeout)
+	defer cancel()
+
+	conn, err := s.DialMonitor(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	cli := pb.NewKubebenchMonitorClient(conn)
+	stats, err := cli.GetConntrackStats(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve conntrack stats from monitor on %q: %w", nodeName, err)
+	}
+
+	ret := make(map[string]int64, len(stats.Counters))
+	for _, c := range stats.Counters {
+		ret[c.Name] = c.Value
+	}
+	return ret, nil
+}
+
+// conntrackSnapshot returns the conntrack counters of all nodes. Nodes whose
+// counters cannot be retrieved are omitted.
+func (r *RunBenchCtx) conntrackSnapshot() map[string]map[string]int64 {
+	ret := make(map[string]map[string]int64)
+	nodes, err := r.session.KubeGetNodes()
+	if err != nil {
+		log.Printf("conntrack snapshot failed: %s", err)
+		return ret
+	}
+
+	// NB: nodes are queried concurrently, so that the snapshot of all nodes
+	// takes (at most) conntrackTimeout
+	counters := make([]map[string]int64, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			counters[i], errs[i] = getConntrackStatsNode(r.session, nodes[i].Name)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, node := range nodes {
+		if errs[i] != nil {
+			log.Printf("conntrack snapshot on %s failed: %s", node.Name, errs[i])
+			continue
+		}
+		ret[node.Name] = counters[i]
+	}
+	return ret
+}
+
+// conntrackDelta returns the difference of the counters of the nodes in both
+// snapshots
+func conntrackDelta(before, after map[string]map[string]int64) map[string]map[string]int64 {
+	ret := make(map[string]map[string]int64)
+	for node, a := range after {
+		b, ok := before[node]
+		if !ok {
+			continue
+		}
+		d := make(map[string]int64)
+		for name, v := range a {
+			if bv, ok := b[name]; ok {
+				d[name] = v - bv
+			}
+		}
+		ret[node] = d
+	}
+	return ret
+}
+
+// saveConntrackSnapshots writes conntrack.json in dir, and logs the changes
+// of the conntrack counters
+func saveConntrackSnapshots(dir string, before, after map[string]map[string]int64) error {
+	snaps := ConntrackSnapshots{
+		Before: before,
+		After:  after,
+		Delta:  conntrackDelta(before, after),
+	}
+
+	nodes := make([]string, 0, len(snaps.Delta))
+	for node := range snaps.Delta {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		d := snaps.Delta[node]
+		names := make([]string, 0, len(d))
+		for name := range d {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if d[name] != 0 {
+				log.Printf("conntrack %s: %s: %+d (%d -> %d)", node, name, d[name], before[node][name], after[node][name])
+			}
+		}
+	}
+
+	fname := fmt.Sprintf("%s/conntrack.json", dir)
+	log.Printf("Writing %s", fname)
+	return writeJSONFile(fname, snaps)
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/template"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// DNSSt is the state for the dns scenario: client pods send DNS queries for
+// service names to the cluster DNS (see DNSPerfConf).
+type DNSSt struct {
+	RunBenchCtx *RunBenchCtx
+	Conf        *DNSPerfConf
+	// DNSServer is the address of the DNS server (e.g., of NodeLocal DNS).
+	// If empty, the cluster IP of the kube-dns service is used.
+	DNSServer     string
+	ClusterDomain string
+}
+
+// the service whose name is resolved (it has no backends)
+var dnsTargetTemplate = template.Must(template.New("dns").Parse(`apiVersion: v1
+kind: Service
+metadata:
+  name: {{.serviceName}}
+  labels : {
+    {{.runLabel}},
+  }
+spec:
+  ports:
+  - port: 80
+`))
+
+func (s *DNSSt) genTargetYaml(svcName string) (string, error) {
+	vals := map[string]interface{}{
+		"serviceName": svcName,
+		"runLabel":    s.RunBenchCtx.getRunLabel(": "),
+	}
+
+	yaml := fmt.Sprintf("%s/dns-target.yaml", s.RunBenchCtx.getDir())
+	log.Printf("Generating %s", yaml)
+	f, err := os.Create(yaml)
+	if err != nil {
+		return "", err
+	}
+	err = utils.RenderTemplate(dnsTargetTemplate, vals, nil, f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s: %w", yaml, err)
+	}
+	return yaml, nil
+}
+
+// clusterDNS returns the cluster IP of the kube-dns service
+func (s *DNSSt) clusterDNS() (string, error) {
+	ctx, cancel := kubeCtx()
+	defer cancel()
+
+	svcs, err := s.RunBenchCtx.kube.WithNamespace("kube-system").GetServices(ctx, "k8s-app=kube-dns")
+	if err != nil {
+		return "", err
+	}
+	if len(svcs) != 1 || svcs[0].ClusterIP == "" || svcs[0].ClusterIP == "None" {
+		return "", fmt.Errorf("failed to find the cluster DNS service (k8s-app=kube-dns in kube-system), use a DNS server address")
+	}
+	return svcs[0].ClusterIP, nil
+}
+
+// setQueries sets the queries of the benchmark. By default, the names are the
+// target service and the kubernetes API service, and the search list is the
+// search list of pods in the namespace of the run.
+func (s *DNSSt) setQueries(svcName string) error {
+	ns := s.RunBenchCtx.kube.Namespace()
+	if len(s.Conf.Names) == 0 {
+		s.Conf.Names = []string{svcName, "kubernetes.default"}
+	}
+	if s.Conf.Search == nil {
+		s.Conf.Search = DefaultSearch(ns, s.ClusterDomain)
+	}
+
+	existing := map[string]bool{
+		fmt.Sprintf("%s.%s.svc.%s", svcName, ns, s.ClusterDomain): true,
+		fmt.Sprintf("kubernetes.default.svc.%s", s.ClusterDomain): true,
+	}
+	s.Conf.Queries = expandQueries(s.Conf.Names, s.Conf.QueryType, s.Conf.Ndots, s.Conf.Search, func(name string) bool {
+		return existing[name]
+	})
+	log.Printf("DNS queries (ndots: %d): %v", s.Conf.Ndots, s.Conf.Queries)
+
+	// update the benchmark configuration in the manifest
+	if m := s.RunBenchCtx.manifest; m != nil {
+		conf, err := json.Marshal(s.Conf)
+		if err != nil {
+			return err
+		}
+		m.Benchmark.Config = conf
+		if err := s.RunBenchCtx.writeManifest(); err != nil {
+			log.Printf("failed to write run manifest: %s", err)
+		}
+	}
+	return nil
+}
+
+// Execute dns scenario
+func (s DNSSt) Execute() (err error) {
+	defer func() {
+		s.RunBenchCtx.finishManifest(err)
+	}()
+
+	err = s.RunBenchCtx.KubeCreateNamespace()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// delete the objects of the run (on success, error, or interrupt)
+		if err := s.RunBenchCtx.KubeCleanup(); err != nil {
+			log.Printf("cleanup failed: %s", err)
+		}
+	}()
+
+	dnsServer := s.DNSServer
+	if dnsServer == "" {
+		dnsServer, err = s.clusterDNS()
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("dns_server=%s", dnsServer)
+
+	svcName := s.RunBenchCtx.objName("dns")
+	yaml, err := s.genTargetYaml(svcName)
+	if err != nil {
+		return err
+	}
+	err = s.RunBenchCtx.KubeApply(yaml)
+	if err != nil {
+		return err
+	}
+
+	err = s.setQueries(svcName)
+	if err != nil {
+		return err
+	}
+
+	return s.RunBenchCtx.runClients([]string{dnsServer})
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// DNSPerfConf is the configuration of the DNS resolution benchmark. It uses
+// dnsperf to send queries to the cluster DNS. There is no server (the DNS
+// server is the server), so it can only be used by the dns scenario.
+//
+// NB: dnsperf sends the queries as given (i.e., it does not use the resolver
+// of the pod), so the search list expansion of the resolver is emulated when
+// generating the queries (see expandQueries).
+type DNSPerfConf struct {
+	Timeout int    `json:"timeout"`
+	Image   string `json:"image"`
+	// queries per second. 0 means no rate limiting.
+	QPS int `json:"qps"`
+	// number of concurrent dnsperf clients (-c)
+	Clients int `json:"clients"`
+	// time to wait for a response before a query is considered lost (sec)
+	QueryTimeout int    `json:"query_timeout"`
+	QueryType    string `json:"query_type"`
+	// names to resolve, and resolver options used to expand them
+	Names  []string `json:"names"`
+	Ndots  int      `json:"ndots"`
+	Search []string `json:"search,omitempty"`
+	// queries sent by dnsperf (set by the dns scenario)
+	Queries  []string `json:"queries,omitempty"`
+	MoreArgs []string `json:"more_args,omitempty"`
+}
+
+// DNSPerfConfDefault returns a DNSPerfConf with the default values
+func DNSPerfConfDefault() DNSPerfConf {
+	return DNSPerfConf{
+		Timeout:      60,
+		Image:        "cilium/kubenetbench",
+		QPS:          1000,
+		Clients:      1,
+		QueryTimeout: 5,
+		QueryType:    "A",
+		// default ndots of kubernetes pods
+		Ndots: 5,
+	}
+}
+
+// GetTimeout returns the benchmark timeout
+func (cnf *DNSPerfConf) GetTimeout() int {
+	return cnf.Timeout
+}
+
+// GetName returns the benchmark name
+func (cnf *DNSPerfConf) GetName() string {
+	return "dnsperf"
+}
+
+// GetTestName returns the test name
+func (cnf *DNSPerfConf) GetTestName() string {
+	return "dns_" + strings.ToLower(cnf.QueryType)
+}
+
+// errDNSPerfNoServer is returned when writing the server of dnsperf, which
+// uses an existing DNS server (see the dns scenario)
+var errDNSPerfNoServer = errors.New("dnsperf has no server (use the dns scenario)")
+
+// WriteSrvContainerYaml writes the server yaml
+func (cnf *DNSPerfConf) WriteSrvContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	return errDNSPerfNoServer
+}
+
+// WriteSrvPortsYaml writes the ports part of yaml (e.g., for services)
+func (cnf *DNSPerfConf) WriteSrvPortsYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	return errDNSPerfNoServer
+}
+
+// WriteCliContainerYaml writes the client yaml. serverIP is the address of
+// the DNS server.
+func (cnf *DNSPerfConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	serverIP, ok := params["serverIP"]
+	if !ok {
+		return errServerIPUndefined
+	}
+	if len(cnf.Queries) == 0 {
+		return fmt.Errorf("no DNS queries")
+	}
+
+	pw.AppendNewLineOrDie(`name: dnsperf-cli`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`image: %s`, cnf.Image))
+	cliCommandWrite(pw, params, "scripts/knb-dnsperf.sh")
+	pw.AppendNewLineOrDie(`args : [`)
+	pw.PushPrefix("    ")
+	pw.AppendNewLineOrDie("# queries")
+	for _, q := range cnf.Queries {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"%s",`, q))
+	}
+	pw.AppendNewLineOrDie(`"--",`)
+	pw.AppendNewLineOrDie("# dnsperf args")
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-s", "%v",`, serverIP))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-l", "%d", # timeout`, cnf.Timeout))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-c", "%d", # clients`, cnf.Clients))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-t", "%d", # query timeout`, cnf.QueryTimeout))
+	if cnf.QPS > 0 {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"-Q", "%d",`, cnf.QPS))
+	}
+	pw.AppendNewLineOrDie(`"-v", # print the latency of each query (summarized by the script)`)
+	if len(cnf.MoreArgs) > 0 {
+		pw.AppendNewLineOrDie("# Additional args")
+		for _, arg := range cnf.MoreArgs {
+			pw.AppendNewLineOrDie(fmt.Sprintf(`"%s",`, arg))
+		}
+	}
+	pw.PopPrefix()
+	pw.AppendNewLineOrDie(`]`)
+	return nil
+}
+
+// ParseCliOutput parses the client output
+func (cnf *DNSPerfConf) ParseCliOutput(r io.Reader) (*BenchResult, error) {
+	return parseDNSPerfOutput(r, cnf.GetTestName())
+}
+
+// DefaultSearch returns the search list of pods in the given namespace
+func DefaultSearch(namespace string, clusterDomain string) []string {
+	return []string{
+		fmt.Sprintf("%s.svc.%s", namespace, clusterDomain),
+		fmt.Sprintf("svc.%s", clusterDomain),
+		clusterDomain,
+	}
+}
+
+// expandQueries returns the queries (name and type) that a resolver with the
+// given ndots and search list sends to resolve names. As in the glibc
+// resolver, names with at least ndots dots are tried as is first, and the rest
+// are tried with each search domain first. A name is resolved by the first
+// query for a name that exists (see exists) or for the name as is (which is
+// assumed to exist), so the queries until then get NXDOMAIN responses.
+// Absolute names (i.e., with a trailing dot) are not expanded.
+func expandQueries(names []string, qtype string, ndots int, search []string, exists func(string) bool) []string {
+	var ret []string
+	for _, name := range names {
+		if strings.HasSuffix(name, ".") {
+			ret = append(ret, fmt.Sprintf("%s %s", strings.TrimSuffix(name, "."), qtype))
+			continue
+		}
+
+		var candidates []string
+		for _, s := range search {
+			candidates = append(candidates, name+"."+s)
+		}
+		if strings.Count(name, ".") >= ndots {
+			candidates = append([]string{name}, candidates...)
+		} else {
+			candidates = append(candidates, name)
+		}
+
+		for _, c := range candidates {
+			ret = append(ret, fmt.Sprintf("%s %s", c, qtype))
+			if c == name || exists(c) {
+				break
+			}
+		}
+	}
+	return ret
+}
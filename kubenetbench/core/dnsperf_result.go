@@ -0,0 +1,149 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// latency percentiles computed from the per-query output of dnsperf
+var dnsPercentiles = map[string]float64{
+	MetricP50Latency:  50,
+	MetricP90Latency:  90,
+	MetricP99Latency:  99,
+	MetricP999Latency: 99.9,
+}
+
+// latency percentiles of the summary line of knb-dnsperf.sh (in order)
+var dnsSummaryPercentiles = []string{MetricP50Latency, MetricP90Latency, MetricP99Latency, MetricP999Latency}
+
+var (
+	dnsCountRegEx   = regexp.MustCompile(`^(Queries sent|Queries completed|Queries lost):\s+(\d+)`)
+	dnsQPSRegEx     = regexp.MustCompile(`^Queries per second:\s+([0-9.]+)`)
+	dnsAvgLatRegEx  = regexp.MustCompile(`^Average Latency \(s\):\s+([0-9.]+)(?: \(min ([0-9.]+), max ([0-9.]+)\))?`)
+	dnsRcodeRegEx   = regexp.MustCompile(`([A-Z]+) (\d+) \([0-9.]+%\)`)
+	dnsVersionRegEx = regexp.MustCompile(`^Version (\S+)`)
+	dnsSummaryRegEx = regexp.MustCompile(`^knb-dnsperf-latencies n=(\d+) p50=([0-9.]+) p90=([0-9.]+) p99=([0-9.]+) p99\.9=([0-9.]+)`)
+)
+
+var dnsCountMetrics = map[string]string{
+	"Queries sent":      "queries",
+	"Queries completed": "queries_completed",
+	"Queries lost":      "lost_queries",
+}
+
+// parseDNSPerfOutput parses the output of knb-dnsperf.sh. The statistics at
+// the end of the dnsperf output provide the QPS, the mean latency, and the
+// number of lost (timed out) queries, and the summary line of the script
+// provides the latency percentiles. If there is no summary line (i.e., the
+// output of dnsperf -v itself), the percentiles are computed from the
+// per-query lines (e.g., "> NOERROR kubernetes.default.svc.cluster.local A
+// 0.000512").
+func parseDNSPerfOutput(r io.Reader, test string) (*BenchResult, error) {
+	res := NewBenchResult("dnsperf", test)
+	var latencies []float64
+	// number of queries of the latency percentiles (-1: no summary line)
+	summaryN := -1
+	rcodes := make(map[string]float64)
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "> ") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				res.addWarning(lineNo, "", fmt.Sprintf("unexpected query line: %q", line))
+				continue
+			}
+			lat, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+			if err != nil {
+				res.addWarning(lineNo, "", fmt.Sprintf("unexpected query line: %q", line))
+				continue
+			}
+			latencies = append(latencies, lat*1e6)
+			continue
+		}
+
+		switch {
+		case dnsSummaryRegEx.MatchString(line):
+			m := dnsSummaryRegEx.FindStringSubmatch(line)
+			summaryN, _ = strconv.Atoi(m[1])
+			for i, name := range dnsSummaryPercentiles {
+				v, _ := strconv.ParseFloat(m[i+2], 64)
+				res.Metrics[name] = Metric{v * 1e6, "us"}
+			}
+
+		case dnsVersionRegEx.MatchString(line):
+			res.Info["version"] = dnsVersionRegEx.FindStringSubmatch(line)[1]
+
+		case dnsCountRegEx.MatchString(line):
+			m := dnsCountRegEx.FindStringSubmatch(line)
+			v, _ := strconv.ParseFloat(m[2], 64)
+			res.Metrics[dnsCountMetrics[m[1]]] = Metric{Value: v}
+
+		case dnsQPSRegEx.MatchString(line):
+			v, _ := strconv.ParseFloat(dnsQPSRegEx.FindStringSubmatch(line)[1], 64)
+			res.Metrics[MetricThroughput] = Metric{v, "queries/s"}
+
+		case dnsAvgLatRegEx.MatchString(line):
+			m := dnsAvgLatRegEx.FindStringSubmatch(line)
+			v, _ := strconv.ParseFloat(m[1], 64)
+			res.Metrics[MetricMeanLatency] = Metric{v * 1e6, "us"}
+			if m[2] != "" {
+				min, _ := strconv.ParseFloat(m[2], 64)
+				max, _ := strconv.ParseFloat(m[3], 64)
+				res.Metrics["min_latency"] = Metric{min * 1e6, "us"}
+				res.Metrics["max_latency"] = Metric{max * 1e6, "us"}
+			}
+
+		case strings.HasPrefix(line, "Response codes:"):
+			for _, m := range dnsRcodeRegEx.FindAllStringSubmatch(line, -1) {
+				v, _ := strconv.ParseFloat(m[2], 64)
+				rcodes[m[1]] = v
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{MetricThroughput, MetricMeanLatency, "queries", "lost_queries"} {
+		if _, ok := res.Metrics[name]; !ok {
+			res.addWarning(0, name, "missing from dnsperf output")
+		}
+	}
+
+	if sent, ok := res.GetMetric("queries"); ok && sent > 0 {
+		lost, _ := res.GetMetric("lost_queries")
+		res.Metrics["timeout_percent"] = Metric{100 * lost / sent, "%"}
+	}
+	if completed, ok := res.GetMetric("queries_completed"); ok && completed > 0 {
+		res.Metrics["nxdomain_percent"] = Metric{100 * rcodes["NXDOMAIN"] / completed, "%"}
+		res.Metrics["servfail_percent"] = Metric{100 * rcodes["SERVFAIL"] / completed, "%"}
+	}
+
+	n := summaryN
+	if n < 0 {
+		n = len(latencies)
+		if n > 0 {
+			for name, p := range dnsPercentiles {
+				res.Metrics[name] = Metric{utils.Percentile(latencies, p), "us"}
+			}
+		}
+	}
+	if n == 0 {
+		res.addWarning(0, "", "no query latencies (latency percentiles require dnsperf -v)")
+	} else if completed, ok := res.GetMetric("queries_completed"); ok && float64(n) != completed {
+		res.addWarning(0, "", fmt.Sprintf("latency percentiles of %d queries while %.0f were completed", n, completed))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+const dnsperfOutput = `DNS Performance Testing Tool
+Version 2.3.4
+
+[Status] Command line: dnsperf -d /tmp/tmp.q1 -s 10.96.0.10 -l 0 -c 1 -t 5 -Q 1000 -v
+[Status] Sending queries (to 10.96.0.10:53)
+[Status] Started at: Mon Jan  1 00:00:00 2024
+[Status] Stopping after 10 queries
+> NOERROR knb-dns.knb-ns.svc.cluster.local A 0.000100
+> NOERROR knb-dns.knb-ns.svc.cluster.local A 0.000200
+> NOERROR knb-dns.knb-ns.svc.cluster.local A 0.000300
+> NOERROR knb-dns.knb-ns.svc.cluster.local A 0.000400
+> NOERROR knb-dns.knb-ns.svc.cluster.local A 0.000500
+> NXDOMAIN kubernetes.default.knb-ns.svc.cluster.local A 0.000600
+> NOERROR kubernetes.default.svc.cluster.local A 0.000700
+> NOERROR kubernetes.default.svc.cluster.local A 0.000800
+> NOERROR kubernetes.default.svc.cluster.local A 0.000900
+[Timeout] Query timed out: msg id 9
+[Status] Testing complete (end of file)
+
+Statistics:
+
+  Queries sent:         10
+  Queries completed:    9 (90.00%)
+  Queries lost:         1 (10.00%)
+
+  Response codes:       NOERROR 8 (88.89%), NXDOMAIN 1 (11.11%)
+  Average packet size:  request 50, response 110
+  Run time (s):         0.010000
+  Queries per second:   900.000000
+
+  Average Latency (s):  0.000500 (min 0.000100, max 0.000900)
+  Latency StdDev (s):   0.000258
+`
+
+func TestParseDNSPerfOutput(t *testing.T) {
+	cnf := DNSPerfConfDefault()
+	res, err := cnf.ParseCliOutput(strings.NewReader(dnsperfOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", res.Warnings)
+	}
+	if res.Test != "dns_a" || res.Info["version"] != "2.3.4" {
+		t.Errorf("unexpected result: test=%s info=%v", res.Test, res.Info)
+	}
+
+	expected := map[string]float64{
+		"queries":           10,
+		"queries_completed": 9,
+		"lost_queries":      1,
+		MetricThroughput:    900,
+		MetricMeanLatency:   500,
+		"min_latency":       100,
+		"max_latency":       900,
+		MetricP50Latency:    500,
+		MetricP99Latency:    900,
+		"timeout_percent":   10,
+		"nxdomain_percent":  100.0 / 9,
+		"servfail_percent":  0,
+	}
+	for name, ev := range expected {
+		v, ok := res.GetMetric(name)
+		if !ok {
+			t.Errorf("missing metric %s", name)
+		} else if math.Abs(v-ev) > 1e-6 {
+			t.Errorf("got %s=%f while expected %f", name, v, ev)
+		}
+	}
+}
+
+func TestParseDNSPerfSummary(t *testing.T) {
+	// output of knb-dnsperf.sh: per-query lines are replaced by a summary
+	var lines []string
+	for _, line := range strings.Split(dnsperfOutput, "\n") {
+		if !strings.HasPrefix(line, "> ") {
+			lines = append(lines, line)
+		}
+	}
+	out := strings.Join(lines, "\n") + "knb-dnsperf-latencies n=9 p50=0.000500 p90=0.000900 p99=0.000900 p99.9=0.000900\n"
+
+	cnf := DNSPerfConfDefault()
+	res, err := cnf.ParseCliOutput(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", res.Warnings)
+	}
+	for name, ev := range map[string]float64{MetricP50Latency: 500, MetricP90Latency: 900, MetricP999Latency: 900, MetricThroughput: 900} {
+		if v, ok := res.GetMetric(name); !ok || math.Abs(v-ev) > 1e-6 {
+			t.Errorf("got %s=%f while expected %f", name, v, ev)
+		}
+	}
+
+	// the percentiles are not of all the completed queries
+	out = strings.Replace(out, "n=9", "n=7", 1)
+	res, err = cnf.ParseCliOutput(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Warnings) != 1 || !strings.Contains(res.Warnings[0].String(), "latency percentiles of 7 queries while 9 were completed") {
+		t.Errorf("unexpected warnings: %v", res.Warnings)
+	}
+}
+
+func TestParseDNSPerfQueryLines(t *testing.T) {
+	cnf := DNSPerfConfDefault()
+	// a line with too few fields, and one with an invalid latency
+	out := "> 0.000100\n> NOERROR example.com A foo\n"
+	res, err := cnf.ParseCliOutput(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, w := range res.Warnings {
+		if strings.Contains(w.String(), "unexpected query line") {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("got %d query line warnings while expected 2: %v", n, res.Warnings)
+	}
+}
+
+func TestExpandQueries(t *testing.T) {
+	search := DefaultSearch("knb-ns", "cluster.local")
+	exists := func(name string) bool {
+		return name == "kubernetes.default.svc.cluster.local"
+	}
+
+	tests := []struct {
+		names    []string
+		ndots    int
+		expected []string
+	}{
+		{[]string{"kubernetes.default"}, 5, []string{
+			"kubernetes.default.knb-ns.svc.cluster.local A",
+			"kubernetes.default.svc.cluster.local A",
+		}},
+		{[]string{"example.com"}, 5, []string{
+			"example.com.knb-ns.svc.cluster.local A",
+			"example.com.svc.cluster.local A",
+			"example.com.cluster.local A",
+			"example.com A",
+		}},
+		{[]string{"example.com"}, 1, []string{"example.com A"}},
+		{[]string{"kubernetes.default.svc.cluster.local."}, 5, []string{
+			"kubernetes.default.svc.cluster.local A",
+		}},
+	}
+
+	for _, tt := range tests {
+		q := expandQueries(tt.names, "A", tt.ndots, search, exists)
+		if !reflect.DeepEqual(q, tt.expected) {
+			t.Errorf("%v (ndots:%d): got %v while expected %v", tt.names, tt.ndots, q, tt.expected)
+		}
+	}
+}
+
+func TestExecuteDNS(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	sessDir, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sessDir) })
+
+	fake := kube.NewFakeClient(kube.Node{Name: "node1", Addresses: []string{"192.168.1.1"}})
+	fake.PodHook = func(pod *kube.Pod) string {
+		pod.Phase = "Succeeded"
+		return dnsperfOutput
+	}
+
+	sess := &Session{id: "test", dir: sessDir, kube: fake}
+	cnf := DNSPerfConfDefault()
+	cnf.Timeout = 0
+	r := NewRunBenchCtx(sess, "dns", "foo", &ContainerSpec{Affinity: "different"}, &ContainerSpec{Affinity: "none"}, true, &cnf, false)
+	if err := r.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	st := DNSSt{RunBenchCtx: r, Conf: &cnf, DNSServer: "10.96.0.10", ClusterDomain: "cluster.local"}
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	yaml, err := ioutil.ReadFile(r.getDir() + "/client.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"-s", "10.96.0.10"`, `"kubernetes.default.svc.cluster.local A"`} {
+		if !strings.Contains(string(yaml), s) {
+			t.Errorf("client yaml does not contain %q", s)
+		}
+	}
+
+	res, err := LoadBenchResult(r.getDir() + "/result.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := res.GetMetric(MetricThroughput); v != 900 {
+		t.Errorf("got throughput %f while expected 900", v)
+	}
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+// Leftover is an object created by kubenetbench that belongs to a run or a
+// session that is no longer active
+type Leftover struct {
+	kube.Object
+	Session string
+	Run     string
+	Reason  string
+}
+
+// GCConf configures the garbage collection of leftover objects
+type GCConf struct {
+	// SessDirBase is the base directory of sessions. It is used to find
+	// the manifests of runs, and the directories of sessions.
+	SessDirBase string
+	// MinAge is the minimum age of objects whose run or session state is
+	// unknown (e.g., because they were created from a different host or
+	// with a different session base directory) for them to be considered
+	// leftovers.
+	MinAge time.Duration
+}
+
+// processAlive checks whether a (local) process exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// runState returns whether a run is known to be dead (and the reason), or
+// whether its state is unknown.
+func (conf *GCConf) runState(runID string) (dead bool, unknown bool, reason string) {
+	fnames, _ := filepath.Glob(filepath.Join(conf.SessDirBase, "*", runID, "run.json"))
+	if len(fnames) == 0 {
+		return false, true, fmt.Sprintf("run manifest not found in %s", conf.SessDirBase)
+	}
+
+	m, err := LoadRunManifest(fnames[0])
+	if err != nil {
+		return false, true, err.Error()
+	}
+
+	if m.Outcome != OutcomeRunning {
+		return true, false, fmt.Sprintf("run finished (%s)", m.Outcome)
+	}
+
+	host, _ := os.Hostname()
+	if m.Host == "" || m.Host != host {
+		return false, true, "run executed from a different host"
+	}
+
+	if processAlive(m.PID) {
+		return false, false, "run in progress"
+	}
+
+	return true, false, fmt.Sprintf("run process (pid %d) does not exist", m.PID)
+}
+
+// sessionState returns whether a session is known to be dead (and the
+// reason), or whether its state is unknown.
+// NB: a session is active until its monitor is deleted (see StopMonitor), so
+// sessions with an existing directory are considered active.
+func (conf *GCConf) sessionState(sessID string) (dead bool, unknown bool, reason string) {
+	info, err := os.Stat(SessionDir(conf.SessDirBase, sessID))
+	if err == nil && info.IsDir() {
+		return false, false, "session directory exists"
+	}
+	return false, true, fmt.Sprintf("session directory not found in %s", conf.SessDirBase)
+}
+
+// leftoverGroup are the objects of a single run, or the (non-run) objects of
+// a single session
+type leftoverGroup struct {
+	session string
+	run     string
+	objs    []kube.Object
+}
+
+func (g *leftoverGroup) selector() string {
+	if g.run != "" {
+		return fmt.Sprintf("%s=%s", runIdLabel, g.run)
+	}
+	return fmt.Sprintf("%s=%s,!%s", sessIdLabel, g.session, runIdLabel)
+}
+
+func (g *leftoverGroup) oldest() time.Time {
+	ret := g.objs[0].Created
+	for _, obj := range g.objs[1:] {
+		if obj.Created.Before(ret) {
+			ret = obj.Created
+		}
+	}
+	return ret
+}
+
+// gcKinds are the kinds of leftover objects: all kinds, and Cilium policies
+// (there are none if the Cilium CRDs are not installed, see Client.List).
+// NB: the order is preserved when deleting leftovers, so namespaces are last.
+var gcKinds = append([]kube.Kind{kube.KindCiliumNetworkPolicy}, kube.AllKinds...)
+
+// FindLeftovers returns the objects (of all namespaces) with a run or session
+// label that belong to dead runs or sessions. Objects of runs or sessions
+// whose state is unknown are considered leftovers if the oldest of them is
+// older than conf.MinAge.
+func FindLeftovers(cli kube.Client, conf *GCConf) ([]*Leftover, error) {
+	ctx, cancel := kubeCtx()
+	defer cancel()
+
+	// NB: runs and sessions may use their own namespace
+	cli = cli.WithNamespace(kube.AllNamespaces)
+
+	// NB: run objects might also have a session label, so we list them
+	// first, and then list objects that only have a session label.
+	runObjs, err := cli.List(ctx, runIdLabel, gcKinds...)
+	if err != nil {
+		return nil, err
+	}
+	sessObjs, err := cli.List(ctx, fmt.Sprintf("%s,!%s", sessIdLabel, runIdLabel), gcKinds...)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*leftoverGroup
+	groupIdx := make(map[string]*leftoverGroup)
+	for _, obj := range append(runObjs, sessObjs...) {
+		g := &leftoverGroup{session: obj.Labels[sessIdLabel], run: obj.Labels[runIdLabel]}
+		if eg, ok := groupIdx[g.selector()]; ok {
+			g = eg
+		} else {
+			groupIdx[g.selector()] = g
+			groups = append(groups, g)
+		}
+		g.objs = append(g.objs, obj)
+	}
+
+	var ret []*Leftover
+	for _, g := range groups {
+		var dead, unknown bool
+		var reason string
+		if g.run != "" {
+			dead, unknown, reason = conf.runState(g.run)
+		} else {
+			dead, unknown, reason = conf.sessionState(g.session)
+		}
+
+		if unknown && time.Since(g.oldest()) >= conf.MinAge {
+			dead = true
+		}
+		if !dead {
+			continue
+		}
+
+		for _, obj := range g.objs {
+			ret = append(ret, &Leftover{
+				Object:  obj,
+				Session: g.session,
+				Run:     g.run,
+				Reason:  reason,
+			})
+		}
+	}
+
+	return ret, nil
+}
+
+// DeleteLeftovers deletes leftover objects (and all other objects of the
+// same kind that belong to the same run or session)
+func DeleteLeftovers(cli kube.Client, leftovers []*Leftover) error {
+	cli = cli.WithNamespace(kube.AllNamespaces)
+	var selectors []string
+	kinds := make(map[string][]kube.Kind)
+	for _, l := range leftovers {
+		g := leftoverGroup{session: l.Session, run: l.Run}
+		sel := g.selector()
+		if _, ok := kinds[sel]; !ok {
+			selectors = append(selectors, sel)
+		}
+		found := false
+		for _, k := range kinds[sel] {
+			found = found || k == l.Kind
+		}
+		if !found {
+			kinds[sel] = append(kinds[sel], l.Kind)
+		}
+	}
+
+	for _, sel := range selectors {
+		ctx, cancel := kubeCtx()
+		err := cli.Delete(ctx, sel, kinds[sel]...)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to delete objects (selector: %s): %w", sel, err)
+		}
+	}
+
+	return nil
+}
+
+// WriteLeftovers writes a table with the leftover objects
+func WriteLeftovers(w io.Writer, leftovers []*Leftover) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tNAMESPACE\tNAME\tSESSION\tRUN\tAGE\tREASON")
+	for _, l := range leftovers {
+		ns := l.Namespace
+		if ns == "" {
+			ns = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			l.Kind, ns, l.Name, l.Session, l.Run,
+			time.Since(l.Created).Round(time.Second), l.Reason)
+	}
+	return tw.Flush()
+}
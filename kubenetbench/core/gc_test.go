@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+const gcTestYaml = `apiVersion: v1
+kind: Pod
+metadata:
+  name: %[1]s
+  labels:
+    %[2]s
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  labels:
+    %[2]s
+`
+
+func gcTestApply(t *testing.T, fake kube.Client, dir, name string, labels string) {
+	fname := filepath.Join(dir, name+".yaml")
+	err := ioutil.WriteFile(fname, []byte(fmt.Sprintf(gcTestYaml, name, labels)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Apply(context.Background(), fname); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gcTestManifest(t *testing.T, dir string, m *RunManifest) {
+	runDir := filepath.Join(dir, "sess1", m.RunID)
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSONFile(filepath.Join(runDir, "run.json"), m); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	host, _ := os.Hostname()
+	gcTestManifest(t, dir, &RunManifest{RunID: "finished", Outcome: OutcomeSuccess})
+	gcTestManifest(t, dir, &RunManifest{RunID: "running", Outcome: OutcomeRunning, Host: host, PID: os.Getpid()})
+	gcTestManifest(t, dir, &RunManifest{RunID: "crashed", Outcome: OutcomeRunning, Host: host, PID: 1 << 30})
+
+	fake := kube.NewFakeClient()
+	old := time.Now().Add(-2 * time.Hour)
+	now := time.Now()
+	fake.Now = func() time.Time { return now }
+	gcTestApply(t, fake, dir, "finished", "knb-sessid: sess1\n    knb-runid: finished")
+	gcTestApply(t, fake, dir, "running", "knb-sessid: sess1\n    knb-runid: running")
+	gcTestApply(t, fake, dir, "crashed", "knb-runid: crashed")
+	cnp := filepath.Join(dir, "crashed-cnp.yaml")
+	err = ioutil.WriteFile(cnp, []byte("apiVersion: cilium.io/v2\nkind: CiliumNetworkPolicy\nmetadata:\n  name: crashed\n  labels:\n    knb-runid: crashed\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Apply(context.Background(), cnp); err != nil {
+		t.Fatal(err)
+	}
+	// run with its own namespace
+	ctx := context.Background()
+	if err := fake.CreateNamespace(ctx, "knb-crashed-ns", map[string]string{"knb-runid": "crashed"}); err != nil {
+		t.Fatal(err)
+	}
+	gcTestApply(t, fake.WithNamespace("knb-crashed-ns"), dir, "crashed-ns", "knb-runid: crashed")
+	gcTestApply(t, fake, dir, "unknown-new", "knb-runid: unknown-new")
+	gcTestApply(t, fake, dir, "monitor1", "knb-sessid: sess1")
+	gcTestApply(t, fake, dir, "monitor-new", "knb-sessid: sess-new")
+	gcTestApply(t, fake, dir, "other", "app: other")
+	fake.Now = func() time.Time { return old }
+	gcTestApply(t, fake, dir, "unknown-old", "knb-runid: unknown-old")
+	gcTestApply(t, fake, dir, "monitor-old", "knb-sessid: sess-old")
+
+	conf := &GCConf{SessDirBase: dir, MinAge: time.Hour}
+	leftovers, err := FindLeftovers(fake, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, l := range leftovers {
+		got = append(got, fmt.Sprintf("%s/%s", l.Kind, l.Name))
+	}
+	sort.Strings(got)
+	expected := "ciliumnetworkpolicy/crashed namespace/knb-crashed-ns " +
+		"pod/crashed pod/crashed-ns pod/finished pod/monitor-old pod/unknown-old " +
+		"service/crashed service/crashed-ns service/finished service/monitor-old service/unknown-old"
+	if strings.Join(got, " ") != expected {
+		t.Fatalf("got leftovers %v while expected %s", got, expected)
+	}
+
+	if err := DeleteLeftovers(fake, leftovers); err != nil {
+		t.Fatal(err)
+	}
+
+	objs, err := fake.WithNamespace(kube.AllNamespaces).List(ctx, "", gcKinds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var remaining []string
+	for _, obj := range objs {
+		remaining = append(remaining, fmt.Sprintf("%s/%s", obj.Kind, obj.Name))
+	}
+	expectedRemaining := "pod/monitor-new pod/monitor1 pod/other pod/running pod/unknown-new " +
+		"service/monitor-new service/monitor1 service/other service/running service/unknown-new"
+	if strings.Join(remaining, " ") != expectedRemaining {
+		t.Errorf("got remaining objects %v while expected %s", remaining, expectedRemaining)
+	}
+}
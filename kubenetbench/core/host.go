@@ -4,13 +4,15 @@ import (
 	"github.com/cilium/kubenetbench/utils"
 )
 
-func (s *ContainerSpec) hostOptsWrite(pw *utils.PrefixWriter, params map[string]interface{}) {
+func (s *ContainerSpec) hostOptsWrite(pw *utils.PrefixWriter, params map[string]interface{}) error {
 	l := func(s string) {
 		pw.AppendNewLineOrDie(s)
 	}
 
 	if s.HostNetwork {
 		l(`hostNetwork: true`)
+		// resolve cluster names (e.g., of headless services)
+		l(`dnsPolicy: ClusterFirstWithHostNet`)
 	}
 
 	if s.HostIPC {
@@ -20,4 +22,45 @@ func (s *ContainerSpec) hostOptsWrite(pw *utils.PrefixWriter, params map[string]
 	if s.HostPID {
 		l(`hostPID: true`)
 	}
+	return nil
+}
+
+// Host network scenarios: pod2pod, where the client and/or the server run on
+// the host network. Comparing pod2pod, pod2node, and node2pod runs against the
+// node2node baseline quantifies the overhead of the pod network (CNI).
+const (
+	ScenarioPod2Node  = "pod2node"
+	ScenarioNode2Pod  = "node2pod"
+	ScenarioNode2Node = "node2node"
+)
+
+// HostScenario returns the scenario name of a pod2pod run, based on whether
+// the client and the server run on the host network
+func HostScenario(cliHost, srvHost bool) string {
+	switch {
+	case cliHost && srvHost:
+		return ScenarioNode2Node
+	case cliHost:
+		return ScenarioNode2Pod
+	case srvHost:
+		return ScenarioPod2Node
+	default:
+		return "pod2pod"
+	}
+}
+
+// HostScenarioSpec returns whether the client and the server of a host
+// network scenario run on the host network. ok is false if scenario is not a
+// host network scenario.
+func HostScenarioSpec(scenario string) (cliHost bool, srvHost bool, ok bool) {
+	switch scenario {
+	case ScenarioPod2Node:
+		return false, true, true
+	case ScenarioNode2Pod:
+		return true, false, true
+	case ScenarioNode2Node:
+		return true, true, true
+	default:
+		return false, false, false
+	}
 }
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// latency percentiles requested from the HTTP load generator
+var httpPercentiles = []string{"50", "90", "99", "99.9"}
+
+// HTTPConf is the configuration of the HTTP load benchmark. It uses fortio
+// both as the server (echo endpoint) and as the (constant-rate) load
+// generator.
+type HTTPConf struct {
+	Timeout int    `json:"timeout"`
+	Port    uint16 `json:"port"`
+	Image   string `json:"image"`
+	// requests per second (across all connections). 0 means no rate
+	// limiting (i.e., as fast as possible).
+	QPS         int `json:"qps"`
+	Connections int `json:"connections"`
+	// size of the responses, and of the request payload (0: GET requests
+	// without payload)
+	ResponseSize int      `json:"response_size,omitempty"`
+	PayloadSize  int      `json:"payload_size,omitempty"`
+	MoreArgs     []string `json:"more_args,omitempty"`
+}
+
+// HTTPConfDefault returns an HTTPConf with the default values
+func HTTPConfDefault() HTTPConf {
+	return HTTPConf{
+		Timeout:     60,
+		Port:        8080,
+		Image:       "fortio/fortio",
+		QPS:         1000,
+		Connections: 8,
+	}
+}
+
+// GetTimeout returns the benchmark timeout
+func (cnf *HTTPConf) GetTimeout() int {
+	return cnf.Timeout
+}
+
+// GetName returns the benchmark name
+func (cnf *HTTPConf) GetName() string {
+	return "http"
+}
+
+// GetTestName returns the test name
+func (cnf *HTTPConf) GetTestName() string {
+	return "http_load"
+}
+
+// WriteSrvContainerYaml writes the server yaml
+func (cnf *HTTPConf) WriteSrvContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	pw.AppendNewLineOrDie(`name: http-srv`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`image: %s`, cnf.Image))
+	pw.AppendNewLineOrDie(`command: ["fortio"]`)
+	pw.AppendNewLineOrDie(`args : [`)
+	pw.PushPrefix("    ")
+	pw.AppendNewLineOrDie(`"server",`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-http-port", "%d",`, cnf.Port))
+	pw.AppendNewLineOrDie(`"-grpc-port", "disabled",`)
+	pw.AppendNewLineOrDie(`"-redirect-port", "disabled",`)
+	pw.PopPrefix()
+	pw.AppendNewLineOrDie(`]`)
+	pw.AppendNewLineOrDie(`ports:`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`- containerPort: %d`, cnf.Port))
+	// the server is ready when it accepts connections. NB: a startup probe
+	// (unlike a readiness probe) does not connect to the server while the
+	// clients execute (see tcpStatsWrite).
+	pw.AppendNewLineOrDie(`startupProbe:`)
+	pw.AppendNewLineOrDie(`  tcpSocket:`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`    port: %d`, cnf.Port))
+	pw.AppendNewLineOrDie(`  periodSeconds: 1`)
+	pw.AppendNewLineOrDie(`  failureThreshold: 60`)
+	return nil
+}
+
+// WriteSrvPortsYaml writes the ports part of yaml (e.g., for services)
+func (cnf *HTTPConf) WriteSrvPortsYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	pw.AppendNewLineOrDie(`- name: http`)
+	pw.AppendNewLineOrDie(`  protocol: TCP`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  port: %d`, cnf.Port))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  targetPort: %d`, cnf.Port))
+	return nil
+}
+
+func (cnf *HTTPConf) url(serverIP interface{}, port int) string {
+	ret := fmt.Sprintf("http://%v:%d/echo", serverIP, port)
+	if cnf.ResponseSize > 0 {
+		ret = fmt.Sprintf("%s?size=%d", ret, cnf.ResponseSize)
+	}
+	return ret
+}
+
+// WriteCliContainerYaml writes the client yaml
+func (cnf *HTTPConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	pw.AppendNewLineOrDie(`name: http-cli`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`image: %s`, cnf.Image))
+	cliCommandWrite(pw, params, "fortio")
+	pw.AppendNewLineOrDie(`args : [`)
+	pw.PushPrefix("    ")
+	pw.AppendNewLineOrDie(`"load",`)
+	if err := cnf.writeLoadArgs(pw, params); err != nil {
+		return err
+	}
+	pw.PopPrefix()
+	pw.AppendNewLineOrDie(`]`)
+	return nil
+}
+
+// writeLoadArgs writes the arguments of the fortio load command, followed by
+// the URL of the server
+func (cnf *HTTPConf) writeLoadArgs(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	serverIP, ok := params["serverIP"]
+	if !ok {
+		return errServerIPUndefined
+	}
+
+	qps := cnf.QPS
+	if qps <= 0 {
+		// fortio: -1 means no rate limiting
+		qps = -1
+	}
+
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-t", "%ds", # timeout`, cnf.Timeout))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-qps", "%d",`, qps))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-c", "%d", # connections`, cnf.Connections))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-p", "%s", # percentiles`, strings.Join(httpPercentiles, ",")))
+	if cnf.PayloadSize > 0 {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"-payload-size", "%d",`, cnf.PayloadSize))
+	}
+	pw.AppendNewLineOrDie(`"-quiet",`)
+	pw.AppendNewLineOrDie(`"-json", "-", # JSON output to stdout`)
+	if len(cnf.MoreArgs) > 0 {
+		pw.AppendNewLineOrDie("# Additional args")
+		for _, arg := range cnf.MoreArgs {
+			pw.AppendNewLineOrDie(fmt.Sprintf(`"%s",`, arg))
+		}
+	}
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"%s",`, cnf.url(serverIP, clientPort(params, int(cnf.Port)))))
+	return nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fortioHistogram is a fortio duration histogram (durations are in seconds)
+type fortioHistogram struct {
+	Count       int64   `json:"Count"`
+	Min         float64 `json:"Min"`
+	Max         float64 `json:"Max"`
+	Avg         float64 `json:"Avg"`
+	StdDev      float64 `json:"StdDev"`
+	Percentiles []struct {
+		Percentile float64 `json:"Percentile"`
+		Value      float64 `json:"Value"`
+	} `json:"Percentiles"`
+}
+
+// fortioOutput is the part of the fortio load JSON output used by kubenetbench
+type fortioOutput struct {
+	RunType           string           `json:"RunType"`
+	Version           string           `json:"Version"`
+	URL               string           `json:"URL"`
+	NumThreads        int              `json:"NumThreads"`
+	RequestedQPS      string           `json:"RequestedQPS"`
+	ActualQPS         float64          `json:"ActualQPS"`
+	DurationHistogram *fortioHistogram `json:"DurationHistogram"`
+	RetCodes          map[string]int64 `json:"RetCodes"`
+}
+
+// httpLatencyMetric returns the metric name for a latency percentile (e.g.,
+// p99_latency for 99, and p999_latency for 99.9)
+func httpLatencyMetric(percentile float64) string {
+	p := strconv.FormatFloat(percentile, 'f', -1, 64)
+	return fmt.Sprintf("p%s_latency", strings.Replace(p, ".", "", -1))
+}
+
+// findFortioOutput finds the fortio JSON result in the client output. The
+// output also includes the fortio logs, which might also be JSON (one object
+// per line), so we look for the first object that has a duration histogram.
+func findFortioOutput(data []byte) *fortioOutput {
+	for offset := 0; offset < len(data); {
+		rest := data[offset:]
+		next := len(data)
+		if idx := bytes.IndexByte(rest, '\n'); idx >= 0 {
+			next = offset + idx + 1
+		}
+		line := bytes.TrimSpace(data[offset:next])
+		offset = next
+		if !bytes.HasPrefix(line, []byte("{")) {
+			continue
+		}
+
+		out := &fortioOutput{}
+		err := json.NewDecoder(bytes.NewReader(rest)).Decode(out)
+		if err == nil && out.DurationHistogram != nil {
+			return out
+		}
+	}
+	return nil
+}
+
+// parseHTTPOutput parses the output of the fortio load generator
+func parseHTTPOutput(r io.Reader, test string) (*BenchResult, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := NewBenchResult("http", test)
+	out := findFortioOutput(data)
+	if out == nil {
+		res.addWarning(0, "", "no JSON result in output")
+		return res, nil
+	}
+
+	res.Info["version"] = out.Version
+	res.Info["url"] = out.URL
+	res.Info["connections"] = strconv.Itoa(out.NumThreads)
+
+	res.Metrics[MetricThroughput] = Metric{out.ActualQPS, "req/s"}
+	if qps, err := strconv.ParseFloat(out.RequestedQPS, 64); err == nil {
+		res.Metrics["requested_qps"] = Metric{qps, "req/s"}
+	}
+
+	h := out.DurationHistogram
+	if h.Count == 0 {
+		res.addWarning(0, "DurationHistogram", "no requests completed")
+	}
+	res.Metrics["requests"] = Metric{Value: float64(h.Count)}
+	res.Metrics[MetricMeanLatency] = Metric{h.Avg * 1e6, "us"}
+	res.Metrics["min_latency"] = Metric{h.Min * 1e6, "us"}
+	res.Metrics["max_latency"] = Metric{h.Max * 1e6, "us"}
+	res.Metrics["stdev_latency"] = Metric{h.StdDev * 1e6, "us"}
+	for _, p := range h.Percentiles {
+		res.Metrics[httpLatencyMetric(p.Percentile)] = Metric{p.Value * 1e6, "us"}
+	}
+	for _, p := range httpPercentiles {
+		v, _ := strconv.ParseFloat(p, 64)
+		if _, ok := res.Metrics[httpLatencyMetric(v)]; !ok {
+			res.addWarning(0, httpLatencyMetric(v), "missing percentile")
+		}
+	}
+
+	// errors are requests that did not get a 2xx response (fortio uses -1
+	// for connection errors)
+	codes := make([]string, 0, len(out.RetCodes))
+	var errCount, total int64
+	for code, n := range out.RetCodes {
+		codes = append(codes, fmt.Sprintf("%s:%d", code, n))
+		total += n
+		if !strings.HasPrefix(code, "2") {
+			errCount += n
+		}
+	}
+	sort.Strings(codes)
+	res.Info["ret_codes"] = strings.Join(codes, ",")
+	res.Metrics["errors"] = Metric{Value: float64(errCount)}
+	if total > 0 {
+		res.Metrics["error_percent"] = Metric{100 * float64(errCount) / float64(total), "%"}
+	}
+
+	return res, nil
+}
+
+// ParseCliOutput parses the client output
+func (cnf *HTTPConf) ParseCliOutput(r io.Reader) (*BenchResult, error) {
+	return parseHTTPOutput(r, cnf.GetTestName())
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+var fortioLoadOutput = `{"ts":1603877491.123,"level":"info","file":"periodic.go","line":225,"msg":"Starting at 1000 qps with 8 thread(s) [gomax 4] for 30s"}
+{
+  "RunType": "HTTP",
+  "Labels": "",
+  "Version": "1.60.3",
+  "RequestedQPS": "1000",
+  "RequestedDuration": "30s",
+  "ActualQPS": 999.5,
+  "ActualDuration": 30001234567,
+  "NumThreads": 8,
+  "DurationHistogram": {
+    "Count": 29985,
+    "Min": 0.000112,
+    "Max": 0.0215,
+    "Sum": 12.5,
+    "Avg": 0.000417,
+    "StdDev": 0.0002,
+    "Data": [{"Start": 0.000112, "End": 0.001, "Percent": 99.1, "Count": 29715}],
+    "Percentiles": [
+      {"Percentile": 50, "Value": 0.00039},
+      {"Percentile": 90, "Value": 0.00061},
+      {"Percentile": 99, "Value": 0.0012}
+    ]
+  },
+  "RetCodes": {"200": 29970, "503": 10, "-1": 5},
+  "URL": "http://10.0.0.3:8080/echo?size=1024"
+}
+{"ts":1603877521.2,"level":"info","msg":"Successfully wrote 4527 bytes of Json data to stdout"}
+`
+
+func TestParseHTTP(t *testing.T) {
+	res, err := parseHTTPOutput(strings.NewReader(fortioLoadOutput), "http_load")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	metrics := map[string]Metric{
+		MetricThroughput:  {999.5, "req/s"},
+		"requested_qps":   {1000, "req/s"},
+		"requests":        {Value: 29985},
+		MetricP50Latency:  {390, "us"},
+		MetricP90Latency:  {610, "us"},
+		MetricP99Latency:  {1200, "us"},
+		MetricMeanLatency: {417, "us"},
+		"errors":          {Value: 15},
+	}
+	for name, expected := range metrics {
+		m, ok := res.Metrics[name]
+		// NB: latencies are converted from seconds, so compare approximately
+		if !ok || m.Unit != expected.Unit || m.Value-expected.Value > 1e-6 || expected.Value-m.Value > 1e-6 {
+			t.Errorf("metric %s: got %v (exists:%t) while expected %v", name, m, ok, expected)
+		}
+	}
+
+	if res.Info["ret_codes"] != "-1:5,200:29970,503:10" || res.Info["connections"] != "8" {
+		t.Errorf("unexpected info: %v", res.Info)
+	}
+
+	// p99.9 was not reported
+	if len(res.Warnings) != 1 || res.Warnings[0].Key != MetricP999Latency {
+		t.Errorf("got warnings %v while expected a missing %s warning", res.Warnings, MetricP999Latency)
+	}
+}
+
+func TestParseHTTPNoResult(t *testing.T) {
+	out := `{"ts":1603877491.123,"level":"fatal","msg":"Unable to connect to 10.0.0.3:8080"}` + "\n"
+	res, err := parseHTTPOutput(strings.NewReader(out), "http_load")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	if len(res.Warnings) != 1 || len(res.Metrics) != 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// IperfConf is the iperf3 configuration
+type IperfConf struct {
+	Timeout int    `json:"timeout"`
+	Port    uint16 `json:"port"`
+	UDP     bool   `json:"udp"`
+	// number of parallel client streams (iperf3 -P), <= 1: single stream
+	Parallel int `json:"parallel,omitempty"`
+	// reverse mode: the server sends and the client receives (iperf3 -R)
+	Reverse bool `json:"reverse,omitempty"`
+	// target bitrate in bits/sec, using iperf3 notation (e.g., 100M) (iperf3 -b)
+	Bitrate string `json:"bitrate,omitempty"`
+	// socket buffer (window) size, using iperf3 notation (e.g., 256K) (iperf3 -w)
+	Window   string   `json:"window,omitempty"`
+	MoreArgs []string `json:"more_args,omitempty"`
+}
+
+// IperfConfDefault returns an IperfConf with the default values
+func IperfConfDefault() IperfConf {
+	return IperfConf{
+		Timeout: 60,
+		Port:    5201,
+	}
+}
+
+// GetTimeout returns the benchmark timeout
+func (cnf *IperfConf) GetTimeout() int {
+	return cnf.Timeout
+}
+
+// GetName returns the benchmark name
+func (cnf *IperfConf) GetName() string {
+	return "iperf3"
+}
+
+// GetTestName returns the test name. Names follow the netperf test names
+// (e.g., tcp_stream, tcp_maerts for reverse mode), so that results can be
+// compared across tools.
+func (cnf *IperfConf) GetTestName() string {
+	proto := "tcp"
+	if cnf.UDP {
+		proto = "udp"
+	}
+	if cnf.Reverse {
+		return proto + "_maerts"
+	}
+	return proto + "_stream"
+}
+
+// WriteSrvContainerYaml writes the server yaml
+func (cnf *IperfConf) WriteSrvContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	pw.AppendNewLineOrDie(`name: iperf-srv`)
+	pw.AppendNewLineOrDie(`image: cilium/kubenetbench`)
+	pw.AppendNewLineOrDie(`command: ["iperf3"]`)
+	pw.AppendNewLineOrDie(`args : [`)
+	pw.PushPrefix("    ")
+	pw.AppendNewLineOrDie(`"-s", # server mode`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-p", "%d",`, cnf.Port))
+	pw.PopPrefix()
+	pw.AppendNewLineOrDie(`]`)
+	return nil
+}
+
+// CountSrvConnections counts the tests handled by an iperf3 server, based on
+// its logs
+func (cnf *IperfConf) CountSrvConnections(r io.Reader) (int, error) {
+	return countLinesWithPrefix(r, "Accepted connection from")
+}
+
+// WriteSrvPortsYaml writes the ports part of yaml (e.g., for services)
+// NB: iperf3 uses a TCP control connection, and the same port number for
+// (TCP or UDP) data connections. Hence, for UDP over NodePort services, the
+// node port is the iperf3 port (which needs to be in the node port range).
+func (cnf *IperfConf) WriteSrvPortsYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	pw.AppendNewLineOrDie(`- name: iperf-tcp`)
+	pw.AppendNewLineOrDie(`  protocol: TCP`)
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  port: %d`, cnf.Port))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  targetPort: %d`, cnf.Port))
+	if cnf.UDP {
+		if err := pinNodePortWrite(pw, params, int(cnf.Port)); err != nil {
+			return err
+		}
+		pw.AppendNewLineOrDie(`- name: iperf-udp`)
+		pw.AppendNewLineOrDie(`  protocol: UDP`)
+		pw.AppendNewLineOrDie(fmt.Sprintf(`  port: %d`, cnf.Port))
+		pw.AppendNewLineOrDie(fmt.Sprintf(`  targetPort: %d`, cnf.Port))
+		return pinNodePortWrite(pw, params, int(cnf.Port))
+	}
+	return nil
+}
+
+// PinnedPorts returns the server port for UDP tests, whose node port is pinned
+// for NodePort services (see WriteSrvPortsYaml)
+func (cnf *IperfConf) PinnedPorts(clients int) []int {
+	if cnf.UDP {
+		return []int{int(cnf.Port)}
+	}
+	return nil
+}
+
+// WriteCliContainerYaml writes the client yaml
+func (cnf *IperfConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	serverIP, ok := params["serverIP"]
+	if !ok {
+		return errServerIPUndefined
+	}
+
+	pw.AppendNewLineOrDie(`name: iperf-cli`)
+	pw.AppendNewLineOrDie(`image: cilium/kubenetbench`)
+	cliCommandWrite(pw, params, "iperf3")
+	pw.AppendNewLineOrDie(`args : [`)
+	pw.PushPrefix("    ")
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-c", "%v",`, serverIP))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-p", "%d",`, clientPort(params, int(cnf.Port))))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-t", "%d", # timeout`, cnf.Timeout))
+	pw.AppendNewLineOrDie(`"-J", # JSON output`)
+	if cnf.UDP {
+		pw.AppendNewLineOrDie(`"-u",`)
+	}
+	if cnf.Parallel > 1 {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"-P", "%d", # parallel streams`, cnf.Parallel))
+	}
+	if cnf.Reverse {
+		pw.AppendNewLineOrDie(`"-R", # reverse mode`)
+	}
+	if cnf.Bitrate != "" {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"-b", "%s",`, cnf.Bitrate))
+	}
+	if cnf.Window != "" {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"-w", "%s",`, cnf.Window))
+	}
+	if len(cnf.MoreArgs) > 0 {
+		pw.AppendNewLineOrDie("# Additional args")
+		for _, arg := range cnf.MoreArgs {
+			pw.AppendNewLineOrDie(fmt.Sprintf(`"%s",`, arg))
+		}
+	}
+	pw.PopPrefix()
+	pw.AppendNewLineOrDie(`]`)
+	return nil
+}
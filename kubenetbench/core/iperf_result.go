@@ -0,0 +1,170 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// iperf3 throughput is reported in the same units as netperf stream tests
+const iperfThroughputUnit = "10^6bits/s"
+
+// iperfSum is a summary of (one or all) iperf3 streams. Fields that are
+// specific to TCP or UDP are pointers, so that missing fields can be
+// detected.
+type iperfSum struct {
+	Seconds       float64  `json:"seconds"`
+	Bytes         float64  `json:"bytes"`
+	BitsPerSecond float64  `json:"bits_per_second"`
+	Retransmits   *float64 `json:"retransmits"`
+	JitterMs      *float64 `json:"jitter_ms"`
+	LostPackets   *float64 `json:"lost_packets"`
+	Packets       *float64 `json:"packets"`
+	LostPercent   *float64 `json:"lost_percent"`
+}
+
+// iperfOutput is the part of the iperf3 JSON output (-J) used by kubenetbench
+type iperfOutput struct {
+	Start struct {
+		Version    string `json:"version"`
+		SystemInfo string `json:"system_info"`
+		TestStart  struct {
+			Protocol string `json:"protocol"`
+			Reverse  int    `json:"reverse"`
+		} `json:"test_start"`
+	} `json:"start"`
+	End struct {
+		Streams []struct {
+			Sender   *iperfSum `json:"sender"`
+			Receiver *iperfSum `json:"receiver"`
+			UDP      *iperfSum `json:"udp"`
+		} `json:"streams"`
+		SumSent     *iperfSum `json:"sum_sent"`
+		SumReceived *iperfSum `json:"sum_received"`
+		// UDP summary (includes jitter and losses)
+		Sum *iperfSum `json:"sum"`
+		CPU *struct {
+			HostTotal   float64 `json:"host_total"`
+			RemoteTotal float64 `json:"remote_total"`
+		} `json:"cpu_utilization_percent"`
+	} `json:"end"`
+	Error string `json:"error"`
+}
+
+// iperfMetrics adds the metrics of the sent, received, and UDP summaries (any
+// of which may be nil) to res. The throughput metric is the receiver
+// throughput.
+func iperfMetrics(res *BenchResult, sent, received, udp *iperfSum) {
+	switch {
+	case received != nil:
+		res.Metrics[MetricThroughput] = Metric{received.BitsPerSecond / 1e6, iperfThroughputUnit}
+		res.Metrics["bytes_received"] = Metric{received.Bytes, "bytes"}
+	case udp != nil:
+		res.Metrics[MetricThroughput] = Metric{udp.BitsPerSecond / 1e6, iperfThroughputUnit}
+	default:
+		res.addWarning(0, "sum_received", "missing field")
+	}
+
+	if sent != nil {
+		res.Metrics["sender_throughput"] = Metric{sent.BitsPerSecond / 1e6, iperfThroughputUnit}
+		res.Metrics["bytes_sent"] = Metric{sent.Bytes, "bytes"}
+		if sent.Retransmits != nil {
+			res.Metrics["retransmits"] = Metric{Value: *sent.Retransmits}
+		}
+	}
+
+	if udp != nil {
+		if udp.JitterMs != nil {
+			res.Metrics["jitter"] = Metric{*udp.JitterMs, "ms"}
+		}
+		if udp.LostPackets != nil {
+			res.Metrics["lost_packets"] = Metric{Value: *udp.LostPackets}
+		}
+		if udp.Packets != nil {
+			res.Metrics["packets"] = Metric{Value: *udp.Packets}
+		}
+		if udp.LostPercent != nil {
+			res.Metrics["lost_percent"] = Metric{*udp.LostPercent, "%"}
+		}
+	}
+}
+
+// parseIperfOutput parses the JSON output of an iperf3 client. Text before
+// the JSON document (e.g., warnings printed to stderr) is reported as
+// warnings.
+func parseIperfOutput(r io.Reader, test string) (*BenchResult, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := NewBenchResult("iperf3", test)
+	idx := bytes.IndexByte(data, '{')
+	if idx < 0 {
+		res.addWarning(0, "", "no JSON output")
+		return res, nil
+	}
+	for i, line := range strings.Split(string(data[:idx]), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			res.addWarning(i+1, "", fmt.Sprintf("unexpected output: %q", line))
+		}
+	}
+
+	out := &iperfOutput{}
+	if err := json.NewDecoder(bytes.NewReader(data[idx:])).Decode(out); err != nil {
+		res.addWarning(0, "", fmt.Sprintf("invalid JSON output: %s", err))
+		return res, nil
+	}
+
+	if out.Error != "" {
+		res.addWarning(0, "error", out.Error)
+	}
+
+	ts := &out.Start.TestStart
+	udp := strings.EqualFold(ts.Protocol, "UDP")
+	res.Info["version"] = out.Start.Version
+	res.Info["system_info"] = out.Start.SystemInfo
+	res.Info["protocol"] = ts.Protocol
+	res.Info["reverse"] = strconv.FormatBool(ts.Reverse != 0)
+
+	end := &out.End
+	if udp {
+		iperfMetrics(res, end.SumSent, end.SumReceived, end.Sum)
+	} else {
+		if end.SumSent == nil {
+			res.addWarning(0, "sum_sent", "missing field")
+		}
+		iperfMetrics(res, end.SumSent, end.SumReceived, nil)
+	}
+
+	if end.CPU != nil {
+		res.Metrics["local_cpu_util"] = Metric{end.CPU.HostTotal, "%"}
+		res.Metrics["remote_cpu_util"] = Metric{end.CPU.RemoteTotal, "%"}
+	}
+
+	if len(end.Streams) > 1 {
+		res.Info["streams"] = strconv.Itoa(len(end.Streams))
+		for i, s := range end.Streams {
+			sres := NewBenchResult("iperf3", test)
+			sres.Info["stream"] = fmt.Sprintf("%02d", i)
+			iperfMetrics(sres, s.Sender, s.Receiver, s.UDP)
+			for _, w := range sres.Warnings {
+				w.Message = fmt.Sprintf("stream %02d: %s", i, w.Message)
+				res.Warnings = append(res.Warnings, w)
+			}
+			sres.Warnings = nil
+			res.Streams = append(res.Streams, sres)
+		}
+	}
+
+	return res, nil
+}
+
+// ParseCliOutput parses the client output
+func (cnf *IperfConf) ParseCliOutput(r io.Reader) (*BenchResult, error) {
+	return parseIperfOutput(r, cnf.GetTestName())
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+var iperfTCPOutput = `warning: this is a test warning
+{
+	"start": {
+		"version": "iperf 3.9",
+		"system_info": "Linux knb-cli-x7k2p 5.10.0 #1 SMP x86_64",
+		"test_start": {"protocol": "TCP", "num_streams": 2, "duration": 10, "reverse": 1}
+	},
+	"intervals": [],
+	"end": {
+		"streams": [
+			{
+				"sender": {"socket": 5, "seconds": 10, "bytes": 5000000000, "bits_per_second": 4000000000, "retransmits": 3},
+				"receiver": {"socket": 5, "seconds": 10, "bytes": 4950000000, "bits_per_second": 3960000000}
+			},
+			{
+				"sender": {"socket": 7, "seconds": 10, "bytes": 6000000000, "bits_per_second": 4800000000, "retransmits": 7},
+				"receiver": {"socket": 7, "seconds": 10, "bytes": 5900000000, "bits_per_second": 4720000000}
+			}
+		],
+		"sum_sent": {"seconds": 10, "bytes": 11000000000, "bits_per_second": 8800000000, "retransmits": 10},
+		"sum_received": {"seconds": 10, "bytes": 10850000000, "bits_per_second": 8680000000},
+		"cpu_utilization_percent": {"host_total": 55.5, "remote_total": 12.25}
+	}
+}
+`
+
+var iperfUDPOutput = `{
+	"start": {
+		"version": "iperf 3.7",
+		"test_start": {"protocol": "UDP", "num_streams": 1, "reverse": 0}
+	},
+	"end": {
+		"streams": [
+			{"udp": {"socket": 5, "seconds": 10, "bytes": 1310720, "bits_per_second": 1048576, "jitter_ms": 0.012, "lost_packets": 2, "packets": 906, "lost_percent": 0.22}}
+		],
+		"sum": {"seconds": 10, "bytes": 1310720, "bits_per_second": 1048576, "jitter_ms": 0.012, "lost_packets": 2, "packets": 906, "lost_percent": 0.22}
+	}
+}
+`
+
+func TestParseIperfTCP(t *testing.T) {
+	res, err := parseIperfOutput(strings.NewReader(iperfTCPOutput), "tcp_maerts")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	metrics := map[string]Metric{
+		MetricThroughput:    {8680, iperfThroughputUnit},
+		"sender_throughput": {8800, iperfThroughputUnit},
+		"retransmits":       {Value: 10},
+		"bytes_received":    {10850000000, "bytes"},
+		"local_cpu_util":    {55.5, "%"},
+		"remote_cpu_util":   {12.25, "%"},
+	}
+	for name, expected := range metrics {
+		if m, ok := res.Metrics[name]; !ok || m != expected {
+			t.Errorf("metric %s: got %v (exists:%t) while expected %v", name, m, ok, expected)
+		}
+	}
+
+	if res.Tool != "iperf3" || res.Test != "tcp_maerts" {
+		t.Errorf("unexpected tool/test: %s/%s", res.Tool, res.Test)
+	}
+	if res.Info["protocol"] != "TCP" || res.Info["reverse"] != "true" || res.Info["streams"] != "2" {
+		t.Errorf("unexpected info: %v", res.Info)
+	}
+
+	if len(res.Streams) != 2 {
+		t.Fatalf("got %d streams while expected 2", len(res.Streams))
+	}
+	if m := res.Streams[1].Metrics[MetricThroughput]; m.Value != 4720 {
+		t.Errorf("stream 1: got throughput %v while expected 4720", m)
+	}
+
+	warnings := []ResultWarning{
+		{Line: 1, Message: `unexpected output: "warning: this is a test warning"`},
+	}
+	if len(res.Warnings) != len(warnings) || res.Warnings[0] != warnings[0] {
+		t.Errorf("got warnings %v while expected %v", res.Warnings, warnings)
+	}
+}
+
+func TestParseIperfUDP(t *testing.T) {
+	res, err := parseIperfOutput(strings.NewReader(iperfUDPOutput), "udp_stream")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	metrics := map[string]Metric{
+		MetricThroughput: {1.048576, iperfThroughputUnit},
+		"jitter":         {0.012, "ms"},
+		"lost_packets":   {Value: 2},
+		"lost_percent":   {0.22, "%"},
+	}
+	for name, expected := range metrics {
+		if m, ok := res.Metrics[name]; !ok || m != expected {
+			t.Errorf("metric %s: got %v (exists:%t) while expected %v", name, m, ok, expected)
+		}
+	}
+	if len(res.Streams) != 0 || len(res.Warnings) != 0 {
+		t.Errorf("unexpected streams (%d) or warnings (%v)", len(res.Streams), res.Warnings)
+	}
+}
+
+func TestParseIperfError(t *testing.T) {
+	out := `{"start": {}, "end": {}, "error": "error - unable to connect to server: Connection refused"}`
+	res, err := parseIperfOutput(strings.NewReader(out), "tcp_stream")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	if _, ok := res.GetMetric(MetricThroughput); ok {
+		t.Errorf("unexpected throughput metric")
+	}
+
+	found := false
+	for _, w := range res.Warnings {
+		found = found || (w.Key == "error" && strings.Contains(w.Message, "Connection refused"))
+	}
+	if !found {
+		t.Errorf("missing error warning in %v", res.Warnings)
+	}
+
+	res, err = parseIperfOutput(strings.NewReader("iperf3: error - bad\n"), "tcp_stream")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Message != "no JSON output" {
+		t.Errorf("got warnings %v while expected no JSON output", res.Warnings)
+	}
+}
@@ -0,0 +1,284 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+// timeout for individual Kubernetes API operations
+const kubeOpTimeout = 30 * time.Second
+
+func kubeCtx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), kubeOpTimeout)
+}
+
+// kubeGetPod returns the single pod that matches the selector
+func kubeGetPod(cli kube.Client, selector string) (*kube.Pod, error) {
+	ctx, cancel := kubeCtx()
+	defer cancel()
+
+	pods, err := cli.GetPods(ctx, selector, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods (selector: %s): %w", selector, err)
+	}
+
+	if len(pods) != 1 {
+		return nil, fmt.Errorf("selector %s did not provide a single pod (got %d pods)", selector, len(pods))
+	}
+
+	return &pods[0], nil
+}
+
+// KubeGetPods returns the pods of the current run
+func (c *RunBenchCtx) KubeGetPods() ([]kube.Pod, error) {
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	return c.kube.GetPods(ctx, c.getRunLabel("="), "")
+}
+
+// KubeGetPodName returns the name of a pod
+func (c *RunBenchCtx) KubeGetPodName(selector string) (string, error) {
+	pod, err := kubeGetPod(c.kube, selector)
+	if err != nil {
+		return "", err
+	}
+	return pod.Name, nil
+}
+
+// KubeSaveLogs saves logs using a selector
+func (c *RunBenchCtx) KubeSaveLogs(selector string, logfile string) error {
+	podname, err := c.KubeGetPodName(selector)
+	if err != nil {
+		return fmt.Errorf("Failed to get pod name: %w", err)
+	}
+	return c.KubeSavePodLogs(podname, "", logfile)
+}
+
+// KubeSavePodLogs saves the logs of a container of a pod. The container may be
+// empty if the pod has a single container.
+func (c *RunBenchCtx) KubeSavePodLogs(podname string, container string, logfile string) error {
+	f, err := os.Create(logfile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	log.Printf("saving logs of pod %s to %s", podname, logfile)
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	return c.kube.GetLogs(ctx, podname, container, f)
+}
+
+// KubeGetService returns the (single) service that matches the selector
+func (c *RunBenchCtx) KubeGetService(selector string) (*kube.Service, error) {
+	ctx, cancel := kubeCtx()
+	defer cancel()
+
+	svcs, err := c.kube.GetServices(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(svcs) != 1 {
+		return nil, fmt.Errorf("selector %s did not provide a single service (got %d services)", selector, len(svcs))
+	}
+
+	return &svcs[0], nil
+}
+
+// KubeGetServiceIP returns the ip of a service
+// NB: probably a better option to use DNS
+func (c *RunBenchCtx) KubeGetServiceIP(selector string) (string, error) {
+	svc, err := c.KubeGetService(selector)
+	if err != nil {
+		return "", err
+	}
+
+	ip := svc.ClusterIP
+	if ip == "" || ip == "None" {
+		return "", fmt.Errorf("service %s has no cluster IP", svc.Name)
+	}
+
+	return ip, nil
+}
+
+// KubeApply applies a manifest file (unless the run was interrupted)
+func (c *RunBenchCtx) KubeApply(fname string) error {
+	if err := c.interrupted(); err != nil {
+		return err
+	}
+	log.Printf("applying %s (namespace: %s)", fname, c.kube.Namespace())
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	return c.kube.Apply(ctx, fname)
+}
+
+// KubeApply applies a manifest file
+func (s *Session) KubeApply(fname string) error {
+	log.Printf("applying %s (namespace: %s)", fname, s.kube.Namespace())
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	return s.kube.Apply(ctx, fname)
+}
+
+// KubeCreateNamespace creates a namespace
+func (s *Session) KubeCreateNamespace(name string, labels map[string]string) error {
+	log.Printf("creating namespace %s", name)
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	err := s.kube.CreateNamespace(ctx, name, labels)
+	if err != nil {
+		return fmt.Errorf("failed to create namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+// KubeCreateNamespace creates the namespace of the run, if the session uses
+// a namespace per run
+func (c *RunBenchCtx) KubeCreateNamespace() error {
+	if c.session.namespacePer != NamespacePerRun {
+		return nil
+	}
+	return c.session.KubeCreateNamespace(c.kube.Namespace(), map[string]string{
+		sessIdLabel: c.session.id,
+		runIdLabel:  c.runid,
+	})
+}
+
+// KubeCleanup deletes pods and networkpolicies from our run (and the run
+// namespace, if any)
+// NB: this matches on the runid, so objectgs that have a session label and not
+// a runid label (e.g., the monitor) do not match
+func (c *RunBenchCtx) KubeCleanup() error {
+	if !c.cleanup {
+		log.Printf("Cleanup disabled")
+		return nil
+	}
+
+	selector := c.getRunLabel("=")
+	kinds := []kube.Kind{kube.KindPod, kube.KindDeployment, kube.KindJob, kube.KindService, kube.KindNetworkPolicy}
+	kinds = append(kinds, c.cleanupKinds...)
+	if c.session.namespacePer == NamespacePerRun {
+		kinds = append(kinds, kube.KindNamespace)
+	}
+	log.Printf("deleting %v objects of %s", kinds, selector)
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	return c.kube.Delete(ctx, selector, kinds...)
+}
+
+// time to wait for a deleted client pod to go away
+const kubeDeleteWaitTimeout = 2 * time.Minute
+
+// KubeDeleteClient deletes the client pod(s) of the run, and the client job if
+// any (and waits until the pods are deleted)
+func (c *RunBenchCtx) KubeDeleteClient() error {
+	selector := c.getCliSelector()
+	log.Printf("deleting client pods %s", selector)
+
+	ctx, cancel := context.WithTimeout(context.Background(), kubeDeleteWaitTimeout)
+	defer cancel()
+
+	// start watching before deleting, so that no deletion is missed
+	events, err := c.kube.WatchPods(ctx, selector)
+	if err != nil {
+		return err
+	}
+
+	err = c.kube.Delete(ctx, selector, kube.KindJob, kube.KindPod)
+	if err != nil {
+		return err
+	}
+
+	pods, err := c.kube.GetPods(ctx, selector, "")
+	if err != nil {
+		return err
+	}
+
+	return waitForPodsDeleted(events, pods)
+}
+
+// KubeGetPodForNode returns the session pod that matches the given labels
+// and runs on the given node
+func (s *Session) KubeGetPodForNode(node string, podLabels ...string) (string, error) {
+	selector := strings.Join(append(podLabels, s.getSessionLabel("=")), ",")
+	ctx, cancel := kubeCtx()
+	defer cancel()
+
+	pods, err := s.kube.GetPods(ctx, selector, fmt.Sprintf("spec.nodeName=%s", node))
+	if err != nil {
+		return "", fmt.Errorf("failed to get pods (selector: %s, node: %s): %w", selector, node, err)
+	}
+
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no pods for selector %s on node %s", selector, node)
+	}
+
+	return pods[0].Name, nil
+}
+
+// deletes the monitor (and the session namespace, if any)
+func (s *Session) KubeCleanup() error {
+	selector := s.getSessionLabel("=")
+	kinds := []kube.Kind{kube.KindDaemonSet}
+	if s.namespacePer == NamespacePerSession {
+		// NB: run namespaces also have a session label
+		selector = fmt.Sprintf("%s,!%s", selector, runIdLabel)
+		kinds = append(kinds, kube.KindNamespace)
+	}
+	log.Printf("deleting %v objects of %s", kinds, selector)
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	return s.kube.Delete(ctx, selector, kinds...)
+}
+
+// KubeGetNodes returns the nodes of the cluster
+func (s *Session) KubeGetNodes() ([]kube.Node, error) {
+	ctx, cancel := kubeCtx()
+	defer cancel()
+	return s.kube.GetNodes(ctx)
+}
+
+// KubeGetNodeIP returns the (first) address of a node
+func (s *Session) KubeGetNodeIP(nodeName string) (string, error) {
+	nodes, err := s.KubeGetNodes()
+	if err != nil {
+		return "", err
+	}
+
+	for i := range nodes {
+		if nodes[i].Name != nodeName {
+			continue
+		}
+		if addr := nodes[i].Address(); addr != "" {
+			return addr, nil
+		}
+		return "", fmt.Errorf("missing address for node %s", nodeName)
+	}
+
+	return "", fmt.Errorf("node %s not found", nodeName)
+}
+
+// KubePortForward forwards a local port to the target port of a pod, until
+// ctx is done. It returns the local port.
+func (s *Session) KubePortForward(ctx context.Context, target string, targetPort string) (localPort string, err error) {
+	port, err := strconv.Atoi(targetPort)
+	if err != nil {
+		return "", fmt.Errorf("invalid port %q: %w", targetPort, err)
+	}
+
+	log.Printf("forwarding port %s of pod %s", targetPort, target)
+	lport, err := s.kube.PortForward(ctx, target, port)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(lport), nil
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+func TestKubeFake(t *testing.T) {
+	sessDir, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sessDir)
+
+	fake := kube.NewFakeClient(
+		kube.Node{Name: "node1", Addresses: []string{"192.168.1.1"}},
+		kube.Node{Name: "node2", Addresses: []string{"192.168.1.2"}},
+	)
+	fake.PodHook = func(pod *kube.Pod) string {
+		if pod.Labels["role"] == "cli" {
+			pod.Phase = "Succeeded"
+			return netperfRROutput
+		}
+		return ""
+	}
+
+	sess := &Session{id: "test", dir: sessDir, kube: fake}
+	cliSpec := &ContainerSpec{Affinity: "host=node2"}
+	srvSpec := &ContainerSpec{Affinity: "host=node1"}
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	r := NewRunBenchCtx(sess, "pod2pod", "foo", cliSpec, srvSpec, true, cnf, false)
+	if err := r.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	// monitor
+	if err := sess.StartMonitor(); err != nil {
+		t.Fatal(err)
+	}
+	monitorPod, err := sess.KubeGetPodForNode("node2", monitorSelector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if monitorPod != "knb-monitor-test-node2" {
+		t.Errorf("got monitor pod %s while expected knb-monitor-test-node2", monitorPod)
+	}
+	nodeIP, err := sess.KubeGetNodeIP("node2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodeIP != "192.168.1.2" {
+		t.Errorf("got node IP %s while expected 192.168.1.2", nodeIP)
+	}
+
+	// server
+	st := Pod2PodSt{RunBenchCtx: r}
+	srvYaml, err := st.genSrvYaml()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.KubeApply(srvYaml); err != nil {
+		t.Fatal(err)
+	}
+	srvSelector := r.getRunLabel("=") + ",role=srv"
+	srvPod, err := r.waitForServer(srvSelector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvIP := srvPod.IP
+
+	// client
+	cliYaml, err := r.genCliYaml(r.getDir(), srvIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.KubeApply(cliYaml); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.waitForClient(); err != nil {
+		t.Fatal(err)
+	}
+	r.saveCliLogsAndResult(r.getCliSelector(), r.getDir())
+	res, err := LoadBenchResult(r.getDir() + "/result.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.GetMetric(MetricThroughput); !ok {
+		t.Errorf("missing throughput metric in result: %+v", res)
+	}
+
+	pods, err := r.KubeGetPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("got %d pods while expected 2", len(pods))
+	}
+	if pods[0].Name != r.objName("cli") || pods[0].Node != "node2" || pods[1].Name != r.objName("srv") || pods[1].Node != "node1" {
+		t.Errorf("unexpected pods: %+v", pods)
+	}
+
+	if err := r.KubeDeleteClient(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.KubeGetPodName(r.getCliSelector()); err == nil {
+		t.Errorf("client pod was not deleted")
+	}
+
+	// cleanup deletes the server, but not the monitor
+	if err := r.KubeCleanup(); err != nil {
+		t.Fatal(err)
+	}
+	pods, err = r.KubeGetPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 0 {
+		t.Errorf("got %d pods after cleanup while expected 0", len(pods))
+	}
+	if _, err := sess.KubeGetPodForNode("node1", monitorSelector); err != nil {
+		t.Errorf("monitor was deleted by run cleanup: %s", err)
+	}
+
+	if err := sess.StopMonitor(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.KubeGetPodForNode("node1", monitorSelector); err == nil {
+		t.Errorf("monitor was not deleted")
+	}
+}
@@ -3,4 +3,8 @@ package core
 var (
 	runIdLabel  = "knb-runid"
 	sessIdLabel = "knb-sessid"
+	// index of a client pod (when using multiple client pods)
+	clientIdxLabel = "knb-client"
+	// index of a client/server pair (see PairsSt)
+	pairIdxLabel = "knb-pair"
 )
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+// Version is the kubenetbench version (set at build time, see Makefile)
+var Version = "dev"
+
+// run outcomes
+const (
+	OutcomeRunning      = "running"
+	OutcomeSuccess      = "success"
+	OutcomeClientFailed = "client-failed"
+	OutcomeTimeout      = "timeout"
+	OutcomeInterrupted  = "interrupted"
+	OutcomeError        = "error"
+)
+
+var (
+	// ErrClientFailed is returned when the benchmark client fails
+	ErrClientFailed = errors.New("client execution failed")
+	// ErrClientTimeout is returned when the benchmark client did not finish in time
+	ErrClientTimeout = errors.New("timed out waiting for client")
+	// ErrInterrupted is returned when the run is interrupted (e.g., SIGINT)
+	ErrInterrupted = errors.New("run interrupted")
+)
+
+// BenchmarkManifest describes the benchmark of a run
+type BenchmarkManifest struct {
+	Name   string          `json:"name"`
+	Test   string          `json:"test"`
+	Config json.RawMessage `json:"config"`
+}
+
+// RunManifest describes a benchmark run. It is stored as run.json in the run
+// directory.
+type RunManifest struct {
+	Version     string            `json:"version"`
+	CommandLine []string          `json:"command_line"`
+	Host        string            `json:"host,omitempty"` // host executing the run
+	PID         int               `json:"pid,omitempty"`  // process executing the run
+	Session     string            `json:"session"`
+	RunID       string            `json:"runid"`
+	Label       string            `json:"label"`
+	Scenario    string            `json:"scenario"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Client      ContainerSpec     `json:"client"`
+	Server      ContainerSpec     `json:"server"`
+	Cleanup     bool              `json:"cleanup"`
+	Benchmark   BenchmarkManifest `json:"benchmark"`
+	CollectPerf bool              `json:"collect_perf"`
+	Collectors  []CollectorSpec   `json:"collectors,omitempty"`
+	Conntrack   bool              `json:"conntrack_snapshots,omitempty"`
+	NodeMetrics string            `json:"node_metrics_interval,omitempty"`
+	Repeat      int               `json:"repeat,omitempty"`
+	Warmup      int               `json:"warmup,omitempty"`
+	Clients     int               `json:"clients,omitempty"`
+	ClientJob   bool              `json:"client_job,omitempty"`
+	Service     *ServiceManifest  `json:"service,omitempty"`
+	Policy      *PolicyManifest   `json:"policy,omitempty"`
+	StartTime   time.Time         `json:"start_time"`
+	EndTime     *time.Time        `json:"end_time,omitempty"`
+	Outcome     string            `json:"outcome"`
+	Error       string            `json:"error,omitempty"`
+
+	// actual start times of the clients that use a start barrier
+	ClientStarts []ClientStart `json:"client_starts,omitempty"`
+}
+
+// LoadRunManifest loads a manifest from a (run.json) file
+func LoadRunManifest(fname string) (*RunManifest, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &RunManifest{}
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", fname, err)
+	}
+	return m, nil
+}
+
+// outcomeFromError returns the run outcome based on the error returned by the execution
+func outcomeFromError(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, ErrClientFailed):
+		return OutcomeClientFailed
+	case errors.Is(err, ErrClientTimeout):
+		return OutcomeTimeout
+	case errors.Is(err, ErrInterrupted):
+		return OutcomeInterrupted
+	default:
+		return OutcomeError
+	}
+}
+
+func (r *RunBenchCtx) getManifestFname() string {
+	return fmt.Sprintf("%s/run.json", r.getDir())
+}
+
+func (r *RunBenchCtx) newManifest() (*RunManifest, error) {
+	benchConf, err := json.Marshal(r.benchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeMetrics := ""
+	if r.nodeMetrics > 0 {
+		nodeMetrics = r.nodeMetrics.String()
+	}
+
+	host, _ := os.Hostname()
+	return &RunManifest{
+		Version:     Version,
+		CommandLine: os.Args,
+		Host:        host,
+		PID:         os.Getpid(),
+		Session:     r.session.id,
+		RunID:       r.runid,
+		Label:       r.label,
+		Scenario:    r.scenario,
+		Namespace:   r.kube.Namespace(),
+		Client:      *r.cliSpec,
+		Server:      *r.srvSpec,
+		Cleanup:     r.cleanup,
+		Benchmark: BenchmarkManifest{
+			Name:   r.benchmark.GetName(),
+			Test:   r.benchmark.GetTestName(),
+			Config: benchConf,
+		},
+		CollectPerf: r.collectPerf,
+		Collectors:  r.collectors,
+		Conntrack:   r.conntrack,
+		NodeMetrics: nodeMetrics,
+		Repeat:      r.repeat,
+		Warmup:      r.warmup,
+		Clients:     r.clients,
+		ClientJob:   r.clientJob,
+		StartTime:   r.startTime,
+		Outcome:     OutcomeRunning,
+	}, nil
+}
+
+// writeManifest writes the run manifest
+func (r *RunBenchCtx) writeManifest() error {
+	fname := r.getManifestFname()
+	log.Printf("Writing %s", fname)
+	return writeJSONFile(fname, r.manifest)
+}
+
+// finishManifest updates the run manifest with the end time and the outcome
+// of the run.
+func (r *RunBenchCtx) finishManifest(runErr error) {
+	if r.manifest == nil {
+		return
+	}
+
+	now := time.Now()
+	r.manifest.EndTime = &now
+	r.manifest.Outcome = outcomeFromError(runErr)
+	if runErr != nil {
+		r.manifest.Error = runErr.Error()
+	}
+
+	err := r.writeManifest()
+	if err != nil {
+		log.Printf("failed to write run manifest: %s", err)
+	}
+}
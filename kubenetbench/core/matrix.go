@@ -0,0 +1,249 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"sigs.k8s.io/yaml"
+)
+
+// MatrixScenarioParam is the parameter that selects the scenario of a
+// combination (e.g., pod2pod or service). All other parameters are flags of
+// the scenario command (e.g., netperf-type, client-affinity, etc.).
+const MatrixScenarioParam = "scenario"
+
+// MatrixPlan describes a benchmark matrix: a set of axes whose cartesian
+// product (plus the combinations in Include, minus the ones matching
+// Exclude) defines the runs to be executed. Plans can be written in YAML or
+// JSON.
+type MatrixPlan struct {
+	// Name of the plan, used as a prefix for the run labels
+	Name string `json:"name"`
+	// Scenario of the runs (if it is not defined as an axis)
+	Scenario string `json:"scenario"`
+	// Params are fixed parameters applied to all combinations
+	Params map[string]interface{} `json:"params"`
+	// Axes are the parameters that vary, and their values
+	Axes map[string][]interface{} `json:"axes"`
+	// Include are additional combinations (Params are applied to them as well)
+	Include []map[string]interface{} `json:"include"`
+	// Exclude are rules for skipping combinations: a combination is skipped
+	// if it matches all the parameters of a rule.
+	Exclude []map[string]interface{} `json:"exclude"`
+}
+
+// MatrixCombination is a single combination of a matrix plan
+type MatrixCombination struct {
+	Label  string                 `json:"label"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// LoadMatrixPlan loads a plan from a YAML or JSON file
+func LoadMatrixPlan(fname string) (*MatrixPlan, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MatrixPlan{}
+	err = yaml.Unmarshal(data, plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", fname, err)
+	}
+
+	if plan.Name == "" {
+		return nil, fmt.Errorf("plan %s: name is required", fname)
+	}
+
+	return plan, nil
+}
+
+func (p *MatrixPlan) newCombination(params ...map[string]interface{}) (*MatrixCombination, error) {
+	c := &MatrixCombination{Params: make(map[string]interface{})}
+	if p.Scenario != "" {
+		c.Params[MatrixScenarioParam] = p.Scenario
+	}
+	for _, ps := range params {
+		for k, v := range ps {
+			c.Params[k] = v
+		}
+	}
+	h, err := c.hash()
+	if err != nil {
+		return nil, err
+	}
+	c.Label = fmt.Sprintf("%s-%s", p.Name, h)
+	return c, nil
+}
+
+// hash returns a short hash of the combination parameters, so that the same
+// combination always maps to the same label.
+func (c *MatrixCombination) hash() (string, error) {
+	// NB: json.Marshal sorts map keys
+	data, err := json.Marshal(c.Params)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal params: %w", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:8], nil
+}
+
+// Scenario returns the scenario of the combination
+func (c *MatrixCombination) Scenario() string {
+	s, _ := c.Params[MatrixScenarioParam].(string)
+	return s
+}
+
+func (c *MatrixCombination) matches(rule map[string]interface{}) bool {
+	for k, v := range rule {
+		cv, ok := c.Params[k]
+		if !ok || !reflect.DeepEqual(cv, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expand returns the combinations of the plan
+func (p *MatrixPlan) Expand() ([]*MatrixCombination, error) {
+	axes := make([]string, 0, len(p.Axes))
+	for axis, vals := range p.Axes {
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("axis %s has no values", axis)
+		}
+		axes = append(axes, axis)
+	}
+	sort.Strings(axes)
+
+	var ret []*MatrixCombination
+	seen := make(map[string]struct{})
+	add := func(c *MatrixCombination) {
+		if _, ok := seen[c.Label]; ok {
+			return
+		}
+		seen[c.Label] = struct{}{}
+		ret = append(ret, c)
+	}
+
+	// cartesian product of the axes (the last axis varies the fastest).
+	// If there are no axes, the product is a single combination with the
+	// fixed parameters, unless there are explicitly included combinations.
+	idxs := make([]int, len(axes))
+	for len(axes) > 0 || len(p.Include) == 0 {
+		vals := make(map[string]interface{}, len(axes))
+		for i, axis := range axes {
+			vals[axis] = p.Axes[axis][idxs[i]]
+		}
+		c, err := p.newCombination(p.Params, vals)
+		if err != nil {
+			return nil, err
+		}
+
+		excluded := false
+		for _, rule := range p.Exclude {
+			if c.matches(rule) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			add(c)
+		}
+
+		i := len(axes) - 1
+		for ; i >= 0; i-- {
+			idxs[i]++
+			if idxs[i] < len(p.Axes[axes[i]]) {
+				break
+			}
+			idxs[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+
+	for _, inc := range p.Include {
+		c, err := p.newCombination(p.Params, inc)
+		if err != nil {
+			return nil, err
+		}
+		add(c)
+	}
+
+	for _, c := range ret {
+		if c.Scenario() == "" {
+			return nil, fmt.Errorf("combination %s (%v) has no scenario", c.Label, c.Params)
+		}
+	}
+
+	return ret, nil
+}
+
+// MatrixParamValues converts a parameter value to a list of (flag) values.
+// Lists are used for parameters that can be specified multiple times (e.g.,
+// netperf-bench-args).
+func MatrixParamValues(v interface{}) ([]string, error) {
+	switch x := v.(type) {
+	case string:
+		return []string{x}, nil
+	case bool:
+		return []string{strconv.FormatBool(x)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(x, 'f', -1, 64)}, nil
+	case []interface{}:
+		ret := make([]string, 0, len(x))
+		for _, xv := range x {
+			vals, err := MatrixParamValues(xv)
+			if err != nil {
+				return nil, err
+			}
+			if len(vals) != 1 {
+				return nil, fmt.Errorf("nested lists are not supported: %v", v)
+			}
+			ret = append(ret, vals[0])
+		}
+		return ret, nil
+	default:
+		return nil, fmt.Errorf("unsupported value: %v (%T)", v, v)
+	}
+}
+
+// HasSuccessfulRun checks whether there is a successful run with the given label
+func HasSuccessfulRun(runs []*RunInfo, label string) bool {
+	for _, ri := range runs {
+		if ri.Label == label && ri.Outcome == OutcomeSuccess {
+			return true
+		}
+	}
+	return false
+}
+
+// matrix combination states
+const (
+	MatrixPending = "pending"
+	MatrixDone    = "done"    // a successful run existed before execution
+	MatrixSuccess = "success" // combination was executed successfully
+	MatrixFailed  = "failed"
+)
+
+// MatrixStatus is the execution status of a matrix combination
+type MatrixStatus struct {
+	MatrixCombination
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MatrixStatusFname returns the file where the status of a plan is stored
+func MatrixStatusFname(sessDir string, plan *MatrixPlan) string {
+	return fmt.Sprintf("%s/matrix-%s.json", sessDir, plan.Name)
+}
+
+// SaveMatrixStatus writes the status of the matrix combinations
+func SaveMatrixStatus(fname string, status []*MatrixStatus) error {
+	return writeJSONFile(fname, status)
+}
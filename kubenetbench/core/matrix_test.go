@@ -0,0 +1,75 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var testPlan = `
+name: sweep
+scenario: pod2pod
+params:
+  duration: 10
+axes:
+  netperf-type: [tcp_rr, tcp_stream]
+  cli-on-host: [false, true]
+exclude:
+  - {netperf-type: tcp_stream, cli-on-host: true}
+include:
+  - {scenario: service, netperf-type: udp_rr}
+`
+
+func TestMatrixExpand(t *testing.T) {
+	f, err := ioutil.TempFile("", "knb-plan-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(testPlan)
+	f.Close()
+
+	plan, err := LoadMatrixPlan(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	combs, err := plan.Expand()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]interface{}{
+		{"scenario": "pod2pod", "duration": 10.0, "cli-on-host": false, "netperf-type": "tcp_rr"},
+		{"scenario": "pod2pod", "duration": 10.0, "cli-on-host": false, "netperf-type": "tcp_stream"},
+		{"scenario": "pod2pod", "duration": 10.0, "cli-on-host": true, "netperf-type": "tcp_rr"},
+		{"scenario": "service", "duration": 10.0, "netperf-type": "udp_rr"},
+	}
+	if len(combs) != len(expected) {
+		t.Fatalf("got %d combinations while expected %d", len(combs), len(expected))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(combs[i].Params, expected[i]) {
+			t.Errorf("combination %d: got %v while expected %v", i, combs[i].Params, expected[i])
+		}
+	}
+
+	// labels are stable
+	again, _ := plan.Expand()
+	if combs[0].Label != again[0].Label || combs[0].Label == combs[1].Label {
+		t.Errorf("unexpected labels: %s %s %s", combs[0].Label, again[0].Label, combs[1].Label)
+	}
+}
+
+func TestMatrixParamValues(t *testing.T) {
+	vals, err := MatrixParamValues([]interface{}{"-r", "1,1"})
+	if err != nil || !reflect.DeepEqual(vals, []string{"-r", "1,1"}) {
+		t.Errorf("unexpected result: %v (%v)", vals, err)
+	}
+
+	vals, err = MatrixParamValues(30.0)
+	if err != nil || !reflect.DeepEqual(vals, []string{"30"}) {
+		t.Errorf("unexpected result: %v (%v)", vals, err)
+	}
+}
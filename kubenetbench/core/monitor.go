@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"text/tabwriter"
 	"text/template"
 	"time"
 
@@ -24,7 +25,7 @@ const (
 var monitorTemplate = template.Must(template.New("monitor").Parse(`apiVersion: apps/v1
 kind: DaemonSet
 metadata:
-  name: knb-monitor
+  name: {{.monitorName}}
   labels:
     {{.sessLabel}}
     role: monitor
@@ -81,7 +82,8 @@ func (s *Session) genMonitorYaml() (string, error) {
 	}
 
 	vals := map[string]interface{}{
-		"sessLabel": s.getSessionLabel(": "),
+		"monitorName": dnsLabel("knb-monitor-"+s.id, dnsLabelMaxLen),
+		"sessLabel":   s.getSessionLabel(": "),
 	}
 	err = monitorTemplate.Execute(f, vals)
 	if err != nil {
@@ -91,40 +93,11 @@ func (s *Session) genMonitorYaml() (string, error) {
 	return yaml, nil
 }
 
-type FileReceiver interface {
-	Recv() (*pb.File, error)
-}
-
-func copyStreamToFile(fname string, stream FileReceiver) error {
-
-	f, err := os.OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	for {
-		data, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("io error: %w", err)
-		}
-
-		_, err = f.Write(data.Data)
-		if err != nil {
-			return fmt.Errorf("Error writing data: %w", err)
-		}
-	}
-
-	return nil
-}
-
 func (s *Session) srvAddrForNode(ctx context.Context, nodeName string) (string, error) {
 	var host, port string
 	if !s.portForward {
 		// directly connect to node IP if port-forwarding is disabled
-		nodeIP, err := KubeGetNodeIP(nodeName)
+		nodeIP, err := s.KubeGetNodeIP(nodeName)
 		if err != nil {
 			return "", err
 		}
@@ -136,7 +109,7 @@ func (s *Session) srvAddrForNode(ctx context.Context, nodeName string) (string,
 			return "", err
 		}
 
-		port, err = KubePortForward(ctx, monitorPod, monitorPort)
+		port, err = s.KubePortForward(ctx, monitorPod, monitorPort)
 		if err != nil {
 			return "", err
 		}
@@ -178,25 +151,21 @@ func (s *Session) GetSysInfoNode(node_name, node_ip string) error {
 	}
 
 	fname := fmt.Sprintf("%s/%s.sysinfo", s.dir, node_name)
-	return copyStreamToFile(fname, stream)
+	return copyStreamToFile(fname, 0, stream)
 }
 
 func (s *Session) GetSysInfoNodes() error {
 
-	lines, err := KubeGetNodesAndIps()
+	nodes, err := s.KubeGetNodes()
 	if err != nil {
 		return err
 	}
 
 	errstr := ""
 	retriesOrig := 10
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) != 2 {
-			log.Fatal("filed to parse  line %s", line)
-		}
-		node_name := fields[0]
-		node_ip := fields[1]
+	for _, node := range nodes {
+		node_name := node.Name
+		node_ip := node.Address()
 		retries := retriesOrig
 		for {
 			log.Printf("calling GetSysInfoNode on %s/%s (remaining retries: %d)", node_name, node_ip, retries)
@@ -206,7 +175,7 @@ func (s *Session) GetSysInfoNodes() error {
 			}
 
 			if retries == 0 {
-				err := fmt.Sprintf("Error calling GetSysInfoNode %s after %d retries (last error:%w)", node_name, retriesOrig, err)
+				err := fmt.Sprintf("Error calling GetSysInfoNode %s after %d retries (last error:%s)", node_name, retriesOrig, err)
 				errstr = errstr + "\n" + err
 				break
 			}
@@ -223,55 +192,114 @@ func (s *Session) GetSysInfoNodes() error {
 	}
 }
 
-func (r *RunBenchCtx) endCollection() error {
-	ctx, cancel := context.WithCancel(context.Background())
+// time to wait for the collectors of a node to stop, and for its results to
+// be available
+var collectionStopTimeout = 2 * time.Minute
+
+// endCollectionNode stops (or cancels) the collection on a node, and writes
+// its results (unless it was cancelled) in dir
+func (r *RunBenchCtx) endCollectionNode(node string, id string, dir string, cancelled bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), collectionStopTimeout)
 	defer cancel()
 
-	var err error = nil
+	conn, err := r.session.DialMonitor(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cli := pb.NewKubebenchMonitorClient(conn)
 
-	for _, node := range r.collectNodes {
-		conn, err := r.session.DialMonitor(ctx, node)
+	ref := &pb.CollectionRef{CollectionId: id}
+	if cancelled {
+		_, err = cli.CancelCollection(ctx, ref)
 		if err != nil {
-			return err
-		}
-		defer conn.Close()
-		cli := pb.NewKubebenchMonitorClient(conn)
-		conf := &pb.CollectionResultsConf{
-			CollectionId: r.runid,
+			return fmt.Errorf("failed to cancel collection: %w", err)
 		}
+		log.Printf("cancelled collection on monitor %s\n", node)
+		return nil
+	}
 
-		stream, err := cli.GetCollectionResults(ctx, conf)
-		if err != nil {
-			log.Printf("collection on monitor %s failed: %s\n", node, err)
-		}
+	st, err := cli.StopCollection(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("failed to stop collection: %w", err)
+	}
+	log.Printf("stopped collection on monitor %s (state: %s, duration: %s)\n",
+		node, st.State, time.Duration(st.EndTime-st.StartTime))
 
-		fname := fmt.Sprintf("%s/perf-%s.tar.bz2", r.getDir(), node)
-		err = copyStreamToFile(fname, stream)
-		if err != nil {
-			log.Printf("writing collection data from node %s failed: %s\n", node, err)
-		} else {
-			log.Printf("perf data for %s can be found in: %s\n", node, fname)
-		}
+	fname := fmt.Sprintf("%s/collection-%s.tar.gz", dir, node)
+	err = receiveFile(fname, func(offset int64) error {
+		return r.session.getCollectionResultsNode(node, id, fname, offset)
+	})
+	if err != nil {
+		return fmt.Errorf("writing collection data failed: %w", err)
 	}
+	log.Printf("collection data for %s can be found in: %s\n", node, fname)
+	logCollectionManifest(fname)
+	return nil
+}
 
+// getCollectionResultsNode retrieves the results of a collection on a node,
+// starting at offset. It uses a new connection (and port-forward), so that it
+// can be retried if the previous one broke.
+func (s *Session) getCollectionResultsNode(node string, id string, fname string, offset int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), collectionStopTimeout)
+	defer cancel()
+
+	conn, err := s.DialMonitor(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cli := pb.NewKubebenchMonitorClient(conn)
+
+	conf := &pb.CollectionResultsConf{
+		CollectionId: id,
+		Offset:       offset,
+	}
+	stream, err := cli.GetCollectionResults(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("collection failed: %w", err)
+	}
+	return copyStreamToFile(fname, offset, stream)
+}
+
+// endCollection stops the collections of the run, and stores their results in
+// dir. If cancelled is true (e.g., the run was interrupted), the collections
+// are cancelled instead, and their results are discarded.
+func (r *RunBenchCtx) endCollection(id string, dir string, cancelled bool) error {
+	var err error
+	for _, node := range r.collectNodes {
+		if nerr := r.endCollectionNode(node, id, dir, cancelled); nerr != nil {
+			log.Printf("collection on monitor %s: %s\n", node, nerr)
+			err = nerr
+		}
+	}
+	r.collectNodes = nil
 	return err
 }
 
-func (r *RunBenchCtx) startCollection() error {
+// collectionMaxDuration returns the maximum duration of collections. Normally,
+// collections are stopped when the clients finish (see endCollection), so this
+// only matters if kubenetbench does not stop them (e.g., it crashes).
+func (r *RunBenchCtx) collectionMaxDuration() time.Duration {
+	return podStartTimeout + time.Duration(r.benchmark.GetTimeout())*time.Second + clientExitSlack + collectionStopTimeout
+}
+
+func (r *RunBenchCtx) startCollection(id string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	labels := [...]string{PodName, PodNodeName, PodPhase}
-	podsinfo, err := r.KubeGetPods__(labels[:])
+	pods, err := r.KubeGetPods()
 	if err != nil {
 		return err
 	}
 
+	r.collectNodes = nil
 	nodes := make(map[string]struct{})
 	log.Printf("Pods: \n")
-	for _, a := range podsinfo {
-		log.Printf(" %v\n", a)
-		nodes[a[1]] = struct{}{}
+	for _, pod := range pods {
+		log.Printf(" %s %s %s\n", pod.Name, pod.Node, pod.Phase)
+		nodes[pod.Node] = struct{}{}
 	}
 
 	for node, _ := range nodes {
@@ -283,8 +311,9 @@ func (r *RunBenchCtx) startCollection() error {
 		//log.Printf("connected to monitor on %s\n", node)
 		cli := pb.NewKubebenchMonitorClient(conn)
 		conf := &pb.CollectionConf{
-			Duration:     "5",
-			CollectionId: r.runid,
+			Duration:     r.collectionMaxDuration().String(),
+			CollectionId: id,
+			Collectors:   r.collectorConfs(),
 		}
 
 		_, err = cli.StartCollection(context.Background(), conf)
@@ -298,3 +327,83 @@ func (r *RunBenchCtx) startCollection() error {
 
 	return nil
 }
+
+// NodeCollection is a collection of the monitor of a node
+type NodeCollection struct {
+	Node   string
+	Status *pb.CollectionStatus
+}
+
+// ListCollections returns the collections of the monitors of all nodes
+func (s *Session) ListCollections() ([]NodeCollection, error) {
+	nodes, err := s.KubeGetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []NodeCollection
+	for _, node := range nodes {
+		err := func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), collectionStopTimeout)
+			defer cancel()
+
+			conn, err := s.DialMonitor(ctx, node.Name)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			list, err := pb.NewKubebenchMonitorClient(conn).ListCollections(ctx, &pb.Empty{})
+			if err != nil {
+				return fmt.Errorf("failed to list collections of monitor on %q: %w", node.Name, err)
+			}
+			for _, st := range list.Collections {
+				ret = append(ret, NodeCollection{Node: node.Name, Status: st})
+			}
+			return nil
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// CancelCollection cancels a collection on the monitor of a node
+func (s *Session) CancelCollection(nodeName string, id string) (*pb.CollectionStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectionStopTimeout)
+	defer cancel()
+
+	conn, err := s.DialMonitor(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	st, err := pb.NewKubebenchMonitorClient(conn).CancelCollection(ctx, &pb.CollectionRef{CollectionId: id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel collection %s on %q: %w", id, nodeName, err)
+	}
+	return st, nil
+}
+
+// WriteCollections writes a table with the given collections
+func WriteCollections(w io.Writer, collections []NodeCollection) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tID\tSTATE\tCOLLECTORS\tSTARTED\tERROR")
+	for _, c := range collections {
+		st := c.Status
+		started := "-"
+		if st.StartTime != 0 {
+			started = time.Unix(0, st.StartTime).Format(time.RFC3339)
+		}
+		errstr := st.Error
+		if errstr == "" {
+			errstr = "-"
+		}
+		state := strings.ToLower(strings.TrimPrefix(st.State.String(), "COLLECTION_"))
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.Node, st.CollectionId, state, strings.Join(st.Collectors, ","), started, errstr)
+	}
+	return tw.Flush()
+}
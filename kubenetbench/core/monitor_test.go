@@ -0,0 +1,46 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+func TestWriteCollections(t *testing.T) {
+	start := time.Date(2020, 8, 26, 17, 4, 43, 0, time.UTC)
+	collections := []NodeCollection{
+		{Node: "k8s1", Status: &pb.CollectionStatus{
+			CollectionId: "pod2pod-1",
+			State:        pb.CollectionState_COLLECTION_RUNNING,
+			Collectors:   []string{"perf-record", "softirqs"},
+			StartTime:    start.UnixNano(),
+		}},
+		{Node: "k8s2", Status: &pb.CollectionStatus{
+			CollectionId: "pod2pod-1",
+			State:        pb.CollectionState_COLLECTION_FAILED,
+			Error:        "collection cancelled",
+		}},
+	}
+
+	var buff bytes.Buffer
+	if err := WriteCollections(&buff, collections); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected output:\n%s", buff.String())
+	}
+	for i, fields := range [][]string{
+		{"k8s1", "pod2pod-1", "running", "perf-record,softirqs", start.Local().Format(time.RFC3339), "-"},
+		{"k8s2", "pod2pod-1", "failed", "", "-", "collection cancelled"},
+	} {
+		for _, f := range fields {
+			if !strings.Contains(lines[i+1], f) {
+				t.Errorf("line %q does not contain %q", lines[i+1], f)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+)
+
+// Namespace isolation modes. By default, all objects are created in the
+// namespace of the kubernetes client (see --namespace). Alternatively, a
+// dedicated namespace can be created for every session or for every run.
+const (
+	NamespacePerNone    = ""
+	NamespacePerSession = "session"
+	NamespacePerRun     = "run"
+)
+
+// ValidateNamespacePer checks a namespace isolation mode
+func ValidateNamespacePer(per string) error {
+	switch per {
+	case NamespacePerNone, NamespacePerSession, NamespacePerRun:
+		return nil
+	default:
+		return fmt.Errorf("invalid namespace isolation mode %q (valid values: %q, %q)", per, NamespacePerSession, NamespacePerRun)
+	}
+}
+
+// maximum length of DNS labels (RFC 1123), e.g., namespace and service names
+const dnsLabelMaxLen = 63
+
+// dnsLabel converts s to a valid DNS label of at most maxLen characters, by
+// replacing invalid characters with '-'
+func dnsLabel(s string, maxLen int) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	ret := b.String()
+	if len(ret) > maxLen {
+		ret = ret[:maxLen]
+	}
+	return strings.Trim(ret, "-")
+}
+
+const uidLen = 5
+
+// newUID returns a random identifier, used to make the names of objects
+// unique across runs (potentially from different hosts)
+func newUID() string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, uidLen)
+	for i := range b {
+		b[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(b)
+}
+
+// sessionConf is the session configuration that needs to be retained across
+// invocations. It is stored as session.json in the session directory.
+type sessionConf struct {
+	Namespace    string `json:"namespace"`
+	NamespacePer string `json:"namespace_per,omitempty"`
+}
+
+func (s *Session) getConfFname() string {
+	return fmt.Sprintf("%s/session.json", s.dir)
+}
+
+func (s *Session) writeConf() error {
+	return writeJSONFile(s.getConfFname(), &sessionConf{
+		Namespace:    s.kube.Namespace(),
+		NamespacePer: s.namespacePer,
+	})
+}
+
+// loadConf loads the session configuration, if it exists. Sessions created
+// by older versions do not have a configuration. If namespace is not empty, it
+// is the namespace requested by the user, and it needs to match the namespace
+// of the session.
+func (s *Session) loadConf(namespace string) error {
+	data, err := ioutil.ReadFile(s.getConfFname())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	conf := &sessionConf{}
+	err = json.Unmarshal(data, conf)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.getConfFname(), err)
+	}
+
+	if namespace != "" && namespace != conf.Namespace {
+		return fmt.Errorf("namespace %q does not match the namespace of session %s (%q)", namespace, s.id, conf.Namespace)
+	}
+	s.kube = s.kube.WithNamespace(conf.Namespace)
+	s.namespacePer = conf.NamespacePer
+	return nil
+}
+
+// sessionNamespace returns the name of the namespace of a session
+func sessionNamespace(sessId string) string {
+	return dnsLabel("knb-"+sessId, dnsLabelMaxLen)
+}
+
+// setupNamespace creates the session namespace (if needed), and writes the
+// session configuration
+func (s *Session) setupNamespace() error {
+	if s.namespacePer == NamespacePerSession {
+		ns := sessionNamespace(s.id)
+		err := s.KubeCreateNamespace(ns, map[string]string{sessIdLabel: s.id})
+		if err != nil {
+			return err
+		}
+		s.kube = s.kube.WithNamespace(ns)
+	}
+	return s.writeConf()
+}
+
+// runNamespace returns the name of the namespace of a run
+func (r *RunBenchCtx) runNamespace() string {
+	prefix := dnsLabel("knb-"+r.runid, dnsLabelMaxLen-uidLen-1)
+	return fmt.Sprintf("%s-%s", prefix, r.uid)
+}
+
+// objName returns the name of an object of the run. Names include the run
+// uid, so that concurrent runs do not clash.
+func (r *RunBenchCtx) objName(name string) string {
+	return fmt.Sprintf("knb-%s-%s", name, r.uid)
+}
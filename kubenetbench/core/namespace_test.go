@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+func TestDNSLabel(t *testing.T) {
+	tests := []struct {
+		s        string
+		maxLen   int
+		expected string
+	}{
+		{"knb-foo-20200826165847", 63, "knb-foo-20200826165847"},
+		{"knb-Foo_Bar.1", 63, "knb-foo-bar-1"},
+		{"knb-foo-bar", 8, "knb-foo"},
+	}
+
+	for _, tt := range tests {
+		if got := dnsLabel(tt.s, tt.maxLen); got != tt.expected {
+			t.Errorf("dnsLabel(%q, %d): got %q while expected %q", tt.s, tt.maxLen, got, tt.expected)
+		}
+	}
+}
+
+func fakeNamespaces(t *testing.T, fake *kube.FakeClient) []string {
+	objs, err := fake.List(context.Background(), "", kube.KindNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ret []string
+	for _, obj := range objs {
+		ret = append(ret, obj.Name)
+	}
+	return ret
+}
+
+func TestNamespacePerRun(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	base, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	fake := kube.NewFakeClient(kube.Node{Name: "node1", Addresses: []string{"192.168.1.1"}})
+	fake.PodHook = func(pod *kube.Pod) string {
+		if pod.Labels["role"] == "cli" {
+			pod.Phase = "Succeeded"
+			return netperfRROutput
+		}
+		return ""
+	}
+
+	sess, err := InitSession("test", base, false, fake, NamespacePerRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	cnf.Timeout = 0
+	r := NewRunBenchCtx(sess, "pod2pod", "foo", &ContainerSpec{Affinity: "different"}, &ContainerSpec{Affinity: "none"}, true, cnf, false)
+	if err := r.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (Pod2PodSt{RunBenchCtx: r}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	ns := r.runNamespace()
+	m, err := LoadRunManifest(r.getManifestFname())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Namespace != ns {
+		t.Errorf("got manifest namespace %q while expected %q", m.Namespace, ns)
+	}
+
+	created, deleted := false, false
+	for _, o := range fake.Applied {
+		created = created || o == "namespace/"+ns
+	}
+	for _, o := range fake.Deleted {
+		deleted = deleted || o == "namespace/"+ns
+	}
+	if !created || !deleted {
+		t.Errorf("namespace %s: created: %t, deleted: %t", ns, created, deleted)
+	}
+	if nss := fakeNamespaces(t, fake); len(nss) != 0 {
+		t.Errorf("got namespaces %v after run", nss)
+	}
+}
+
+func TestNamespacePerSession(t *testing.T) {
+	base, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	fake := kube.NewFakeClient(kube.Node{Name: "node1", Addresses: []string{"192.168.1.1"}})
+	sess, err := InitSession("Test_1", base, false, fake, NamespacePerSession)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns := sess.kube.Namespace(); ns != "knb-test-1" {
+		t.Errorf("got session namespace %q while expected knb-test-1", ns)
+	}
+
+	// a different namespace cannot be requested for the session
+	if _, err := NewSession("Test_1", base, false, fake, "default", NamespacePerNone); err == nil {
+		t.Error("unexpected success of session with a different namespace")
+	}
+
+	// the namespace configuration is retained by the session
+	sess, err = NewSession("Test_1", base, false, fake, "knb-test-1", NamespacePerNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns := sess.kube.Namespace(); ns != "knb-test-1" {
+		t.Errorf("got session namespace %q while expected knb-test-1", ns)
+	}
+
+	if err := sess.StartMonitor(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.KubeGetPodForNode("node1", monitorSelector); err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.StopMonitor(); err != nil {
+		t.Fatal(err)
+	}
+	if nss := fakeNamespaces(t, fake); len(nss) != 0 {
+		t.Errorf("got namespaces %v after session was done", nss)
+	}
+}
@@ -7,15 +7,18 @@ import (
 	"github.com/cilium/kubenetbench/utils"
 )
 
+// port of the netperf control connection
+const netperfCtlPort = 12865
+
 // NetperfConf base netperf configuration
 type NetperfConf struct {
-	Timeout       int
-	DataPort      uint16
-	TestName      string
-	CliCommand    string
-	PreArgs       []string
-	MoreArgs      []string
-	MoreBenchArgs []string
+	Timeout       int      `json:"timeout"`
+	DataPort      uint16   `json:"data_port"`
+	TestName      string   `json:"test_name"`
+	CliCommand    string   `json:"cli_command"`
+	PreArgs       []string `json:"pre_args"`
+	MoreArgs      []string `json:"more_args"`
+	MoreBenchArgs []string `json:"more_bench_args"`
 }
 
 // NetperfConfDefault returns a NetperfConf with the default values
@@ -35,8 +38,18 @@ func (cnf *NetperfConf) GetTimeout() int {
 	return cnf.Timeout
 }
 
+// GetName returns the benchmark name
+func (cnf *NetperfConf) GetName() string {
+	return "netperf"
+}
+
+// GetTestName returns the netperf test name
+func (cnf *NetperfConf) GetTestName() string {
+	return cnf.TestName
+}
+
 // WriteSrvContainerYaml writes the server yaml
-func (cnf *NetperfConf) WriteSrvContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) {
+func (cnf *NetperfConf) WriteSrvContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
 	pw.AppendNewLineOrDie(`name: netperf-srv`)
 	pw.AppendNewLineOrDie(`image: cilium/kubenetbench`)
 	pw.AppendNewLineOrDie(`command: ["netserver"]`)
@@ -45,18 +58,63 @@ func (cnf *NetperfConf) WriteSrvContainerYaml(pw *utils.PrefixWriter, params map
 	pw.AppendNewLineOrDie(`"-D", # dont daemonize`)
 	pw.PopPrefix()
 	pw.AppendNewLineOrDie(`]`)
+	return nil
 }
 
-// WriteSrvPortsYaml writes the ports part of yaml (e.g., for services)
-func (cnf *NetperfConf) WriteSrvPortsYaml(pw *utils.PrefixWriter, params map[string]interface{}) {
+// dataPort returns the data port of a client. Concurrent clients of the same
+// server need different data ports, so the client index (if any) is added to
+// the data port.
+func (cnf *NetperfConf) dataPort(params map[string]interface{}) uint16 {
+	if idx, ok := params["clientIdx"].(int); ok {
+		return cnf.DataPort + uint16(idx)
+	}
+	return cnf.DataPort
+}
+
+// WriteSrvPortsYaml writes the ports part of yaml (e.g., for services). If
+// there are multiple clients, there is a data port for each client.
+//
+// NB: the netperf client tells the server the data port to listen to, and
+// connects to the same port. Hence, for NodePort services, the node ports of
+// the data ports are the data ports (which need to be in the node port range).
+func (cnf *NetperfConf) WriteSrvPortsYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
 	pw.AppendNewLineOrDie(`- name: netperf-ctl`)
 	pw.AppendNewLineOrDie(`  protocol: TCP`)
-	pw.AppendNewLineOrDie(`  port: 12865`)
-	pw.AppendNewLineOrDie(`  targetPort: 12865`)
-	pw.AppendNewLineOrDie(`- name: netperf-data`)
-	pw.AppendNewLineOrDie(`  protocol: TCP`)
-	pw.AppendNewLineOrDie(fmt.Sprintf(`  port: %d`, cnf.DataPort))
-	pw.AppendNewLineOrDie(fmt.Sprintf(`  targetPort: %d`, cnf.DataPort))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  port: %d`, netperfCtlPort))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  targetPort: %d`, netperfCtlPort))
+
+	clients, _ := params["clients"].(int)
+	if clients <= 1 {
+		pw.AppendNewLineOrDie(`- name: netperf-data`)
+		pw.AppendNewLineOrDie(`  protocol: TCP`)
+		pw.AppendNewLineOrDie(fmt.Sprintf(`  port: %d`, cnf.DataPort))
+		pw.AppendNewLineOrDie(fmt.Sprintf(`  targetPort: %d`, cnf.DataPort))
+		return pinNodePortWrite(pw, params, int(cnf.DataPort))
+	}
+	for i := 0; i < clients; i++ {
+		port := cnf.DataPort + uint16(i)
+		pw.AppendNewLineOrDie(fmt.Sprintf(`- name: netperf-data-%02d`, i))
+		pw.AppendNewLineOrDie(`  protocol: TCP`)
+		pw.AppendNewLineOrDie(fmt.Sprintf(`  port: %d`, port))
+		pw.AppendNewLineOrDie(fmt.Sprintf(`  targetPort: %d`, port))
+		if err := pinNodePortWrite(pw, params, int(port)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PinnedPorts returns the data ports, whose node ports are pinned for NodePort
+// services (see WriteSrvPortsYaml)
+func (cnf *NetperfConf) PinnedPorts(clients int) []int {
+	if clients <= 1 {
+		return []int{int(cnf.DataPort)}
+	}
+	ports := make([]int, clients)
+	for i := range ports {
+		ports[i] = int(cnf.DataPort) + i
+	}
+	return ports
 }
 
 /**
@@ -113,14 +171,8 @@ func netperfOutFieldsCommon() []string {
 	}
 }
 
-// WriteCliContainerYaml writes the client yaml
-func (cnf *NetperfRRConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) {
-	serverIP, ok := params["serverIP"]
-	if !ok {
-		panic("serverIP undefined")
-	}
-
-	outputFields := append(
+func netperfRROutFields() []string {
+	return append(
 		netperfOutFieldsCommon(),
 		"TRANSACTION_RATE",
 		"P50_LATENCY",
@@ -132,10 +184,20 @@ func (cnf *NetperfRRConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params m
 		"RESPONSE_SIZE",
 		"BURST_SIZE",
 	)
+}
+
+// WriteCliContainerYaml writes the client yaml
+func (cnf *NetperfRRConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	serverIP, ok := params["serverIP"]
+	if !ok {
+		return errServerIPUndefined
+	}
+
+	outputFields := netperfRROutFields()
 
 	pw.AppendNewLineOrDie(`name: netperf-cli`)
 	pw.AppendNewLineOrDie(`image: cilium/kubenetbench`)
-	pw.AppendNewLineOrDie(fmt.Sprintf(`command: ["%s"]`, cnf.CliCommand))
+	cliCommandWrite(pw, params, cnf.CliCommand)
 	pw.AppendNewLineOrDie(`args : [`)
 	pw.PushPrefix("    ")
 	if len(cnf.PreArgs) > 0 {
@@ -147,6 +209,9 @@ func (cnf *NetperfRRConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params m
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-l", "%d", # timeout`, cnf.Timeout))
 	pw.AppendNewLineOrDie(`"-j", # enable additional statistics`)
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-H", "%v",`, serverIP))
+	if port := clientPort(params, netperfCtlPort); port != netperfCtlPort {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"-p", "%d", # control port`, port))
+	}
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-t", "%s", # testname`, cnf.TestName))
 	if len(cnf.MoreArgs) > 0 {
 		pw.AppendNewLineOrDie("# Additional args")
@@ -157,7 +222,7 @@ func (cnf *NetperfRRConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params m
 
 	pw.AppendNewLineOrDie(`"--",`)
 	pw.AppendNewLineOrDie("# Benchmark args")
-	pw.AppendNewLineOrDie(fmt.Sprintf(`"-P", ",%d", # data connection port`, cnf.DataPort))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-P", ",%d", # data connection port`, cnf.dataPort(params)))
 	// -D seems to kill the performance for high queue depths, so don't use it
 	// pw.AppendNewLineOrDie(`"-D",# no delay`)
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-k", "%s",`, strings.Join(outputFields, ",")))
@@ -169,6 +234,7 @@ func (cnf *NetperfRRConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params m
 	}
 	pw.PopPrefix()
 	pw.AppendNewLineOrDie(`]`)
+	return nil
 }
 
 /**
@@ -180,13 +246,8 @@ type NetperfStreamConf struct {
 	NetperfConf
 }
 
-// WriteCliContainerYaml writes the client yaml
-func (cnf *NetperfStreamConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) {
-	serverIP, ok := params["serverIP"]
-	if !ok {
-		panic("serverIP undefined")
-	}
-	outputFields := []string{
+func netperfStreamOutFields() []string {
+	return []string{
 		"THROUGHPUT",
 		"THROUGHPUT_UNITS",
 		"THROUGHPUT_CONFID",
@@ -225,9 +286,18 @@ func (cnf *NetperfStreamConf) WriteCliContainerYaml(pw *utils.PrefixWriter, para
 		"LOCAL_TRANSPORT_RETRANS",
 		"REMOTE_TRANSPORT_RETRANS",
 	}
+}
+
+// WriteCliContainerYaml writes the client yaml
+func (cnf *NetperfStreamConf) WriteCliContainerYaml(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	serverIP, ok := params["serverIP"]
+	if !ok {
+		return errServerIPUndefined
+	}
+	outputFields := netperfStreamOutFields()
 	pw.AppendNewLineOrDie(`name: netperf-cli`)
 	pw.AppendNewLineOrDie(`image: cilium/kubenetbench`)
-	pw.AppendNewLineOrDie(fmt.Sprintf(`command: ["%s"]`, cnf.CliCommand))
+	cliCommandWrite(pw, params, cnf.CliCommand)
 	pw.AppendNewLineOrDie(`args : [`)
 	pw.PushPrefix("    ")
 	if len(cnf.PreArgs) > 0 {
@@ -239,6 +309,9 @@ func (cnf *NetperfStreamConf) WriteCliContainerYaml(pw *utils.PrefixWriter, para
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-l", "%d", # timeout`, cnf.Timeout))
 	pw.AppendNewLineOrDie(`"-j", # enable additional statistics`)
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-H", "%v",`, serverIP))
+	if port := clientPort(params, netperfCtlPort); port != netperfCtlPort {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`"-p", "%d", # control port`, port))
+	}
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-t", "%s", # testname`, cnf.TestName))
 	if len(cnf.MoreArgs) > 0 {
 		pw.AppendNewLineOrDie("# Additional args")
@@ -249,7 +322,7 @@ func (cnf *NetperfStreamConf) WriteCliContainerYaml(pw *utils.PrefixWriter, para
 
 	pw.AppendNewLineOrDie(`"--",`)
 	pw.AppendNewLineOrDie("# Benchmark args")
-	pw.AppendNewLineOrDie(fmt.Sprintf(`"-P", ",%d", # data connection port`, cnf.DataPort))
+	pw.AppendNewLineOrDie(fmt.Sprintf(`"-P", ",%d", # data connection port`, cnf.dataPort(params)))
 	// -D seems to kill the performance for high queue depths, so don't use it
 	// pw.AppendNewLineOrDie(`"-D",# no delay`)
 	pw.AppendNewLineOrDie(fmt.Sprintf(`"-k", "%s",`, strings.Join(outputFields, ",")))
@@ -268,4 +341,5 @@ func (cnf *NetperfStreamConf) WriteCliContainerYaml(pw *utils.PrefixWriter, para
 	}
 	pw.PopPrefix()
 	pw.AppendNewLineOrDie(`]`)
+	return nil
 }
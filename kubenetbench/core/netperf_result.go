@@ -0,0 +1,241 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// netperf -k output is a list of KEY=VALUE lines. When using duper_netperf,
+// each line is prefixed with the stream number (e.g., "00 THROUGHPUT=10.2"),
+// and a final AGGREGATE_THROUGHPUT line is added.
+var netperfLineRegEx = regexp.MustCompile(`^(?:(\d+) )?([A-Z][A-Z0-9_]*)=(.*)$`)
+
+// netperfStreamPrefixRegEx matches the stream number prefix of duper_netperf
+var netperfStreamPrefixRegEx = regexp.MustCompile(`^\d+ `)
+
+// netperf output fields that are not numeric
+var netperfStringFields = map[string]struct{}{
+	"THROUGHPUT_UNITS": {},
+	"PROTOCOL":         {},
+	"DIRECTION":        {},
+	"LOCAL_SYSNAME":    {},
+	"LOCAL_RELEASE":    {},
+	"LOCAL_VERSION":    {},
+	"LOCAL_MACHINE":    {},
+	"REMOTEL_SYSNAME":  {},
+	"REMOTEL_RELEASE":  {},
+	"REMOTEL_VERSION":  {},
+	"REMOTEL_MACHINE":  {},
+	"COMMAND_LINE":     {},
+	"LOCAL_CPU_BIND":   {},
+	"REMOTE_CPU_BIND":  {},
+}
+
+// netperfUnit returns the unit of a (numeric) netperf field
+func netperfUnit(key string, tputUnits string) string {
+	switch {
+	case key == "THROUGHPUT" || key == "AGGREGATE_THROUGHPUT" || strings.HasSuffix(key, "_THROUGHPUT"):
+		return tputUnits
+	case key == "TRANSACTION_RATE":
+		return "Trans/s"
+	case key == "THROUGHPUT_CONFID":
+		return "%"
+	case key == "ELAPSED_TIME":
+		return "s"
+	case strings.HasSuffix(key, "_LATENCY"):
+		return "us"
+	case strings.HasSuffix(key, "_SIZE") || strings.Contains(key, "_BYTES_PER_"):
+		return "bytes"
+	default:
+		return ""
+	}
+}
+
+// netperfRawResult holds the fields of a single netperf invocation
+type netperfRawResult struct {
+	fields map[string]string
+	lines  map[string]int
+}
+
+func newNetperfRawResult() *netperfRawResult {
+	return &netperfRawResult{
+		fields: make(map[string]string),
+		lines:  make(map[string]int),
+	}
+}
+
+func (raw *netperfRawResult) toResult(test string, expected []string) *BenchResult {
+	res := NewBenchResult("netperf", test)
+	tputUnits := raw.fields["THROUGHPUT_UNITS"]
+
+	for key, val := range raw.fields {
+		line := raw.lines[key]
+		if _, ok := netperfStringFields[key]; ok {
+			res.Info[strings.ToLower(key)] = val
+			continue
+		}
+
+		v, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			res.addWarning(line, key, fmt.Sprintf("invalid numeric value %q", val))
+			continue
+		}
+		res.Metrics[strings.ToLower(key)] = Metric{
+			Value: v,
+			Unit:  netperfUnit(key, tputUnits),
+		}
+	}
+
+	for _, key := range expected {
+		if _, ok := raw.fields[key]; !ok {
+			res.addWarning(0, key, "missing field")
+		}
+	}
+
+	return res
+}
+
+// parseNetperfOutput parses the output of a netperf client that was executed
+// with -k, where expected are the requested output fields.
+func parseNetperfOutput(r io.Reader, test string, expected []string) (*BenchResult, error) {
+	var warnings []ResultWarning
+	single := newNetperfRawResult()
+	streams := make(map[string]*netperfRawResult)
+	aggregate := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		m := netperfLineRegEx.FindStringSubmatch(line)
+		if m == nil {
+			// netperf prints a banner for the test (for every stream, when
+			// using duper_netperf)
+			if strings.HasPrefix(netperfStreamPrefixRegEx.ReplaceAllString(line, ""), "MIGRATED ") {
+				continue
+			}
+			warnings = append(warnings, ResultWarning{
+				Line:    lineNo,
+				Message: fmt.Sprintf("unexpected output: %q", line),
+			})
+			continue
+		}
+
+		stream, key, val := m[1], m[2], m[3]
+		if stream == "" && key == "AGGREGATE_THROUGHPUT" {
+			aggregate = val
+			continue
+		}
+
+		raw := single
+		if stream != "" {
+			var ok bool
+			raw, ok = streams[stream]
+			if !ok {
+				raw = newNetperfRawResult()
+				streams[stream] = raw
+			}
+		}
+
+		if _, dup := raw.fields[key]; dup {
+			warnings = append(warnings, ResultWarning{
+				Line:    lineNo,
+				Key:     key,
+				Message: "duplicate field (ignoring previous value)",
+			})
+		}
+		raw.fields[key] = val
+		raw.lines[key] = lineNo
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var res *BenchResult
+	if len(streams) == 0 {
+		res = single.toResult(test, expected)
+	} else {
+		res = netperfStreamsResult(test, expected, streams, aggregate)
+		if len(single.fields) > 0 {
+			res.addWarning(0, "", "ignoring fields without a stream prefix")
+		}
+	}
+
+	res.Warnings = append(warnings, res.Warnings...)
+	sort.SliceStable(res.Warnings, func(i, j int) bool {
+		li, lj := res.Warnings[i].Line, res.Warnings[j].Line
+		// warnings that are not associated with a line go last
+		return li != 0 && (lj == 0 || li < lj)
+	})
+	return res, nil
+}
+
+// netperfStreamsResult builds the result of multiple netperf streams
+// (duper_netperf)
+func netperfStreamsResult(
+	test string,
+	expected []string,
+	streams map[string]*netperfRawResult,
+	aggregate string,
+) *BenchResult {
+	res := NewBenchResult("netperf", test)
+
+	ids := make([]string, 0, len(streams))
+	for id := range streams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	tputSum := 0.0
+	tputUnits := ""
+	for _, id := range ids {
+		sres := streams[id].toResult(test, expected)
+		sres.Info["stream"] = id
+		for _, w := range sres.Warnings {
+			w.Message = fmt.Sprintf("stream %s: %s", id, w.Message)
+			res.Warnings = append(res.Warnings, w)
+		}
+		sres.Warnings = nil
+		res.Streams = append(res.Streams, sres)
+
+		if m, ok := sres.Metrics[MetricThroughput]; ok {
+			tputSum += m.Value
+			tputUnits = m.Unit
+		}
+	}
+
+	res.Info["streams"] = strconv.Itoa(len(ids))
+	tput := tputSum
+	if aggregate != "" {
+		v, err := strconv.ParseFloat(aggregate, 64)
+		if err != nil {
+			res.addWarning(0, "AGGREGATE_THROUGHPUT", fmt.Sprintf("invalid numeric value %q", aggregate))
+		} else {
+			tput = v
+		}
+	} else {
+		res.addWarning(0, "AGGREGATE_THROUGHPUT", "missing field")
+	}
+	res.Metrics[MetricThroughput] = Metric{Value: tput, Unit: tputUnits}
+	return res
+}
+
+// ParseCliOutput parses the client output
+func (cnf *NetperfRRConf) ParseCliOutput(r io.Reader) (*BenchResult, error) {
+	return parseNetperfOutput(r, cnf.TestName, netperfRROutFields())
+}
+
+// ParseCliOutput parses the client output
+func (cnf *NetperfStreamConf) ParseCliOutput(r io.Reader) (*BenchResult, error) {
+	return parseNetperfOutput(r, cnf.TestName, netperfStreamOutFields())
+}
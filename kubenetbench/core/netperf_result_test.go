@@ -0,0 +1,94 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+var netperfRROutput = `MIGRATED TCP REQUEST/RESPONSE TEST from 0.0.0.0 (0.0.0.0) port 0 AF_INET to 10.17.178.131 () port 8000 AF_INET : demo : first burst 0
+enable_enobufs failed: getprotobyname
+THROUGHPUT=2841.07
+THROUGHPUT_UNITS=Trans/s
+TRANSACTION_RATE=2841.067
+P50_LATENCY=334
+P90_LATENCY=391
+RT_LATENCY=351.980
+MEAN_LATENCY=351.66
+REQUEST_SIZE=1
+RESPONSE_SIZE=1
+LOCAL_TRANSPORT_RETRANS=0
+REMOTE_TRANSPORT_RETRANS=bogus
+`
+
+func TestParseNetperfRR(t *testing.T) {
+	expected := []string{"THROUGHPUT", "THROUGHPUT_UNITS", "P50_LATENCY", "P90_LATENCY", "STDEV_LATENCY", "REMOTE_TRANSPORT_RETRANS"}
+	res, err := parseNetperfOutput(strings.NewReader(netperfRROutput), "tcp_rr", expected)
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	metrics := map[string]Metric{
+		MetricThroughput: {2841.07, "Trans/s"},
+		MetricP50Latency: {334, "us"},
+		MetricP90Latency: {391, "us"},
+		"request_size":   {1, "bytes"},
+	}
+	for name, expected := range metrics {
+		if m, ok := res.Metrics[name]; !ok || m != expected {
+			t.Errorf("metric %s: got %v (exists:%t) while expected %v", name, m, ok, expected)
+		}
+	}
+
+	if res.Info["throughput_units"] != "Trans/s" {
+		t.Errorf("unexpected info: %v", res.Info)
+	}
+
+	// enobufs noise, invalid REMOTE_TRANSPORT_RETRANS, and missing STDEV_LATENCY
+	warnings := []ResultWarning{
+		{Line: 2, Message: `unexpected output: "enable_enobufs failed: getprotobyname"`},
+		{Line: 13, Key: "REMOTE_TRANSPORT_RETRANS", Message: `invalid numeric value "bogus"`},
+		{Key: "STDEV_LATENCY", Message: "missing field"},
+	}
+	if len(res.Warnings) != len(warnings) {
+		t.Fatalf("got warnings %v while expected %v", res.Warnings, warnings)
+	}
+	for i := range warnings {
+		if res.Warnings[i] != warnings[i] {
+			t.Errorf("warning %d: got %v while expected %v", i, res.Warnings[i], warnings[i])
+		}
+	}
+}
+
+var netperfStreamsOutput = `00 MIGRATED TCP STREAM TEST from 0.0.0.0 (0.0.0.0) port 0 AF_INET to 10.17.178.131 () port 8000 AF_INET : demo
+01 MIGRATED TCP STREAM TEST from 0.0.0.0 (0.0.0.0) port 0 AF_INET to 10.17.178.131 () port 8000 AF_INET : demo
+00 THROUGHPUT=100.5
+01 THROUGHPUT=200.5
+00 THROUGHPUT_UNITS=10^6bits/s
+01 THROUGHPUT_UNITS=10^6bits/s
+AGGREGATE_THROUGHPUT=301
+`
+
+func TestParseNetperfStreams(t *testing.T) {
+	expected := []string{"THROUGHPUT", "THROUGHPUT_UNITS"}
+	res, err := parseNetperfOutput(strings.NewReader(netperfStreamsOutput), "tcp_stream", expected)
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	if len(res.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", res.Warnings)
+	}
+
+	if len(res.Streams) != 2 {
+		t.Fatalf("got %d streams while expected 2", len(res.Streams))
+	}
+
+	tput := res.Metrics[MetricThroughput]
+	if tput.Value != 301 || tput.Unit != "10^6bits/s" {
+		t.Errorf("unexpected aggregate throughput: %v", tput)
+	}
+
+	if v, _ := res.Streams[1].GetMetric(MetricThroughput); v != 200.5 {
+		t.Errorf("unexpected throughput for stream 1: %v", v)
+	}
+}
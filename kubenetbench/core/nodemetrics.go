@@ -0,0 +1,245 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// NodeMetricsSample is a sample of the metrics of a node, as streamed by its
+// monitor (see StreamNodeMetrics). The samples of each node are stored as JSON
+// lines in node-metrics-<node>.jsonl.
+type NodeMetricsSample struct {
+	Time time.Time `json:"time"`
+	// utilization of every cpu (and of all cpus, "cpu") since the previous
+	// sample
+	CPUs []CPUSample `json:"cpus"`
+	// counters of the network interfaces
+	NICs []NICSample `json:"nics"`
+	// TCP counters (/proc/net/snmp)
+	TCP map[string]int64 `json:"tcp"`
+}
+
+// CPUSample is the utilization (%) of a cpu
+type CPUSample struct {
+	CPU     string  `json:"cpu"`
+	User    float64 `json:"user"`
+	Nice    float64 `json:"nice"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	IOWait  float64 `json:"iowait"`
+	IRQ     float64 `json:"irq"`
+	SoftIRQ float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
+}
+
+// NICSample are the counters of a network interface
+type NICSample struct {
+	Name      string `json:"name"`
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	RxDropped uint64 `json:"rx_dropped"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+	TxDropped uint64 `json:"tx_dropped"`
+}
+
+func newNodeMetricsSample(m *pb.NodeMetrics) *NodeMetricsSample {
+	ret := &NodeMetricsSample{
+		Time: time.Unix(0, m.Timestamp).UTC(),
+		TCP:  make(map[string]int64, len(m.Tcp)),
+	}
+	for _, c := range m.Cpus {
+		ret.CPUs = append(ret.CPUs, CPUSample{
+			CPU:     c.Cpu,
+			User:    c.User,
+			Nice:    c.Nice,
+			System:  c.System,
+			Idle:    c.Idle,
+			IOWait:  c.Iowait,
+			IRQ:     c.Irq,
+			SoftIRQ: c.Softirq,
+			Steal:   c.Steal,
+		})
+	}
+	for _, n := range m.Nics {
+		ret.NICs = append(ret.NICs, NICSample{
+			Name:      n.Name,
+			RxBytes:   n.RxBytes,
+			RxPackets: n.RxPackets,
+			RxErrors:  n.RxErrors,
+			RxDropped: n.RxDropped,
+			TxBytes:   n.TxBytes,
+			TxPackets: n.TxPackets,
+			TxErrors:  n.TxErrors,
+			TxDropped: n.TxDropped,
+		})
+	}
+	for _, c := range m.Tcp {
+		ret.TCP[c.Name] = c.Value
+	}
+	return ret
+}
+
+// streamNodeMetricsNode subscribes to the metrics of a node, calls started
+// once the monitor started the stream (or failed to), and calls fn for every
+// sample until ctx is done. It is a variable so that tests can replace it.
+var streamNodeMetricsNode = func(
+	ctx context.Context,
+	s *Session,
+	nodeName string,
+	conf *pb.NodeMetricsConf,
+	started func(),
+	fn func(*pb.NodeMetrics) error,
+) error {
+	conn, err := s.DialMonitor(ctx, nodeName)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	cli := pb.NewKubebenchMonitorClient(conn)
+	stream, err := cli.StreamNodeMetrics(ctx, conf)
+	if err != nil {
+		return fmt.Errorf("failed to stream node metrics from monitor on %q: %w", nodeName, err)
+	}
+	// NB: the monitor sends the headers when it starts sampling, and errors
+	// (e.g., an invalid interval) are returned by Recv
+	stream.Header()
+	started()
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF || status.Code(err) == codes.Canceled {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to receive node metrics from monitor on %q: %w", nodeName, err)
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+}
+
+// MinNodeMetricsInterval is the minimum sampling interval of node metrics
+// that monitors accept
+const MinNodeMetricsInterval = 100 * time.Millisecond
+
+// timeout for the node metrics streams to start
+var nodeMetricsStartTimeout = 30 * time.Second
+
+// nodeMetricsStreams are the node metrics subscriptions of a client execution
+type nodeMetricsStreams struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// podNodes returns the (sorted) nodes that host the pods of the run
+func (r *RunBenchCtx) podNodes() ([]string, error) {
+	pods, err := r.KubeGetPods()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]struct{})
+	for _, pod := range pods {
+		if pod.Node != "" {
+			nodes[pod.Node] = struct{}{}
+		}
+	}
+	ret := make([]string, 0, len(nodes))
+	for node := range nodes {
+		ret = append(ret, node)
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// writeNodeMetrics writes the samples of a node stream to fname. started is
+// called when the stream starts (see streamNodeMetricsNode).
+func (r *RunBenchCtx) writeNodeMetrics(ctx context.Context, node string, fname string, started func()) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	samples := 0
+	conf := &pb.NodeMetricsConf{Interval: r.nodeMetrics.String()}
+	err = streamNodeMetricsNode(ctx, r.session, node, conf, started, func(m *pb.NodeMetrics) error {
+		samples++
+		return enc.Encode(newNodeMetricsSample(m))
+	})
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	log.Printf("node metrics of %s (%d samples) can be found in: %s", node, samples, fname)
+	return err
+}
+
+// startNodeMetrics subscribes to the metrics of the nodes that host the pods
+// of the run, and stores the samples of each node in
+// dir/node-metrics-<node>.jsonl until the returned streams are stopped. It
+// returns once all the streams started or failed (or nodeMetricsStartTimeout
+// expired), so that clients start after them.
+func (r *RunBenchCtx) startNodeMetrics(dir string) *nodeMetricsStreams {
+	ctx, cancel := context.WithCancel(context.Background())
+	ret := &nodeMetricsStreams{cancel: cancel}
+
+	nodes, err := r.podNodes()
+	if err != nil {
+		log.Printf("failed to get nodes for node metrics: %s", err)
+		return ret
+	}
+
+	var startWg sync.WaitGroup
+	for _, node := range nodes {
+		node := node
+		fname := fmt.Sprintf("%s/node-metrics-%s.jsonl", dir, node)
+		var once sync.Once
+		started := func() { once.Do(startWg.Done) }
+		startWg.Add(1)
+		ret.wg.Add(1)
+		go func() {
+			defer ret.wg.Done()
+			defer started()
+			if err := r.writeNodeMetrics(ctx, node, fname, started); err != nil {
+				log.Printf("node metrics on %s failed: %s", node, err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		startWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(nodeMetricsStartTimeout):
+		log.Printf("timed out waiting for node metrics streams to start")
+	}
+	log.Printf("streaming node metrics (interval: %s) from nodes: %v", r.nodeMetrics, nodes)
+	return ret
+}
+
+// stop ends the subscriptions, and waits until all samples are written
+func (s *nodeMetricsStreams) stop() {
+	s.cancel()
+	s.wg.Wait()
+}
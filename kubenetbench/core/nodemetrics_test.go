@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+func TestExecuteNodeMetrics(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+
+	// every stream sends two samples, and then waits until it is cancelled
+	cancelled := make(chan string, 8)
+	var released int32
+	origStream := streamNodeMetricsNode
+	streamNodeMetricsNode = func(
+		ctx context.Context,
+		s *Session,
+		nodeName string,
+		conf *pb.NodeMetricsConf,
+		started func(),
+		fn func(*pb.NodeMetrics) error,
+	) error {
+		if conf.Interval != "200ms" {
+			t.Errorf("unexpected interval: %q", conf.Interval)
+		}
+		// the stream starts before the clients are released
+		time.Sleep(100 * time.Millisecond)
+		if atomic.LoadInt32(&released) != 0 {
+			t.Errorf("clients released before the node metrics stream started")
+		}
+		started()
+		for i := int64(1); i <= 2; i++ {
+			err := fn(&pb.NodeMetrics{
+				Timestamp: i * int64(time.Second),
+				Cpus:      []*pb.CPUStats{{Cpu: "cpu0", User: 10, Softirq: 5, Idle: 85}},
+				Nics:      []*pb.NICStats{{Name: "eth0", RxBytes: uint64(1000 * i), TxDropped: 1}},
+				Tcp:       []*pb.Counter{{Name: "RetransSegs", Value: 3 * i}},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		cancelled <- nodeName
+		return nil
+	}
+	t.Cleanup(func() { streamNodeMetricsNode = origStream })
+
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	st := ServiceSt{ServiceType: ServiceClusterIP}
+	fake := newTestService(t, &st, cnf, "")
+	fake.PodHook = func(pod *kube.Pod) string {
+		if pod.Labels["role"] == "cli" {
+			setAtBarrier(pod)
+			return netperfRROutput
+		}
+		return ""
+	}
+	fakeBarrier(t, fake)
+	barrierHook := fake.PortForwardHook
+	fake.PortForwardHook = func(podName string, port int) (int, error) {
+		if port != tcpStatsPort {
+			atomic.StoreInt32(&released, 1)
+		}
+		return barrierHook(podName, port)
+	}
+	st.RunBenchCtx.SetNodeMetrics(200 * time.Millisecond)
+
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-cancelled:
+	default:
+		t.Errorf("node metrics stream was not cancelled")
+	}
+
+	dir := st.RunBenchCtx.getDir()
+	files, err := filepath.Glob(dir + "/node-metrics-*.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "node-metrics-node1.jsonl" {
+		t.Fatalf("unexpected node metrics files: %v", files)
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var samples []NodeMetricsSample
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s NodeMetricsSample
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, s)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("got %d samples while expected 2", len(samples))
+	}
+	s := samples[1]
+	if !s.Time.Equal(time.Unix(2, 0)) {
+		t.Errorf("unexpected sample time: %s", s.Time)
+	}
+	if len(s.CPUs) != 1 || s.CPUs[0].SoftIRQ != 5 || s.CPUs[0].Idle != 85 {
+		t.Errorf("unexpected cpus: %+v", s.CPUs)
+	}
+	if len(s.NICs) != 1 || s.NICs[0].RxBytes != 2000 || s.NICs[0].TxDropped != 1 {
+		t.Errorf("unexpected nics: %+v", s.NICs)
+	}
+	if s.TCP["RetransSegs"] != 6 {
+		t.Errorf("unexpected tcp counters: %v", s.TCP)
+	}
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/template"
+
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// ScenarioPairs is the scenario of client/server pairs runs
+const ScenarioPairs = "pairs"
+
+// PairsSt is the necessary state for executing a benchmark of multiple
+// independent client/server pod pairs. The number of pairs is the number of
+// clients (see SetClients), and client i uses the server of pair i.
+type PairsSt struct {
+	RunBenchCtx *RunBenchCtx
+}
+
+// NB: server pods are spread across nodes (best effort)
+var pairsSrvTemplate = template.Must(template.New("srv").Parse(`apiVersion: v1
+kind: Pod
+metadata:
+  name: {{.srvName}}
+  labels : {
+    {{.sessLabel}},
+    {{.runLabel}},
+    role: srv,
+    {{.pairLabel}},
+  }
+spec:
+  {{.srvSpec}}
+  topologySpreadConstraints:
+  - maxSkew: 1
+    topologyKey: kubernetes.io/hostname
+    whenUnsatisfiable: ScheduleAnyway
+    labelSelector:
+      matchLabels:
+        {{.runLabel}}
+        role: srv
+  containers:
+  - {{.srvContainer}}
+`))
+
+func (s *PairsSt) genSrvYaml(pairs int) (string, error) {
+	templates := map[string]utils.PrefixRenderer{
+		"netperfContainer": s.RunBenchCtx.benchmark.WriteSrvContainerYaml,
+		"srvSpec":          s.RunBenchCtx.srvPodSpecWrite,
+	}
+
+	yaml := fmt.Sprintf("%s/netserv.yaml", s.RunBenchCtx.getDir())
+	log.Printf("Generating %s", yaml)
+	f, err := os.Create(yaml)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	for i := 0; i < pairs; i++ {
+		idx := fmt.Sprintf("%02d", i)
+		vals := map[string]interface{}{
+			"srvName":      s.RunBenchCtx.objName("srv-" + idx),
+			"sessLabel":    s.RunBenchCtx.session.getSessionLabel(": "),
+			"runLabel":     s.RunBenchCtx.getRunLabel(": "),
+			"pairLabel":    fmt.Sprintf("%s: \"%s\"", pairIdxLabel, idx),
+			"srvContainer": "{{template \"netperfContainer\"}}",
+			"srvSpec":      "{{template \"srvSpec\"}}",
+		}
+
+		if i > 0 {
+			if _, err := f.WriteString("---\n"); err != nil {
+				return "", err
+			}
+		}
+		err = utils.RenderTemplate(pairsSrvTemplate, vals, templates, f)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate %s: %w", yaml, err)
+		}
+	}
+	return yaml, nil
+}
+
+// Execute pairs command
+func (s PairsSt) Execute() (err error) {
+	defer func() {
+		s.RunBenchCtx.finishManifest(err)
+	}()
+
+	if s.RunBenchCtx.clientJob {
+		return fmt.Errorf("client jobs are not supported for client/server pairs")
+	}
+
+	err = s.RunBenchCtx.KubeCreateNamespace()
+	if err != nil {
+		return err
+	}
+
+	pairs := s.RunBenchCtx.numClients()
+	srvSelector := fmt.Sprintf("%s,role=srv", s.RunBenchCtx.getRunLabel("="))
+	pairSelector := func(i int) string {
+		return fmt.Sprintf("%s,%s=%02d", srvSelector, pairIdxLabel, i)
+	}
+
+	defer func() {
+		// attempt to save server logs
+		for i := 0; i < pairs; i++ {
+			s.RunBenchCtx.KubeSaveLogs(pairSelector(i), fmt.Sprintf("%s/srv-%02d.log", s.RunBenchCtx.getDir(), i))
+		}
+
+		// delete the objects of the run (on success, error, or interrupt)
+		if err := s.RunBenchCtx.KubeCleanup(); err != nil {
+			log.Printf("cleanup failed: %s", err)
+		}
+	}()
+
+	// start server pods
+	srvYamlFname, err := s.genSrvYaml(pairs)
+	if err != nil {
+		return err
+	}
+
+	err = s.RunBenchCtx.KubeApply(srvYamlFname)
+	if err != nil {
+		return err
+	}
+
+	// wait for all server pods to be ready
+	srvPods, err := s.RunBenchCtx.waitForServers(srvSelector, pairs)
+	if err != nil {
+		return err
+	}
+
+	srvIPs := make([]string, pairs)
+	for i := range srvPods {
+		var idx int
+		pair := srvPods[i].Labels[pairIdxLabel]
+		if _, err := fmt.Sscanf(pair, "%d", &idx); err != nil || idx < 0 || idx >= pairs {
+			return fmt.Errorf("server pod %s: invalid pair label: %q", srvPods[i].Name, pair)
+		}
+		srvIPs[idx] = srvPods[i].IP
+		log.Printf("pair %s: server_ip=%s (node: %s)", pair, srvPods[i].IP, srvPods[i].Node)
+	}
+
+	return s.RunBenchCtx.runClients(srvIPs)
+}
@@ -5,7 +5,6 @@ import (
 	"log"
 	"os"
 	"text/template"
-	"time"
 
 	"github.com/cilium/kubenetbench/utils"
 )
@@ -13,13 +12,15 @@ import (
 // Pod2PodSt is the necessary state for executing an pod-to-pod benchmark
 type Pod2PodSt struct {
 	RunBenchCtx *RunBenchCtx
-	Policy      string
+	// Policy is the kind of policies to apply (see PolicyKinds), or empty
+	Policy     string
+	PolicySize int
 }
 
 var pod2podSrvTemplate = template.Must(template.New("srv").Parse(`apiVersion: v1
 kind: Pod
 metadata:
-  name: knb-srv
+  name: {{.srvName}}
   labels : {
     {{.sessLabel}},
     {{.runLabel}},
@@ -33,6 +34,7 @@ spec:
 
 func (s *Pod2PodSt) genSrvYaml() (string, error) {
 	vals := map[string]interface{}{
+		"srvName":      s.RunBenchCtx.objName("srv"),
 		"sessLabel":    s.RunBenchCtx.session.getSessionLabel(": "),
 		"runLabel":     s.RunBenchCtx.getRunLabel(": "),
 		"srvContainer": "{{template \"netperfContainer\"}}",
@@ -50,64 +52,28 @@ func (s *Pod2PodSt) genSrvYaml() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	utils.RenderTemplate(pod2podSrvTemplate, vals, templates, f)
+	err = utils.RenderTemplate(pod2podSrvTemplate, vals, templates, f)
 	f.Close()
-	return yaml, nil
-}
-
-var pod2podPortPolicyTemplate = template.Must(template.New("policy").Parse(`apiVersion: networking.k8s.io/v1
-kind: NetworkPolicy
-metadata:
-  name: kubenetbench-{{.runID}}-policy
-  labels : {
-     "kubenetbench-runid": {{.runID}},
-  }
-spec:
-  podSelector:
-    matchLabels:
-      role: srv
-  policyTypes:
-  - Ingress
-  ingress:
-  - from:
-    ports:
-    # control port
-    - protocol: TCP
-      port: 12865
-    # data port
-    - protocol: TCP
-      port: {{.dataPort}}
-`))
-
-func (s *Pod2PodSt) genPortPolicyYaml() string {
-	m := map[string]interface{}{
-		"runID": s.RunBenchCtx.runid,
-	}
-
-	yaml := fmt.Sprintf("%s/port-policy.yaml", s.RunBenchCtx.getDir())
-	log.Printf("Generating %s", yaml)
-	f, err := os.Create(yaml)
 	if err != nil {
-		log.Fatal(err)
+		return "", fmt.Errorf("failed to generate %s: %w", yaml, err)
 	}
-	pod2podPortPolicyTemplate.Execute(f, m)
-	f.Close()
-	return yaml
-}
-
-func (s *Pod2PodSt) genCliYaml(serverIP string) (string, error) {
-	return s.RunBenchCtx.genCliYaml(serverIP)
+	return yaml, nil
 }
 
 // Execute pod2pod command
-func (s Pod2PodSt) Execute() error {
-	// start server pod (netserver)
-	srvYamlFname, err := s.genSrvYaml()
-	if err != nil {
-		return err
+func (s Pod2PodSt) Execute() (err error) {
+	defer func() {
+		s.RunBenchCtx.finishManifest(err)
+	}()
+
+	if s.Policy != "" {
+		err = s.RunBenchCtx.validatePolicy(s.Policy, s.PolicySize)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = s.RunBenchCtx.KubeApply(srvYamlFname)
+	err = s.RunBenchCtx.KubeCreateNamespace()
 	if err != nil {
 		return err
 	}
@@ -118,43 +84,39 @@ func (s Pod2PodSt) Execute() error {
 		// attempt to save server logs
 		s.RunBenchCtx.KubeSaveLogs(srvSelector, fmt.Sprintf("%s/srv.log", s.RunBenchCtx.getDir()))
 
-		// FIXME: this does not work because we call functions that
-		// call log.Fatal() which calls exit() which does not run the
-		// deferred operations
-		s.RunBenchCtx.KubeCleanup()
+		// delete the objects of the run (on success, error, or interrupt)
+		if err := s.RunBenchCtx.KubeCleanup(); err != nil {
+			log.Printf("cleanup failed: %s", err)
+		}
 	}()
 
-	// get server pod IP
-	time.Sleep(2 * time.Second)
-	srvIP, err := s.RunBenchCtx.KubeGetPodIP(srvSelector, 30, 2*time.Second)
+	// start server pod (netserver)
+	srvYamlFname, err := s.genSrvYaml()
 	if err != nil {
 		return err
 	}
-	log.Printf("server_ip=%s", srvIP)
-
-	// start policy if specified
-	if s.Policy == "port" {
-		policyYamlFname := s.genPortPolicyYaml()
-		err := s.RunBenchCtx.KubeApply(policyYamlFname)
-		if err != nil {
-			return fmt.Errorf("failed to apply policy: %w", err)
-		}
-	}
 
-	// start netperf client (netperf)
-	cliYamlFname, err := s.genCliYaml(srvIP)
+	err = s.RunBenchCtx.KubeApply(srvYamlFname)
 	if err != nil {
 		return err
 	}
 
-	err = s.RunBenchCtx.KubeApply(cliYamlFname)
+	// wait for the server pod to be ready
+	srvPod, err := s.RunBenchCtx.waitForServer(srvSelector)
 	if err != nil {
-		return fmt.Errorf("failed to initiate client: %w", err)
+		return err
 	}
+	srvIP := srvPod.IP
+	log.Printf("server_ip=%s", srvIP)
 
-	cliSelector := fmt.Sprintf("%s,role=cli", s.RunBenchCtx.getRunLabel("="))
-	// attempt to save client logs
-	defer s.RunBenchCtx.KubeSaveLogs(cliSelector, fmt.Sprintf("%s/cli.log", s.RunBenchCtx.getDir()))
+	// apply policies if specified
+	if s.Policy != "" {
+		err = s.RunBenchCtx.applyPolicy(s.Policy, s.PolicySize)
+		if err != nil {
+			return err
+		}
+	}
 
-	return s.RunBenchCtx.finalizeAndWait()
+	// start netperf client(s) (netperf)
+	return s.RunBenchCtx.runClients([]string{srvIP})
 }
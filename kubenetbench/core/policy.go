@@ -0,0 +1,356 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+	"github.com/cilium/kubenetbench/utils"
+)
+
+// Policy kinds. Every kind allows the benchmark traffic (clients to the server
+// ports), and the size is the number of additional rules that do not match
+// it, so that runs can measure the datapath cost versus the number of rules.
+const (
+	// a NetworkPolicy that allows the server ports from anywhere (size is
+	// ignored)
+	PolicyPort = "port"
+	// size NetworkPolicies that select the server and allow other pods,
+	// and one that allows the clients
+	PolicyPolicies = "policies"
+	// a NetworkPolicy with size CIDR (ipBlock) peers
+	PolicyCIDR = "cidr"
+	// a NetworkPolicy with size label selector (matchExpressions) peers
+	PolicyLabels = "labels"
+	// a NetworkPolicy with size L4 port ranges (endPort)
+	PolicyPortRanges = "port-ranges"
+	// a CiliumNetworkPolicy that denies size CIDRs (ingressDeny)
+	PolicyDeny = "deny"
+	// a CiliumNetworkPolicy with size L7 HTTP rules (http benchmarks only)
+	PolicyL7 = "l7"
+)
+
+// PolicyKinds are the supported policy kinds
+var PolicyKinds = []string{PolicyPort, PolicyPolicies, PolicyCIDR, PolicyLabels, PolicyPortRanges, PolicyDeny, PolicyL7}
+
+const (
+	// label of the (non-existing) peers of generated rules
+	policyPeerLabel = "knb-policy-peer"
+	// first port of generated port ranges, and the size of each range
+	policyPortRangeBase = 40000
+	policyPortRangeLen  = 10
+)
+
+// CIDRs of generated rules are /32s from 198.18.0.0/15 (reserved for
+// benchmarking, see RFC 2544)
+const policyMaxCIDRs = 1 << 17
+
+// PolicyManifest describes the policies applied by a run
+type PolicyManifest struct {
+	Kind string `json:"kind"`
+	Size int    `json:"size"`
+	// file (in the run directory) with the policies
+	File string `json:"file"`
+	// applied objects (kind/name)
+	Objects []string `json:"objects"`
+}
+
+// policyPort is a (server) port allowed by a policy
+type policyPort struct {
+	Protocol   string `json:"protocol"`
+	Port       int    `json:"port"`
+	TargetPort int    `json:"targetPort"`
+}
+
+type policyPortRange struct {
+	Port    int
+	EndPort int
+}
+
+var policyTemplate = template.Must(template.New("policy").Parse(`apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: {{.policyName}}
+  labels : {
+     {{.sessLabel}},
+     {{.runLabel}},
+  }
+spec:
+  podSelector:
+    matchLabels:
+      {{.runLabel}}
+      role: srv
+  policyTypes:
+  - Ingress
+  ingress:
+  - from:
+{{- range .cidrs}}
+    - ipBlock:
+        cidr: {{.}}
+{{- end}}
+{{- range .peers}}
+    - podSelector:
+        matchExpressions:
+        - key: {{$.peerLabel}}
+          operator: In
+          values: ["{{.}}"]
+{{- end}}
+{{- if .allowRun}}
+    - podSelector:
+        matchLabels:
+          {{.runLabel}}
+{{- end}}
+    ports:
+{{- range .portRanges}}
+    - protocol: TCP
+      port: {{.Port}}
+      endPort: {{.EndPort}}
+{{- end}}
+{{- range .ports}}
+    - protocol: {{.Protocol}}
+      port: {{.TargetPort}}
+{{- end}}
+`))
+
+var ciliumPolicyTemplate = template.Must(template.New("cnp").Parse(`apiVersion: cilium.io/v2
+kind: CiliumNetworkPolicy
+metadata:
+  name: {{.policyName}}
+  labels : {
+     {{.sessLabel}},
+     {{.runLabel}},
+  }
+spec:
+  endpointSelector:
+    matchLabels:
+      {{.runLabel}}
+      role: srv
+{{- if .cidrs}}
+  ingressDeny:
+  - fromCIDR:
+{{- range .cidrs}}
+    - {{.}}
+{{- end}}
+{{- end}}
+  ingress:
+  - fromEndpoints:
+    - matchLabels:
+        {{.runLabel}}
+    toPorts:
+    - ports:
+{{- range .ports}}
+      - port: "{{.TargetPort}}"
+        protocol: {{.Protocol}}
+{{- end}}
+{{- if .httpPaths}}
+      rules:
+        http:
+{{- range .httpPaths}}
+        - method: {{$.httpMethod}}
+          path: "{{.}}"
+{{- end}}
+{{- end}}
+`))
+
+// validatePolicy checks the kind and the size of a policy, and that it applies
+// to the client and server of the run
+func (r *RunBenchCtx) validatePolicy(kind string, size int) error {
+	valid := false
+	for _, k := range PolicyKinds {
+		valid = valid || k == kind
+	}
+	if !valid {
+		return fmt.Errorf("invalid policy: %s", kind)
+	}
+
+	maxSize := 0
+	switch kind {
+	case PolicyCIDR, PolicyDeny:
+		maxSize = policyMaxCIDRs
+	case PolicyPortRanges:
+		maxSize = (65536 - policyPortRangeBase) / policyPortRangeLen
+	}
+	if size < 0 || (maxSize > 0 && size > maxSize) {
+		return fmt.Errorf("invalid size for %s policy: %d", kind, size)
+	}
+
+	if kind == PolicyL7 && r.benchmark.GetName() != "http" {
+		return fmt.Errorf("%s policies require the http benchmark", kind)
+	}
+
+	if r.srvSpec.HostNetwork {
+		return fmt.Errorf("policies do not apply to servers on the host network")
+	}
+	// NB: policies other than port allow the clients based on the run label,
+	// which does not select traffic from the host network
+	if r.cliSpec.HostNetwork && kind != PolicyPort {
+		return fmt.Errorf("%s policies do not allow clients on the host network (use the %s policy)", kind, PolicyPort)
+	}
+	return nil
+}
+
+// srvPolicyPorts returns the ports of the server, based on the ports that the
+// benchmark uses for services (see Benchmark.WriteSrvPortsYaml)
+func (r *RunBenchCtx) srvPolicyPorts() ([]policyPort, error) {
+	var buff bytes.Buffer
+	pw := utils.NewPrefixWriter(&buff, false)
+	err := r.benchmark.WriteSrvPortsYaml(pw, map[string]interface{}{
+		"clients": r.numClients(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server ports: %w", err)
+	}
+	if err := pw.Flush(); err != nil {
+		return nil, err
+	}
+
+	var ports []policyPort
+	err = yaml.Unmarshal(buff.Bytes(), &ports)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server ports: %w", err)
+	}
+	for i := range ports {
+		if ports[i].TargetPort == 0 {
+			ports[i].TargetPort = ports[i].Port
+		}
+	}
+	return ports, nil
+}
+
+func policyCIDR(i int) string {
+	return fmt.Sprintf("198.%d.%d.%d/32", 18+i/65536, (i/256)%256, i%256)
+}
+
+// genPolicyYaml generates the policies of the given kind and size, and
+// returns the file name and the objects (kind/name) it contains
+func (r *RunBenchCtx) genPolicyYaml(kind string, size int) (string, []string, error) {
+	ports, err := r.srvPolicyPorts()
+	if err != nil {
+		return "", nil, err
+	}
+
+	newVals := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"policyName": name,
+			"sessLabel":  r.session.getSessionLabel(": "),
+			"runLabel":   r.getRunLabel(": "),
+			"peerLabel":  policyPeerLabel,
+			"ports":      ports,
+			"allowRun":   kind != PolicyPort,
+		}
+	}
+
+	var cidrs, peers, httpPaths []string
+	var portRanges []policyPortRange
+	for i := 0; i < size; i++ {
+		switch kind {
+		case PolicyCIDR, PolicyDeny:
+			cidrs = append(cidrs, policyCIDR(i))
+		case PolicyLabels:
+			peers = append(peers, fmt.Sprintf("%d", i))
+		case PolicyPortRanges:
+			port := policyPortRangeBase + i*policyPortRangeLen
+			portRanges = append(portRanges, policyPortRange{port, port + policyPortRangeLen - 1})
+		case PolicyL7:
+			httpPaths = append(httpPaths, fmt.Sprintf("/knb-%d", i))
+		}
+	}
+
+	type policyDoc struct {
+		tmpl *template.Template
+		kind kube.Kind
+		vals map[string]interface{}
+	}
+	var docs []policyDoc
+	switch kind {
+	case PolicyPolicies:
+		// one policy for each (non-existing) peer
+		for i := 0; i < size; i++ {
+			vals := newVals(r.objName(fmt.Sprintf("policy-%d", i)))
+			vals["peers"] = []string{fmt.Sprintf("%d", i)}
+			vals["allowRun"] = false
+			docs = append(docs, policyDoc{policyTemplate, kube.KindNetworkPolicy, vals})
+		}
+		docs = append(docs, policyDoc{policyTemplate, kube.KindNetworkPolicy, newVals(r.objName("policy"))})
+	case PolicyDeny, PolicyL7:
+		vals := newVals(r.objName("policy"))
+		vals["cidrs"] = cidrs
+		vals["httpPaths"] = httpPaths
+		if kind == PolicyL7 {
+			// the path and the method of fortio requests (see HTTPConf)
+			vals["httpPaths"] = append(httpPaths, "/echo.*")
+			vals["httpMethod"] = "GET"
+			if cnf, ok := r.benchmark.(*HTTPConf); ok && cnf.PayloadSize > 0 {
+				vals["httpMethod"] = "POST"
+			}
+		}
+		docs = append(docs, policyDoc{ciliumPolicyTemplate, kube.KindCiliumNetworkPolicy, vals})
+	default:
+		vals := newVals(r.objName("policy"))
+		vals["cidrs"] = cidrs
+		vals["peers"] = peers
+		vals["portRanges"] = portRanges
+		docs = append(docs, policyDoc{policyTemplate, kube.KindNetworkPolicy, vals})
+	}
+
+	yaml := fmt.Sprintf("%s/%s-policy.yaml", r.getDir(), kind)
+	log.Printf("Generating %s", yaml)
+	f, err := os.Create(yaml)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	var objs []string
+	for i, doc := range docs {
+		if i > 0 {
+			if _, err := f.WriteString("---\n"); err != nil {
+				return "", nil, err
+			}
+		}
+		err = doc.tmpl.Execute(f, doc.vals)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate %s: %w", yaml, err)
+		}
+		objs = append(objs, fmt.Sprintf("%s/%s", doc.kind, doc.vals["policyName"]))
+	}
+	return yaml, objs, nil
+}
+
+// applyPolicy generates and applies the policies of the given kind and size
+// (see PolicyKinds), and records them in the run manifest
+func (r *RunBenchCtx) applyPolicy(kind string, size int) error {
+	yaml, objs, err := r.genPolicyYaml(kind, size)
+	if err != nil {
+		return err
+	}
+
+	if kind == PolicyDeny || kind == PolicyL7 {
+		r.cleanupKinds = append(r.cleanupKinds, kube.KindCiliumNetworkPolicy)
+	}
+
+	err = r.KubeApply(yaml)
+	if err != nil {
+		return fmt.Errorf("failed to apply policy: %w", err)
+	}
+	log.Printf("applied %s policy (size: %d): %d object(s)", kind, size, len(objs))
+
+	if m := r.manifest; m != nil {
+		m.Policy = &PolicyManifest{
+			Kind:    kind,
+			Size:    size,
+			File:    filepath.Base(yaml),
+			Objects: objs,
+		}
+		if err := r.writeManifest(); err != nil {
+			log.Printf("failed to write run manifest: %s", err)
+		}
+	}
+	return nil
+}
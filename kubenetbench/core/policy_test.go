@@ -0,0 +1,242 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+// cliSucceedHook is a pod hook for clients that terminate immediately
+func cliSucceedHook(pod *kube.Pod) string {
+	if pod.Labels["role"] == "cli" {
+		pod.Phase = "Succeeded"
+		return netperfRROutput
+	}
+	return ""
+}
+
+func TestPolicies(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+
+	tests := []struct {
+		kind     string
+		size     int
+		objects  int      // number of applied objects
+		expected []string // expected in the policy yaml (in addition to the netperf ports)
+		count    string   // expected size times in the policy yaml
+	}{
+		{kind: PolicyPort, objects: 1},
+		{kind: PolicyPolicies, size: 3, objects: 4, count: "kind: NetworkPolicy"},
+		{kind: PolicyCIDR, size: 3, objects: 1, expected: []string{"cidr: 198.18.0.2/32"}, count: "ipBlock:"},
+		{kind: PolicyLabels, size: 3, objects: 1, expected: []string{"key: " + policyPeerLabel}, count: "matchExpressions:"},
+		{kind: PolicyPortRanges, size: 3, objects: 1, expected: []string{"port: 40020", "endPort: 40029"}, count: "endPort:"},
+		{kind: PolicyDeny, size: 3, objects: 1, expected: []string{"kind: CiliumNetworkPolicy", "ingressDeny:", "- 198.18.0.1/32"}, count: "/32"},
+	}
+
+	for _, test := range tests {
+		st, fake := newTestPod2Pod(t, cliSucceedHook)
+		st.Policy = test.kind
+		st.PolicySize = test.size
+		if err := st.Execute(); err != nil {
+			t.Errorf("%s: %s", test.kind, err)
+			continue
+		}
+
+		r := st.RunBenchCtx
+		m, err := LoadRunManifest(r.getDir() + "/run.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := m.Policy
+		if p == nil || p.Kind != test.kind || p.Size != test.size || len(p.Objects) != test.objects {
+			t.Errorf("%s: unexpected policy manifest: %+v", test.kind, p)
+			continue
+		}
+		for _, obj := range p.Objects {
+			found := false
+			for _, applied := range fake.Applied {
+				found = found || applied == obj
+			}
+			if !found {
+				t.Errorf("%s: %s was not applied (%v)", test.kind, obj, fake.Applied)
+			}
+		}
+
+		data, err := ioutil.ReadFile(r.getDir() + "/" + p.File)
+		if err != nil {
+			t.Fatal(err)
+		}
+		policies := string(data)
+		for _, doc := range strings.Split(policies, "---\n") {
+			obj := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+				t.Errorf("%s: invalid policy yaml: %s:\n%s", test.kind, err, doc)
+			}
+		}
+		// the netperf control and data ports are allowed
+		for _, s := range append(test.expected, "12865", "8000") {
+			if !strings.Contains(policies, s) {
+				t.Errorf("%s: policies do not contain %q:\n%s", test.kind, s, policies)
+			}
+		}
+		if test.count != "" {
+			n := strings.Count(policies, test.count)
+			if test.kind == PolicyPolicies {
+				n--
+			}
+			if n != test.size {
+				t.Errorf("%s: got %d %q while expected %d:\n%s", test.kind, n, test.count, test.size, policies)
+			}
+		}
+
+		// the policies are deleted on cleanup
+		ctx := context.Background()
+		objs, err := fake.List(ctx, "", kube.KindNetworkPolicy, kube.KindCiliumNetworkPolicy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objs) != 0 {
+			t.Errorf("%s: policies were not deleted: %v", test.kind, objs)
+		}
+
+		ri, err := LoadRun(r.getDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key := ri.ConfigKey(); !strings.HasSuffix(key, fmt.Sprintf(" policy=%s/%d", test.kind, test.size)) {
+			t.Errorf("%s: unexpected config key: %s", test.kind, key)
+		}
+	}
+}
+
+func TestPolicyL7(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	st, _ := newTestPod2Pod(t, cliSucceedHook)
+
+	// L7 policies require the http benchmark
+	st.Policy = PolicyL7
+	st.PolicySize = 2
+	if err := st.Execute(); err == nil || !strings.Contains(err.Error(), "http benchmark") {
+		t.Errorf("expected error for l7 policy with netperf, got: %v", err)
+	}
+
+	// the policy is applied by an http run, and deleted on cleanup
+	st, fake := newTestPod2Pod(t, func(pod *kube.Pod) string {
+		if pod.Labels["role"] == "cli" {
+			pod.Phase = "Succeeded"
+			return fortioLoadOutput
+		}
+		return ""
+	})
+	r := st.RunBenchCtx
+	cnf := HTTPConfDefault()
+	cnf.Timeout = 0
+	st.RunBenchCtx = NewRunBenchCtx(r.session, "pod2pod", "l7", r.cliSpec, r.srvSpec, true, &cnf, false)
+	if err := st.RunBenchCtx.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+	st.Policy = PolicyL7
+	st.PolicySize = 2
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadRunManifest(st.RunBenchCtx.getDir() + "/run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := m.Policy
+	if p == nil || p.Kind != PolicyL7 || len(p.Objects) != 1 || !strings.HasPrefix(p.Objects[0], string(kube.KindCiliumNetworkPolicy)+"/") {
+		t.Fatalf("unexpected policy manifest: %+v", p)
+	}
+	found := false
+	for _, applied := range fake.Applied {
+		found = found || applied == p.Objects[0]
+	}
+	if !found {
+		t.Errorf("%s was not applied (%v)", p.Objects[0], fake.Applied)
+	}
+
+	data, err := ioutil.ReadFile(st.RunBenchCtx.getDir() + "/" + p.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`port: "8080"`, `path: "/knb-1"`, `path: "/echo.*"`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("policy does not contain %q:\n%s", s, data)
+		}
+	}
+	if n := strings.Count(string(data), "method: GET"); n != 3 {
+		t.Errorf("got %d HTTP rules while expected 3:\n%s", n, data)
+	}
+
+	objs, err := fake.List(context.Background(), "", kube.KindCiliumNetworkPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 0 {
+		t.Errorf("policies were not deleted: %v", objs)
+	}
+}
+
+func TestPolicyL7Payload(t *testing.T) {
+	st, _ := newTestPod2Pod(t, nil)
+	r := st.RunBenchCtx
+	// requests with a payload are POST requests
+	cnf := HTTPConfDefault()
+	cnf.PayloadSize = 1024
+	r = NewRunBenchCtx(r.session, "pod2pod", "l7-post", r.cliSpec, r.srvSpec, true, &cnf, false)
+	if err := r.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	yaml, _, err := r.genPolicyYaml(PolicyL7, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "method: POST"); n != 2 || strings.Contains(string(data), "method: GET") {
+		t.Errorf("expected 2 POST HTTP rules:\n%s", data)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	st, _ := newTestPod2Pod(t, nil)
+	r := st.RunBenchCtx
+	for _, test := range []struct {
+		kind string
+		size int
+	}{
+		{"foo", 0},
+		{PolicyCIDR, -1},
+		{PolicyPortRanges, 10000},
+	} {
+		if err := r.validatePolicy(test.kind, test.size); err == nil {
+			t.Errorf("%s/%d: expected error", test.kind, test.size)
+		}
+	}
+
+	// only the port policy allows clients on the host network
+	r.cliSpec.HostNetwork = true
+	if err := r.validatePolicy(PolicyPort, 0); err != nil {
+		t.Errorf("unexpected error for port policy with client on host: %s", err)
+	}
+	if err := r.validatePolicy(PolicyCIDR, 1); err == nil {
+		t.Errorf("expected error for cidr policy with client on host")
+	}
+	r.cliSpec.HostNetwork = false
+	r.srvSpec.HostNetwork = true
+	if err := r.validatePolicy(PolicyPort, 0); err == nil {
+		t.Errorf("expected error for policy with server on host")
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+// Common metric names. Benchmarks should use these names (where applicable)
+// so that results from different tools can be compared.
+const (
+	MetricThroughput  = "throughput"
+	MetricP50Latency  = "p50_latency"
+	MetricP90Latency  = "p90_latency"
+	MetricP99Latency  = "p99_latency"
+	MetricP999Latency = "p999_latency"
+	MetricMeanLatency = "mean_latency"
+)
+
+// Metric is a numeric benchmark result
+type Metric struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// ResultWarning is a non-fatal problem found while parsing benchmark output
+type ResultWarning struct {
+	Line    int    `json:"line,omitempty"` // line in the output (starting from 1), 0 if not applicable
+	Key     string `json:"key,omitempty"`  // field the warning refers to (if any)
+	Message string `json:"message"`
+}
+
+func (w ResultWarning) String() string {
+	ret := w.Message
+	if w.Key != "" {
+		ret = fmt.Sprintf("%s: %s", w.Key, ret)
+	}
+	if w.Line != 0 {
+		ret = fmt.Sprintf("line %d: %s", w.Line, ret)
+	}
+	return ret
+}
+
+// BenchResult is the structured result of a benchmark client
+type BenchResult struct {
+	Tool     string            `json:"tool"`
+	Test     string            `json:"test,omitempty"`
+	Metrics  map[string]Metric `json:"metrics"`
+	Info     map[string]string `json:"info,omitempty"`
+	Streams  []*BenchResult    `json:"streams,omitempty"` // per-stream results (e.g., when using multiple netperf streams)
+	Warnings []ResultWarning   `json:"warnings,omitempty"`
+}
+
+// NewBenchResult creates a new (empty) BenchResult
+func NewBenchResult(tool string, test string) *BenchResult {
+	return &BenchResult{
+		Tool:    tool,
+		Test:    test,
+		Metrics: make(map[string]Metric),
+		Info:    make(map[string]string),
+	}
+}
+
+func (res *BenchResult) addWarning(line int, key string, msg string) {
+	res.Warnings = append(res.Warnings, ResultWarning{
+		Line:    line,
+		Key:     key,
+		Message: msg,
+	})
+}
+
+// GetMetric returns the value of a metric, and whether it exists
+func (res *BenchResult) GetMetric(name string) (float64, bool) {
+	m, ok := res.Metrics[name]
+	return m.Value, ok
+}
+
+// LoadBenchResult loads a result from a (result.json) file
+func LoadBenchResult(fname string) (*BenchResult, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BenchResult{}
+	err = json.Unmarshal(data, res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", fname, err)
+	}
+	return res, nil
+}
+
+func writeJSONFile(fname string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, append(data, '\n'), 0644)
+}
+
+// saveResult parses the client log, and writes the result in result.json.
+// Parsing problems are reported as warnings in the result, and do not result
+// in an error.
+func (r *RunBenchCtx) saveResult(dir string) error {
+	cliLog := fmt.Sprintf("%s/cli.log", dir)
+	f, err := os.Open(cliLog)
+	if err != nil {
+		return fmt.Errorf("failed to open client log: %w", err)
+	}
+	defer f.Close()
+
+	res, err := r.benchmark.ParseCliOutput(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse client log %s: %w", cliLog, err)
+	}
+
+	for _, w := range res.Warnings {
+		log.Printf("%s: warning: %s", cliLog, w)
+	}
+
+	fname := fmt.Sprintf("%s/result.json", dir)
+	log.Printf("Writing %s", fname)
+	return writeJSONFile(fname, res)
+}
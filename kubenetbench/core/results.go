@@ -0,0 +1,332 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// RunInfo holds the configuration and the results of a benchmark run, as
+// found in a run directory.
+type RunInfo struct {
+	Dir         string       `json:"dir"`
+	RunID       string       `json:"runid"`
+	Label       string       `json:"label"`
+	Scenario    string       `json:"scenario"`
+	Benchmark   string       `json:"benchmark"`
+	Test        string       `json:"test"`
+	CliAffinity string       `json:"cli_affinity"`
+	SrvAffinity string       `json:"srv_affinity"`
+	CliHost     bool         `json:"cli_host"`
+	SrvHost     bool         `json:"srv_host"`
+	ServiceType string       `json:"service_type,omitempty"`
+	Outcome     string       `json:"outcome,omitempty"`
+	Manifest    *RunManifest `json:"manifest,omitempty"`
+	Result      *BenchResult `json:"result,omitempty"`
+	// results of each (non-warmup) iteration, for runs with repetitions
+	Iterations []*BenchResult `json:"iterations,omitempty"`
+}
+
+// run ids are: <label>-<YYYYmmddHHMMSS>
+var runIDRegEx = regexp.MustCompile(`^(.*)-(\d{14})$`)
+
+var (
+	yamlHostNetRegEx   = regexp.MustCompile(`(?m)^\s*hostNetwork: true\s*$`)
+	yamlNodeSelRegEx   = regexp.MustCompile(`kubernetes.io/hostname: (\S+)`)
+	yamlNetperfTyRegEx = regexp.MustCompile(`"-t", "([^"]+)", # testname`)
+)
+
+func readFileIfExists(fname string) (string, bool, error) {
+	data, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return string(data), true, nil
+}
+
+func (ri *RunInfo) loadFromManifest(m *RunManifest) {
+	ri.Manifest = m
+	ri.Label = m.Label
+	ri.Scenario = m.Scenario
+	ri.Benchmark = m.Benchmark.Name
+	ri.Test = m.Benchmark.Test
+	ri.CliAffinity = m.Client.Affinity
+	ri.SrvAffinity = m.Server.Affinity
+	ri.CliHost = m.Client.HostNetwork
+	ri.SrvHost = m.Server.HostNetwork
+	if m.Service != nil {
+		ri.ServiceType = m.Service.Type
+	}
+	ri.Outcome = m.Outcome
+}
+
+// loadFromYaml infers the run configuration from the generated YAML files.
+// It is used for runs that do not have a manifest.
+func (ri *RunInfo) loadFromYaml() error {
+	cliYaml, cliOk, err := readFileIfExists(filepath.Join(ri.Dir, "client.yaml"))
+	if err != nil {
+		return err
+	}
+	srvYaml, srvOk, err := readFileIfExists(filepath.Join(ri.Dir, "netserv.yaml"))
+	if err != nil {
+		return err
+	}
+
+	if srvOk {
+		if strings.Contains(srvYaml, "kind: Deployment") {
+			ri.Scenario = "service"
+		} else {
+			ri.Scenario = "pod2pod"
+		}
+		ri.SrvHost = yamlHostNetRegEx.MatchString(srvYaml)
+		ri.SrvAffinity = "none"
+		if m := yamlNodeSelRegEx.FindStringSubmatch(srvYaml); m != nil {
+			ri.SrvAffinity = "host=" + m[1]
+		}
+	}
+
+	if cliOk {
+		if m := yamlNetperfTyRegEx.FindStringSubmatch(cliYaml); m != nil {
+			ri.Benchmark = "netperf"
+			ri.Test = m[1]
+		}
+		ri.CliHost = yamlHostNetRegEx.MatchString(cliYaml)
+		switch {
+		case strings.Contains(cliYaml, "podAntiAffinity:"):
+			ri.CliAffinity = "different"
+		case strings.Contains(cliYaml, "podAffinity:"):
+			ri.CliAffinity = "same"
+		default:
+			ri.CliAffinity = "none"
+			if m := yamlNodeSelRegEx.FindStringSubmatch(cliYaml); m != nil {
+				ri.CliAffinity = "host=" + m[1]
+			}
+		}
+	}
+
+	if ri.Scenario == "pod2pod" {
+		ri.Scenario = HostScenario(ri.CliHost, ri.SrvHost)
+	}
+
+	return nil
+}
+
+// LoadRun loads the information of the run stored in dir.
+func LoadRun(dir string) (*RunInfo, error) {
+	runid := filepath.Base(dir)
+	ri := &RunInfo{
+		Dir:   dir,
+		RunID: runid,
+		Label: runid,
+	}
+	if m := runIDRegEx.FindStringSubmatch(runid); m != nil {
+		ri.Label = m[1]
+	}
+
+	manifestFname := filepath.Join(dir, "run.json")
+	if _, err := os.Stat(manifestFname); err == nil {
+		m, err := LoadRunManifest(manifestFname)
+		if err != nil {
+			return nil, err
+		}
+		ri.loadFromManifest(m)
+	} else {
+		err := ri.loadFromYaml()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resFname := filepath.Join(dir, "result.json")
+	if _, err := os.Stat(resFname); err == nil {
+		res, err := LoadBenchResult(resFname)
+		if err != nil {
+			return nil, err
+		}
+		ri.Result = res
+		if ri.Benchmark == "" {
+			ri.Benchmark = res.Tool
+		}
+		if ri.Test == "" {
+			ri.Test = res.Test
+		}
+	}
+
+	err := ri.loadIterations()
+	if err != nil {
+		return nil, err
+	}
+
+	return ri, nil
+}
+
+// loadIterations loads the results of the run iterations (if any)
+func (ri *RunInfo) loadIterations() error {
+	entries, err := ioutil.ReadDir(ri.Dir)
+	if err != nil {
+		return err
+	}
+
+	// NB: entries are sorted by name
+	for _, e := range entries {
+		if !e.IsDir() || !iterDirRegEx.MatchString(e.Name()) {
+			continue
+		}
+
+		resFname := filepath.Join(ri.Dir, e.Name(), "result.json")
+		if _, err := os.Stat(resFname); err != nil {
+			continue
+		}
+		res, err := LoadBenchResult(resFname)
+		if err != nil {
+			return err
+		}
+		ri.Iterations = append(ri.Iterations, res)
+	}
+
+	return nil
+}
+
+// isRunDir checks whether a directory is a run directory
+func isRunDir(dir string) bool {
+	for _, f := range []string{"run.json", "result.json", "client.yaml", "netserv.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadSessionRuns loads all runs from a session directory, ordered by their run id
+func LoadSessionRuns(sessDir string) ([]*RunInfo, error) {
+	entries, err := ioutil.ReadDir(sessDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*RunInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(sessDir, e.Name())
+		if !isRunDir(dir) {
+			continue
+		}
+
+		ri, err := LoadRun(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load run from %s: %w", dir, err)
+		}
+		ret = append(ret, ri)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].RunID < ret[j].RunID
+	})
+	return ret, nil
+}
+
+func (ri *RunInfo) metric(name string) (Metric, bool) {
+	if ri.Result == nil {
+		return Metric{}, false
+	}
+	m, ok := ri.Result.Metrics[name]
+	return m, ok
+}
+
+func fmtFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// results table columns (see runRow)
+var runColumns = []string{
+	"LABEL", "SCENARIO", "TYPE",
+	"CLI-AFFINITY", "SRV-AFFINITY", "CLI-HOST", "SRV-HOST",
+	"THROUGHPUT", "P50-LATENCY", "P90-LATENCY",
+}
+
+// runRow returns the values of a run for the results table. If withUnits
+// is true, units are appended to the values of the metrics.
+func (ri *RunInfo) runRow(withUnits bool) []string {
+	ret := []string{
+		ri.Label,
+		ri.Scenario,
+		ri.Test,
+		ri.CliAffinity,
+		ri.SrvAffinity,
+		strconv.FormatBool(ri.CliHost),
+		strconv.FormatBool(ri.SrvHost),
+	}
+
+	for _, name := range []string{MetricThroughput, MetricP50Latency, MetricP90Latency} {
+		m, ok := ri.metric(name)
+		switch {
+		case !ok:
+			ret = append(ret, "-")
+		case withUnits && m.Unit != "":
+			ret = append(ret, fmt.Sprintf("%s %s", fmtFloat(m.Value), m.Unit))
+		default:
+			ret = append(ret, fmtFloat(m.Value))
+		}
+	}
+
+	return ret
+}
+
+// WriteRunsTable writes a results table for the given runs
+func WriteRunsTable(w io.Writer, runs []*RunInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(runColumns, "\t"))
+	for _, ri := range runs {
+		fmt.Fprintln(tw, strings.Join(ri.runRow(true), "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteRunsCSV writes the results of the given runs in CSV format
+func WriteRunsCSV(w io.Writer, runs []*RunInfo) error {
+	cw := csv.NewWriter(w)
+	header := append([]string{"RUNID"}, runColumns...)
+	header = append(header, "THROUGHPUT-UNITS")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, ri := range runs {
+		tputUnits := ""
+		if m, ok := ri.metric(MetricThroughput); ok {
+			tputUnits = m.Unit
+		}
+		row := append([]string{ri.RunID}, ri.runRow(false)...)
+		row = append(row, tputUnits)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteRunsJSON writes the given runs (including all their metrics) in JSON format
+func WriteRunsJSON(w io.Writer, runs []*RunInfo) error {
+	if runs == nil {
+		runs = []*RunInfo{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(runs)
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+func TestLoadSessionRuns(t *testing.T) {
+	sessDir, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sessDir)
+
+	sess := &Session{id: "test", dir: sessDir, kube: kube.NewFakeClient()}
+	cliSpec := &ContainerSpec{Affinity: "same"}
+	srvSpec := &ContainerSpec{Affinity: "host=node1"}
+	srvSpec.SetHostAll()
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_crr", nil, nil)}
+	r := NewRunBenchCtx(sess, ScenarioPod2Node, "foo", cliSpec, srvSpec, true, cnf, false)
+	r.runid = "foo-20200826165847"
+	if err := r.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	st := Pod2PodSt{RunBenchCtx: r}
+	if _, err := st.genSrvYaml(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.genCliYaml(r.getDir(), "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(r.getDir()+"/cli.log", []byte(netperfRROutput), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.saveResult(r.getDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := LoadSessionRuns(sessDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("got %d runs while expected 1", len(runs))
+	}
+
+	ri := runs[0]
+	expected := []string{"foo", "pod2node", "tcp_crr", "same", "host=node1", "false", "true", "2841.07 Trans/s", "334 us", "391 us"}
+	row := ri.runRow(true)
+	if strings.Join(row, "|") != strings.Join(expected, "|") {
+		t.Errorf("got row %v while expected %v", row, expected)
+	}
+
+	if ri.Outcome != OutcomeRunning {
+		t.Errorf("got outcome %q while expected %q", ri.Outcome, OutcomeRunning)
+	}
+
+	r.finishManifest(fmt.Errorf("wait failed: %w", ErrClientTimeout))
+	ri, err = LoadRun(r.getDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ri.Outcome != OutcomeTimeout || ri.Manifest.EndTime == nil {
+		t.Errorf("unexpected outcome %q (end time: %v)", ri.Outcome, ri.Manifest.EndTime)
+	}
+
+	// runs without a manifest: configuration is inferred from YAML
+	if err := os.Remove(r.getManifestFname()); err != nil {
+		t.Fatal(err)
+	}
+	ri, err = LoadRun(r.getDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	row = ri.runRow(true)
+	if strings.Join(row, "|") != strings.Join(expected, "|") {
+		t.Errorf("got row %v while expected %v (no manifest)", row, expected)
+	}
+
+	var buff bytes.Buffer
+	if err := WriteRunsCSV(&buff, runs); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "foo-20200826165847,foo,pod2node,tcp_crr,") {
+		t.Errorf("unexpected csv output:\n%s", buff.String())
+	}
+}
@@ -1,12 +1,15 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"text/template"
 	"time"
 
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
 	"github.com/cilium/kubenetbench/utils"
 )
 
@@ -14,11 +17,11 @@ import (
 //
 // NB: for now, we just include host options.
 type ContainerSpec struct {
-	Affinity string
+	Affinity string `json:"affinity"`
 
-	HostNetwork bool
-	HostIPC     bool
-	HostPID     bool
+	HostNetwork bool `json:"host_network"`
+	HostIPC     bool `json:"host_ipc"`
+	HostPID     bool `json:"host_pid"`
 }
 
 func (s *ContainerSpec) SetHostAll() {
@@ -29,18 +32,36 @@ func (s *ContainerSpec) SetHostAll() {
 
 // RunBenchCtx is the context for a benchmark run
 type RunBenchCtx struct {
-	session      *Session       // session
-	runid        string         //
-	cliSpec      *ContainerSpec // client security context
-	srvSpec      *ContainerSpec // server security context
-	cleanup      bool           // perform cleanup: remove k8s entitites (pods, policies, etc.)
-	benchmark    Benchmark      // underlying benchmark interface
-	collectPerf  bool           // collect perf results
+	session      *Session        // session
+	scenario     string          // scenario (e.g., pod2pod)
+	label        string          // run label
+	runid        string          //
+	uid          string          // random id, used to make object names unique
+	kube         kube.Client     // client for the namespace of the run
+	cliSpec      *ContainerSpec  // client security context
+	srvSpec      *ContainerSpec  // server security context
+	cleanup      bool            // perform cleanup: remove k8s entitites (pods, policies, etc.)
+	benchmark    Benchmark       // underlying benchmark interface
+	collectPerf  bool            // collect perf results
+	collectors   []CollectorSpec // monitor collectors (in addition to perf-record, if collectPerf)
 	collectNodes []string
+	conntrack    bool            // take conntrack snapshots (see ConntrackSnapshots)
+	nodeMetrics  time.Duration   // node metrics sampling interval (0: disabled)
+	cleanupKinds []kube.Kind     // kinds of objects to delete on cleanup, in addition to the default ones
+	repeat       int             // number of client iterations (<=1: single client)
+	warmup       int             // number of warmup client iterations
+	clients      int             // number of concurrent client pods (<=1: single client pod)
+	clientJob    bool            // use a Job (instead of individual pods) for the clients
+	startDelay   time.Duration   // delay between releasing the start barrier and the start time
+	srvPorts     map[int]int     // ports that clients use for server ports, if different (e.g., node ports)
+	startTime    time.Time       // time the run context was created
+	manifest     *RunManifest    // run manifest (set by MakeDir)
+	interruptCtx context.Context // done when the run is interrupted
 }
 
 func NewRunBenchCtx(
 	sess *Session,
+	scenario string,
 	runLabel string,
 	cliSpec *ContainerSpec,
 	srvSpec *ContainerSpec,
@@ -48,17 +69,100 @@ func NewRunBenchCtx(
 	benchmark Benchmark,
 	collectPerf bool,
 ) *RunBenchCtx {
-	datestr := time.Now().Format("20060102150405")
+	now := time.Now()
+	datestr := now.Format("20060102150405")
 	runid := fmt.Sprintf("%s-%s", runLabel, datestr)
-	return &RunBenchCtx{
-		session:     sess,
-		runid:       runid,
-		cliSpec:     cliSpec,
-		srvSpec:     srvSpec,
-		cleanup:     cleanup,
-		benchmark:   benchmark,
-		collectPerf: collectPerf,
+	r := &RunBenchCtx{
+		session:      sess,
+		scenario:     scenario,
+		label:        runLabel,
+		runid:        runid,
+		uid:          newUID(),
+		kube:         sess.kube,
+		cliSpec:      cliSpec,
+		srvSpec:      srvSpec,
+		cleanup:      cleanup,
+		benchmark:    benchmark,
+		collectPerf:  collectPerf,
+		startTime:    now,
+		interruptCtx: context.Background(),
 	}
+	if sess.namespacePer == NamespacePerRun {
+		r.kube = sess.kube.WithNamespace(r.runNamespace())
+	}
+	return r
+}
+
+// SetInterruptContext sets a context that is done when the run should be
+// interrupted (e.g., because of a signal). An interrupted run stops waiting
+// for its pods, performs cleanup, and fails with ErrInterrupted.
+func (r *RunBenchCtx) SetInterruptContext(ctx context.Context) {
+	r.interruptCtx = ctx
+}
+
+// interrupted returns ErrInterrupted if the run was interrupted
+func (r *RunBenchCtx) interrupted() error {
+	if r.interruptCtx.Err() != nil {
+		return ErrInterrupted
+	}
+	return nil
+}
+
+// SetRepetitions configures the number of times the client is executed
+// (against the same server), and the number of warmup client executions
+// whose results are not taken into account. It needs to be called before
+// MakeDir().
+func (r *RunBenchCtx) SetRepetitions(repeat int, warmup int) {
+	r.repeat = repeat
+	r.warmup = warmup
+}
+
+// SetClients configures the number of client pods that are executed
+// concurrently (e.g., against the same server for a fan-in benchmark). If
+// useJob is true, the client pods are created by a Job with the given
+// parallelism. Client pods block at a start barrier until they are released
+// with a common start time, startDelay after the release (see
+// releaseBarrier). It needs to be called before MakeDir().
+func (r *RunBenchCtx) SetClients(clients int, useJob bool, startDelay time.Duration) {
+	r.clients = clients
+	r.clientJob = useJob
+	r.startDelay = startDelay
+}
+
+// SetConntrackSnapshots enables snapshots of the connection tracking counters
+// of every node (via the monitors) before and after the clients execute (see
+// ConntrackSnapshots). It needs to be called before MakeDir().
+func (r *RunBenchCtx) SetConntrackSnapshots(enable bool) {
+	r.conntrack = enable
+}
+
+// SetCollectors sets the collectors that the monitors of the nodes that host
+// the pods of the run execute while the clients execute (see CollectorSpec).
+// It needs to be called before MakeDir().
+func (r *RunBenchCtx) SetCollectors(collectors []CollectorSpec) {
+	r.collectors = collectors
+}
+
+// SetNodeMetrics enables streaming the metrics (cpu utilization, NIC and TCP
+// counters) of the nodes that host the pods of the run, sampled every
+// interval, while the clients execute (see NodeMetricsSample). An interval of
+// 0 disables it. It needs to be called before MakeDir().
+func (r *RunBenchCtx) SetNodeMetrics(interval time.Duration) {
+	r.nodeMetrics = interval
+}
+
+// numClients returns the number of client pods
+func (r *RunBenchCtx) numClients() int {
+	if r.clients < 1 {
+		return 1
+	}
+	return r.clients
+}
+
+// multiClient returns true if clients are executed using the multi-client
+// path (see genClientsYaml)
+func (r *RunBenchCtx) multiClient() bool {
+	return r.numClients() > 1 || r.clientJob
 }
 
 func (r *RunBenchCtx) getRunLabel(sep string) string {
@@ -69,15 +173,35 @@ func (r *RunBenchCtx) getDir() string {
 	return fmt.Sprintf("%s/%s", r.session.dir, r.runid)
 }
 
+// MakeDir creates the run directory and writes the run manifest
 func (r *RunBenchCtx) MakeDir() error {
+	err := r.validateAffinity()
+	if err != nil {
+		return err
+	}
+
+	err = r.validateClients()
+	if err != nil {
+		return err
+	}
+
 	d := r.getDir()
-	return os.Mkdir(d, 0755)
+	err = os.Mkdir(d, 0755)
+	if err != nil {
+		return err
+	}
+
+	r.manifest, err = r.newManifest()
+	if err != nil {
+		return fmt.Errorf("failed to create run manifest: %w", err)
+	}
+	return r.writeManifest()
 }
 
 var runctxCliTemplate = template.Must(template.New("cli").Parse(`apiVersion: v1
 kind: Pod
 metadata:
-  name: knb-cli
+  name: {{.cliName}}
   labels : {
      {{.runLabel}},
      role: cli,
@@ -86,12 +210,17 @@ spec:
   restartPolicy: Never
   {{.cliHost}}
   {{.cliAffinity}}
+  {{.cliBarrier}}
   containers:
   - {{.cliContainer}}
 `))
 
-func (r *RunBenchCtx) genCliYaml(serverIP string) (string, error) {
-	yaml := fmt.Sprintf("%s/client.yaml", r.getDir())
+func (r *RunBenchCtx) genCliYaml(dir string, serverIP string) (string, error) {
+	if serverIP == "" {
+		return "", errServerIPUndefined
+	}
+
+	yaml := fmt.Sprintf("%s/client.yaml", dir)
 	log.Printf("Generating %s", yaml)
 	f, err := os.Create(yaml)
 	if err != nil {
@@ -99,70 +228,190 @@ func (r *RunBenchCtx) genCliYaml(serverIP string) (string, error) {
 	}
 
 	vals := map[string]interface{}{
+		"cliName":      r.objName("cli"),
 		"runLabel":     r.getRunLabel(": "),
 		"serverIP":     serverIP,
+		"srvPorts":     r.srvPorts,
+		"barrier":      r.useBarrier(),
 		"cliContainer": "{{template \"netperfContainer\"}}",
 		"cliAffinity":  "{{template \"cliAffinity\"}}",
 		"cliHost":      "{{template \"cliHost\"}}",
+		"cliBarrier":   "{{template \"cliBarrier\"}}",
 	}
 
 	templates := map[string]utils.PrefixRenderer{
 		"netperfContainer": r.benchmark.WriteCliContainerYaml,
 		"cliAffinity":      r.cliAffinityWrite,
 		"cliHost":          r.cliSpec.hostOptsWrite,
+		"cliBarrier":       cliBarrierWrite,
 	}
 
-	utils.RenderTemplate(runctxCliTemplate, vals, templates, f)
+	err = utils.RenderTemplate(runctxCliTemplate, vals, templates, f)
 	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s: %w", yaml, err)
+	}
 	return yaml, nil
 }
 
-// NB: limitation: we assume that there is only a single client.
-func (r *RunBenchCtx) waitForClient() error {
-	cliSelector := fmt.Sprintf("%s,role=cli", r.getRunLabel("="))
-	for {
-		cliPhase, err := r.KubeGetPodPhase(cliSelector)
-		if err != nil {
-			return err
-		}
-		log.Printf("client phase: %s", cliPhase)
+// finalizeAndWait waits for the client(s) to finish. If the clients use a
+// start barrier, they are released after collection starts. Collection data
+// conntrack snapshots, and node metrics (if enabled) are stored in dir, using
+// id as the collection id.
+func (r *RunBenchCtx) finalizeAndWait(dir string, id string) error {
+	var pods []kube.Pod
+	var err error
+	if r.useBarrier() {
+		pods, err = r.waitForBarrier()
+	} else {
+		err = r.waitForClientStart()
+	}
+	if err != nil {
+		return err
+	}
 
-		if cliPhase == "Succeeded" {
-			return nil
+	if r.collecting() {
+		r.startCollection(id)
+	}
+
+	var ctBefore map[string]map[string]int64
+	if r.conntrack {
+		ctBefore = r.conntrackSnapshot()
+	}
+
+	var metrics *nodeMetricsStreams
+	if r.nodeMetrics > 0 {
+		metrics = r.startNodeMetrics(dir)
+	}
+
+	if r.useBarrier() {
+		var starts []ClientStart
+		starts, err = r.releaseBarrier(pods)
+		if err == nil {
+			r.recordClientStarts(starts, dir)
 		}
-		if cliPhase == "Failed" {
-			return fmt.Errorf("client execution failed")
+	}
+	if err == nil {
+		err = r.waitForClient()
+	}
+
+	if metrics != nil {
+		metrics.stop()
+	}
+
+	if r.collecting() {
+		r.endCollection(id, dir, errors.Is(err, ErrInterrupted))
+	}
+
+	if r.conntrack {
+		if err := saveConntrackSnapshots(dir, ctBefore, r.conntrackSnapshot()); err != nil {
+			log.Printf("failed to save conntrack snapshots: %s", err)
 		}
-		time.Sleep(10 * time.Second)
 	}
+
+	return err
 }
 
-func (r *RunBenchCtx) finalizeAndWait() error {
+func (r *RunBenchCtx) getCliSelector() string {
+	return fmt.Sprintf("%s,role=cli", r.getRunLabel("="))
+}
 
-	// Wait until things settle down.
-	// We might want something more precise here eventually
-	time.Sleep(time.Duration(5 * time.Second))
+// runClient executes the client(s) against serverIPs, and stores the client
+// files in dir. Client i uses server i (modulo the number of servers).
+func (r *RunBenchCtx) runClient(dir string, id string, serverIPs []string) error {
+	var cliYamlFname string
+	var err error
+	if r.multiClient() {
+		cliYamlFname, err = r.genClientsYaml(dir, serverIPs)
+	} else {
+		cliYamlFname, err = r.genCliYaml(dir, serverIPs[0])
+	}
+	if err != nil {
+		return err
+	}
 
-	// print pods
+	err = r.KubeApply(cliYamlFname)
+	if err != nil {
+		return fmt.Errorf("failed to initiate client: %w", err)
+	}
 
-	if r.collectPerf {
-		r.startCollection()
+	// attempt to save client logs and results
+	if r.multiClient() {
+		defer r.saveClientsLogsAndResults(dir)
+	} else {
+		defer r.saveCliLogsAndResult(r.getCliSelector(), dir)
 	}
 
-	// sleep the duration of the benchmark
-	time.Sleep(time.Duration(r.benchmark.GetTimeout()) * time.Second)
+	return r.finalizeAndWait(dir, id)
+}
 
-	// start wait loop
-	err := r.waitForClient()
+// runClients executes the benchmark client(s) against serverIPs.
+//
+// If repetitions are enabled, the client is executed multiple times against
+// the same server. Each iteration is stored in its own sub-directory (see
+// iterDirName), and the results of all (non-warmup) iterations are
+// aggregated.
+func (r *RunBenchCtx) runClients(serverIPs []string) error {
+	if r.repeat <= 1 && r.warmup == 0 {
+		return r.runClient(r.getDir(), r.runid, serverIPs)
+	}
 
-	if r.collectPerf {
-		r.endCollection()
+	repeat := r.repeat
+	if repeat < 1 {
+		repeat = 1
 	}
 
-	return err
+	var iterDirs []string
+	for i := 0; i < r.warmup+repeat; i++ {
+		warmup := i < r.warmup
+		var name string
+		if warmup {
+			name = iterDirName(true, i)
+		} else {
+			name = iterDirName(false, i-r.warmup)
+		}
+		dir := fmt.Sprintf("%s/%s", r.getDir(), name)
+		err := os.Mkdir(dir, 0755)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("client iteration %s", name)
+		err = r.runClient(dir, fmt.Sprintf("%s-%s", r.runid, name), serverIPs)
+		if err != nil {
+			return fmt.Errorf("client iteration %s failed: %w", name, err)
+		}
+
+		err = r.KubeDeleteClient()
+		if err != nil {
+			return fmt.Errorf("failed to delete client: %w", err)
+		}
+
+		if !warmup {
+			iterDirs = append(iterDirs, dir)
+		}
+	}
+
+	return r.saveAggregate(iterDirs)
+}
+
+func (c *RunBenchCtx) srvPodSpecWrite(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	if err := c.srvAffinityWrite(pw, params); err != nil {
+		return err
+	}
+	return c.srvSpec.hostOptsWrite(pw, params)
 }
 
-func (c *RunBenchCtx) srvPodSpecWrite(pw *utils.PrefixWriter, params map[string]interface{}) {
-	c.srvAffinityWrite(pw, params)
-	c.srvSpec.hostOptsWrite(pw, params)
+// saveCliLogsAndResult saves the client logs and the parsed results in dir
+func (r *RunBenchCtx) saveCliLogsAndResult(cliSelector string, dir string) {
+	err := r.KubeSaveLogs(cliSelector, fmt.Sprintf("%s/cli.log", dir))
+	if err != nil {
+		log.Printf("failed to save client logs: %s", err)
+		return
+	}
+
+	err = r.saveResult(dir)
+	if err != nil {
+		log.Printf("failed to save results: %s", err)
+	}
 }
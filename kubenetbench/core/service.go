@@ -4,27 +4,84 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"text/template"
 	"time"
 
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
 	"github.com/cilium/kubenetbench/utils"
 )
 
+// service types. Headless is a ClusterIP service without a cluster IP, which
+// clients resolve via DNS.
+const (
+	ServiceClusterIP    = "ClusterIP"
+	ServiceNodePort     = "NodePort"
+	ServiceLoadBalancer = "LoadBalancer"
+	ServiceHeadless     = "Headless"
+)
+
+// ServiceTypes are the supported service types
+var ServiceTypes = []string{ServiceClusterIP, ServiceNodePort, ServiceLoadBalancer, ServiceHeadless}
+
+// node port range of the API server (default)
+const (
+	nodePortMin = 30000
+	nodePortMax = 32767
+)
+
+// NB: this is a variable so that tests can modify it
+var (
+	// poll interval when waiting for the load balancer address
+	lbPollInterval = 2 * time.Second
+)
+
 // ServiceSt is the state for the service run
 type ServiceSt struct {
 	RunBenchCtx *RunBenchCtx
 	ServiceType string
+	// NodePortTarget is the node whose address clients use for NodePort
+	// services: "backend" (the node of the backend, default), "other" (a node
+	// other than the backend's), or host=XXXX
+	NodePortTarget string
+	// LoadBalancerIP is the address of LoadBalancer services (e.g., for
+	// MetalLB). If empty, the address allocated by the load balancer is used.
+	LoadBalancerIP string
+	// Replicas is the number of backends (<=1: single backend)
+	Replicas int
+	// SessionAffinity of the service (empty or None, ClientIP)
+	SessionAffinity string
+	// ExternalTrafficPolicy (NodePort and LoadBalancer services) and
+	// InternalTrafficPolicy of the service (empty: default, Cluster, Local)
+	ExternalTrafficPolicy string
+	InternalTrafficPolicy string
+}
+
+// ServiceManifest describes the service of a service run
+type ServiceManifest struct {
+	Type                  string `json:"type"`
+	NodePortTarget        string `json:"nodeport_target,omitempty"`
+	LoadBalancerIP        string `json:"lb_ip,omitempty"`
+	Replicas              int    `json:"replicas,omitempty"`
+	SessionAffinity       string `json:"session_affinity,omitempty"`
+	ExternalTrafficPolicy string `json:"external_traffic_policy,omitempty"`
+	InternalTrafficPolicy string `json:"internal_traffic_policy,omitempty"`
+	// address that clients use, and the ports they use for each server port
+	// (if different)
+	Address string      `json:"address,omitempty"`
+	Ports   map[int]int `json:"ports,omitempty"`
 }
 
 var serviceYamlTemplate = template.Must(template.New("service").Parse(`apiVersion: apps/v1
 kind: Deployment
 metadata:
-  name: knb-deployment
+  name: {{.deploymentName}}
   labels : {
     {{.runLabel}},
   }
 spec:
-  replicas: 1
+  replicas: {{.replicas}}
   selector:
     matchLabels:
       {{.runLabel}},
@@ -32,21 +89,24 @@ spec:
     metadata:
       labels : {
         {{.runLabel}},
+        role: srv,
       }
     spec:
       {{.srvSpec}}
       containers:
       - {{.srvContainer}}
+      {{.srvSidecar}}
 ---
 apiVersion: v1
 kind: Service
 metadata:
-  name: knb-service
+  name: {{.serviceName}}
   labels : {
     {{.runLabel}},
     role: srv,
   }
 spec:
+  {{.svcSpec}}
   selector:
     {{.runLabel}},
     role: srv
@@ -56,16 +116,25 @@ spec:
 
 func (s *ServiceSt) genSrvYaml() (string, error) {
 	vals := map[string]interface{}{
-		"runLabel":     s.RunBenchCtx.getRunLabel(": "),
-		"srvContainer": "{{template \"netperfContainer\"}}",
-		"srvPorts":     "{{template \"netperfPorts\"}}",
-		"srvSpec":      "{{template \"srvSpec\"}}",
+		"deploymentName": s.RunBenchCtx.objName("deployment"),
+		"serviceName":    s.RunBenchCtx.objName("service"),
+		"runLabel":       s.RunBenchCtx.getRunLabel(": "),
+		"clients":        s.RunBenchCtx.numClients(),
+		"serviceType":    s.ServiceType,
+		"replicas":       s.replicas(),
+		"srvContainer":   "{{template \"netperfContainer\"}}",
+		"srvPorts":       "{{template \"netperfPorts\"}}",
+		"srvSpec":        "{{template \"srvSpec\"}}",
+		"svcSpec":        "{{template \"svcSpec\"}}",
+		"srvSidecar":     "{{template \"srvSidecar\"}}",
 	}
 
 	templates := map[string]utils.PrefixRenderer{
 		"netperfContainer": s.RunBenchCtx.benchmark.WriteSrvContainerYaml,
 		"netperfPorts":     s.RunBenchCtx.benchmark.WriteSrvPortsYaml,
 		"srvSpec":          s.RunBenchCtx.srvPodSpecWrite,
+		"svcSpec":          s.svcSpecWrite,
+		"srvSidecar":       s.RunBenchCtx.tcpStatsWrite,
 	}
 
 	yaml := fmt.Sprintf("%s/netserv.yaml", s.RunBenchCtx.getDir())
@@ -74,61 +143,317 @@ func (s *ServiceSt) genSrvYaml() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	utils.RenderTemplate(serviceYamlTemplate, vals, templates, f)
+	err = utils.RenderTemplate(serviceYamlTemplate, vals, templates, f)
 	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s: %w", yaml, err)
+	}
 	return yaml, nil
 }
 
-func (s *ServiceSt) genCliYaml(serverIP string) (string, error) {
-	return s.RunBenchCtx.genCliYaml(serverIP)
+func (s *ServiceSt) replicas() int {
+	if s.Replicas < 1 {
+		return 1
+	}
+	return s.Replicas
+}
+
+// svcSpecWrite writes the configurable part of the service spec (type, session
+// affinity, and traffic policies)
+func (s *ServiceSt) svcSpecWrite(pw *utils.PrefixWriter, params map[string]interface{}) error {
+	switch s.ServiceType {
+	case ServiceHeadless:
+		pw.AppendNewLineOrDie(`clusterIP: None`)
+	case ServiceLoadBalancer:
+		pw.AppendNewLineOrDie(`type: LoadBalancer`)
+		if s.LoadBalancerIP != "" {
+			pw.AppendNewLineOrDie(fmt.Sprintf(`loadBalancerIP: %s`, s.LoadBalancerIP))
+		}
+	default:
+		pw.AppendNewLineOrDie(fmt.Sprintf(`type: %s`, s.ServiceType))
+	}
+
+	if s.SessionAffinity != "" {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`sessionAffinity: %s`, s.SessionAffinity))
+	}
+	if s.ExternalTrafficPolicy != "" {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`externalTrafficPolicy: %s`, s.ExternalTrafficPolicy))
+	}
+	if s.InternalTrafficPolicy != "" {
+		pw.AppendNewLineOrDie(fmt.Sprintf(`internalTrafficPolicy: %s`, s.InternalTrafficPolicy))
+	}
+	return nil
+}
+
+// NodePortPinner is implemented by benchmarks whose NodePort services use node
+// ports equal to their server ports (see pinNodePortWrite)
+type NodePortPinner interface {
+	// PinnedPorts returns the pinned ports for the given number of clients
+	PinnedPorts(clients int) []int
+}
+
+// Validate checks the service configuration. It does not need the run
+// directory, so it can be called before MakeDir.
+func (s *ServiceSt) Validate() error {
+	valid := false
+	for _, ty := range ServiceTypes {
+		valid = valid || ty == s.ServiceType
+	}
+	if !valid {
+		return fmt.Errorf("invalid service type: %s", s.ServiceType)
+	}
+
+	target := s.NodePortTarget
+	if target != "" && target != "backend" && target != "other" && !strings.HasPrefix(target, "host=") {
+		return fmt.Errorf("invalid node port target: %s", target)
+	}
+
+	switch s.SessionAffinity {
+	case "", "None":
+	case "ClientIP":
+		if s.ServiceType == ServiceHeadless {
+			return fmt.Errorf("session affinity is not supported for headless services")
+		}
+	default:
+		return fmt.Errorf("invalid session affinity: %s", s.SessionAffinity)
+	}
+
+	for _, policy := range []string{s.ExternalTrafficPolicy, s.InternalTrafficPolicy} {
+		if policy != "" && policy != "Cluster" && policy != "Local" {
+			return fmt.Errorf("invalid traffic policy: %s", policy)
+		}
+	}
+	if s.ExternalTrafficPolicy != "" && s.ServiceType != ServiceNodePort && s.ServiceType != ServiceLoadBalancer {
+		return fmt.Errorf("external traffic policy requires a NodePort or LoadBalancer service (type: %s)", s.ServiceType)
+	}
+
+	// NB: the control and data connections of netperf and iperf3 tests need
+	// to reach the same backend
+	if name := s.RunBenchCtx.benchmark.GetName(); s.replicas() > 1 && s.SessionAffinity != "ClientIP" && (name == "netperf" || name == "iperf3") {
+		return fmt.Errorf("multiple %s backends require ClientIP session affinity", name)
+	}
+
+	if pinner, ok := s.RunBenchCtx.benchmark.(NodePortPinner); ok && s.ServiceType == ServiceNodePort {
+		for _, port := range pinner.PinnedPorts(s.RunBenchCtx.numClients()) {
+			if port < nodePortMin || port > nodePortMax {
+				return fmt.Errorf("%s port %d is not in the node port range (%d-%d)", s.RunBenchCtx.benchmark.GetName(), port, nodePortMin, nodePortMax)
+			}
+		}
+	}
+	return nil
+}
+
+// isNodePort returns true if clients use the node ports of the service
+func isNodePort(params map[string]interface{}) bool {
+	ty, _ := params["serviceType"].(string)
+	return ty == ServiceNodePort
+}
+
+// pinNodePortWrite writes a node port equal to the (target) port for NodePort
+// services. It is used for benchmarks where the client and the server need to
+// use the same port number (e.g., because the client tells the server which
+// port to listen to). The benchmark needs to implement NodePortPinner, so that
+// the ports are validated (see Validate).
+func pinNodePortWrite(pw *utils.PrefixWriter, params map[string]interface{}, port int) error {
+	if !isNodePort(params) {
+		return nil
+	}
+	if port < nodePortMin || port > nodePortMax {
+		return fmt.Errorf("port %d is not in the node port range (%d-%d)", port, nodePortMin, nodePortMax)
+	}
+	pw.AppendNewLineOrDie(fmt.Sprintf(`  nodePort: %d`, port))
+	return nil
+}
+
+// clientPort returns the port that the client uses for the given server port
+// (e.g., the node port of a NodePort service)
+func clientPort(params map[string]interface{}, port int) int {
+	if ports, ok := params["srvPorts"].(map[int]int); ok {
+		if p, ok := ports[port]; ok {
+			return p
+		}
+	}
+	return port
+}
+
+// nodePortNode returns the node whose address clients use for a NodePort
+// service, given the node of the backend
+func (s *ServiceSt) nodePortNode(backendNode string) (string, error) {
+	switch target := s.NodePortTarget; {
+	case target == "" || target == "backend":
+		return backendNode, nil
+	case target == "other":
+		nodes, err := s.RunBenchCtx.session.KubeGetNodes()
+		if err != nil {
+			return "", err
+		}
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+		for i := range nodes {
+			if nodes[i].Name != backendNode {
+				return nodes[i].Name, nil
+			}
+		}
+		return "", fmt.Errorf("no node other than the node of the backend (%s)", backendNode)
+	case strings.HasPrefix(target, "host="):
+		return strings.TrimPrefix(target, "host="), nil
+	default:
+		return "", fmt.Errorf("invalid node port target: %s", target)
+	}
+}
+
+// waitForLoadBalancer waits until the load balancer of a service has an
+// address, and returns the service
+func (s *ServiceSt) waitForLoadBalancer(selector string) (*kube.Service, error) {
+	deadline := time.Now().Add(podStartTimeout)
+	for {
+		svc, err := s.RunBenchCtx.KubeGetService(selector)
+		if err != nil {
+			return nil, err
+		}
+		if len(svc.LoadBalancerIngress) > 0 {
+			return svc, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: waiting for load balancer address of service %s", ErrClientTimeout, svc.Name)
+		}
+		select {
+		case <-s.RunBenchCtx.interruptCtx.Done():
+			return nil, ErrInterrupted
+		case <-time.After(lbPollInterval):
+		}
+	}
+}
+
+// serverAddress returns the address that clients use to reach the backend via
+// the service, and the ports they use for each server port (if different)
+func (s *ServiceSt) serverAddress(selector string, backend *kube.Pod) (string, map[int]int, error) {
+	switch s.ServiceType {
+	case ServiceHeadless:
+		// NB: DNS returns the addresses of the backends
+		svc, err := s.RunBenchCtx.KubeGetService(selector)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s.%s.svc", svc.Name, s.RunBenchCtx.kube.Namespace()), nil, nil
+
+	case ServiceNodePort:
+		svc, err := s.RunBenchCtx.KubeGetService(selector)
+		if err != nil {
+			return "", nil, err
+		}
+		node, err := s.nodePortNode(backend.Node)
+		if err != nil {
+			return "", nil, err
+		}
+		ip, err := s.RunBenchCtx.session.KubeGetNodeIP(node)
+		if err != nil {
+			return "", nil, err
+		}
+		ports := make(map[int]int)
+		for _, p := range svc.Ports {
+			if p.NodePort == 0 || p.TargetPort == 0 {
+				return "", nil, fmt.Errorf("service %s: port %s has no node port or numeric target port", svc.Name, p.Name)
+			}
+			if np, ok := ports[p.TargetPort]; ok && np != p.NodePort {
+				return "", nil, fmt.Errorf("service %s: port %d has multiple node ports", svc.Name, p.TargetPort)
+			}
+			ports[p.TargetPort] = p.NodePort
+		}
+		log.Printf("using node %s (backend node: %s)", node, backend.Node)
+		return ip, ports, nil
+
+	case ServiceLoadBalancer:
+		addr := s.LoadBalancerIP
+		if addr == "" {
+			svc, err := s.waitForLoadBalancer(selector)
+			if err != nil {
+				return "", nil, err
+			}
+			addr = svc.LoadBalancerIngress[0]
+		}
+		return addr, nil, nil
+
+	default:
+		ip, err := s.RunBenchCtx.KubeGetServiceIP(selector)
+		return ip, nil, err
+	}
 }
 
 // Execute service run
-func (s ServiceSt) Execute() error {
-	// start server pod (netserver)
-	srvYamlFname, err := s.genSrvYaml()
+func (s ServiceSt) Execute() (err error) {
+	defer func() {
+		s.RunBenchCtx.finishManifest(err)
+	}()
+
+	err = s.Validate()
 	if err != nil {
 		return err
 	}
-	err = s.RunBenchCtx.KubeApply(srvYamlFname)
+
+	err = s.RunBenchCtx.KubeCreateNamespace()
 	if err != nil {
 		return err
 	}
 
 	srvSelector := fmt.Sprintf("%s,role=srv", s.RunBenchCtx.getRunLabel("="))
 
+	var tcpBefore map[string]int64
 	defer func() {
-		// attempt to save server logs
-		s.RunBenchCtx.KubeSaveLogs(srvSelector, fmt.Sprintf("%s/srv.log", s.RunBenchCtx.getDir()))
+		// attempt to save the logs of all backends
+		if err := s.RunBenchCtx.saveBackends(srvSelector, tcpBefore); err != nil {
+			log.Printf("failed to save backends: %s", err)
+		}
 
-		// FIXME: this does not work because we call functions that
-		// call log.Fatal() which calls exit() which does not run the
-		// deferred operations
-		s.RunBenchCtx.KubeCleanup()
+		// delete the objects of the run (on success, error, or interrupt)
+		if err := s.RunBenchCtx.KubeCleanup(); err != nil {
+			log.Printf("cleanup failed: %s", err)
+		}
 	}()
 
-	// get service IP
-	time.Sleep(2 * time.Second)
-	srvIP, err := s.RunBenchCtx.KubeGetServiceIP(srvSelector, 10, 2*time.Second)
+	// start server pod (netserver)
+	srvYamlFname, err := s.genSrvYaml()
+	if err != nil {
+		return err
+	}
+	err = s.RunBenchCtx.KubeApply(srvYamlFname)
 	if err != nil {
 		return err
 	}
-	log.Printf("server_ip=%s", srvIP)
 
-	// start netperf client (netperf)
-	cliYamlFname, err := s.genCliYaml(srvIP)
+	// wait for the backend pods to be ready
+	backends, err := s.RunBenchCtx.waitForServers(srvSelector, s.replicas())
 	if err != nil {
 		return err
 	}
+	tcpBefore = s.RunBenchCtx.backendsTCPAccepted(backends)
 
-	err = s.RunBenchCtx.KubeApply(cliYamlFname)
+	// get service address
+	// NB: for NodePort services, the first backend is used as the backend
+	// node (see nodePortNode)
+	srvIP, srvPorts, err := s.serverAddress(srvSelector, &backends[0])
 	if err != nil {
-		return fmt.Errorf("failed to initiate client: %w", err)
+		return err
 	}
+	s.RunBenchCtx.srvPorts = srvPorts
+	log.Printf("server_ip=%s (service type: %s)", srvIP, s.ServiceType)
 
-	cliSelector := fmt.Sprintf("%s,role=cli", s.RunBenchCtx.getRunLabel("="))
-	// attempt to save client logs
-	defer s.RunBenchCtx.KubeSaveLogs(cliSelector, fmt.Sprintf("%s/cli.log", s.RunBenchCtx.getDir()))
+	if m := s.RunBenchCtx.manifest; m != nil {
+		m.Service = &ServiceManifest{
+			Type:                  s.ServiceType,
+			NodePortTarget:        s.NodePortTarget,
+			LoadBalancerIP:        s.LoadBalancerIP,
+			Replicas:              s.replicas(),
+			SessionAffinity:       s.SessionAffinity,
+			ExternalTrafficPolicy: s.ExternalTrafficPolicy,
+			InternalTrafficPolicy: s.InternalTrafficPolicy,
+			Address:               srvIP,
+			Ports:                 srvPorts,
+		}
+		if err := s.RunBenchCtx.writeManifest(); err != nil {
+			log.Printf("failed to write run manifest: %s", err)
+		}
+	}
 
-	return s.RunBenchCtx.finalizeAndWait()
+	// start netperf client(s) (netperf)
+	return s.RunBenchCtx.runClients([]string{srvIP})
 }
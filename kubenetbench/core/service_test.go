@@ -0,0 +1,326 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+func newTestService(t *testing.T, st *ServiceSt, bench Benchmark, srvLogs string) *kube.FakeClient {
+	sessDir, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sessDir) })
+
+	fake := kube.NewFakeClient(
+		kube.Node{Name: "node1", Addresses: []string{"192.168.1.1"}},
+		kube.Node{Name: "node2", Addresses: []string{"192.168.1.2"}},
+	)
+	fake.PodHook = func(pod *kube.Pod) string {
+		if pod.Labels["role"] == "cli" {
+			pod.Phase = "Succeeded"
+			if _, ok := bench.(*IperfConf); ok {
+				return iperfTCPOutput
+			}
+			return netperfRROutput
+		}
+		return srvLogs
+	}
+
+	fakeTCPStats(t, fake)
+
+	sess := &Session{id: "test", dir: sessDir, kube: fake}
+	cliSpec := &ContainerSpec{Affinity: "different"}
+	srvSpec := &ContainerSpec{Affinity: "none"}
+	st.RunBenchCtx = NewRunBenchCtx(sess, "service", st.ServiceType, cliSpec, srvSpec, true, bench, false)
+	if err := st.RunBenchCtx.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+	return fake
+}
+
+// fakeTCPStats emulates the tcp-stats sidecars of backends: port-forwards
+// return the port of a listener that reports the number of accepted
+// connections of the pod. Every query is counted, and so are the two (control
+// and data) connections of a netperf client between queries.
+func fakeTCPStats(t *testing.T, fake *kube.FakeClient) {
+	var mu sync.Mutex
+	accepted := make(map[string]int64)
+	fake.PortForwardHook = func(podName string, port int) (int, error) {
+		if port != tcpStatsPort {
+			return 0, fmt.Errorf("unexpected port-forward to %s:%d", podName, port)
+		}
+		l, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			return 0, err
+		}
+		go func() {
+			defer l.Close()
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			mu.Lock()
+			accepted[podName]++
+			n := accepted[podName]
+			accepted[podName] += 2
+			mu.Unlock()
+			fmt.Fprintf(conn, "%d\n", n)
+		}()
+		return l.Addr().(*net.TCPAddr).Port, nil
+	}
+}
+
+func TestServiceTypes(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+
+	tests := []struct {
+		st       ServiceSt
+		dataPort uint16
+		fail     bool
+		srvYaml  []string // expected in the server yaml
+		cliYaml  []string // expected in the client yaml
+	}{
+		{
+			st:      ServiceSt{ServiceType: ServiceClusterIP},
+			srvYaml: []string{"type: ClusterIP", "role: srv"},
+			cliYaml: []string{`"-H", "10.0.`, `"-P", ",8000"`},
+		},
+		{
+			st:       ServiceSt{ServiceType: ServiceNodePort, NodePortTarget: "backend"},
+			dataPort: 31000,
+			srvYaml:  []string{"type: NodePort", "nodePort: 31000"},
+			cliYaml:  []string{`"-H", "192.168.1.1"`, `"-p", "30000", # control port`, `"-P", ",31000"`},
+		},
+		{
+			st:       ServiceSt{ServiceType: ServiceNodePort, NodePortTarget: "other"},
+			dataPort: 31000,
+			cliYaml:  []string{`"-H", "192.168.1.2"`},
+		},
+		{
+			// the data port is not in the node port range
+			st:   ServiceSt{ServiceType: ServiceNodePort},
+			fail: true,
+		},
+		{
+			st:      ServiceSt{ServiceType: ServiceLoadBalancer, LoadBalancerIP: "172.18.0.100"},
+			srvYaml: []string{"type: LoadBalancer", "loadBalancerIP: 172.18.0.100"},
+			cliYaml: []string{`"-H", "172.18.0.100"`},
+		},
+		{
+			st:      ServiceSt{ServiceType: ServiceLoadBalancer},
+			cliYaml: []string{`"-H", "10.0.`},
+		},
+		{
+			st:      ServiceSt{ServiceType: ServiceHeadless},
+			srvYaml: []string{"clusterIP: None"},
+			cliYaml: []string{`.default.svc",`},
+		},
+	}
+
+	for i := range tests {
+		test := &tests[i]
+		if test.dataPort == 0 {
+			test.dataPort = 8000
+		}
+		cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+		cnf.Timeout = 0
+		cnf.DataPort = test.dataPort
+		newTestService(t, &test.st, cnf, "")
+		err := test.st.Execute()
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s: expected error", test.st.ServiceType)
+			}
+			// the configuration is validated before generating yaml
+			if _, err := os.Stat(test.st.RunBenchCtx.getDir() + "/netserv.yaml"); !os.IsNotExist(err) {
+				t.Errorf("%s: server yaml generated for invalid configuration (err: %v)", test.st.ServiceType, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", test.st.ServiceType, err)
+			continue
+		}
+
+		dir := test.st.RunBenchCtx.getDir()
+		for fname, expected := range map[string][]string{"netserv.yaml": test.srvYaml, "client.yaml": test.cliYaml} {
+			data, err := ioutil.ReadFile(dir + "/" + fname)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range expected {
+				if !strings.Contains(string(data), s) {
+					t.Errorf("%s: %s does not contain %q:\n%s", test.st.ServiceType, fname, s, data)
+				}
+			}
+		}
+
+		ri, err := LoadRun(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ri.ServiceType != test.st.ServiceType || ri.Manifest.Service.Address == "" {
+			t.Errorf("%s: unexpected service in manifest: %+v", test.st.ServiceType, ri.Manifest.Service)
+		}
+		if key := ri.ConfigKey(); strings.Contains(key, "svc=") != (test.st.ServiceType != ServiceClusterIP) {
+			t.Errorf("%s: unexpected config key: %s", test.st.ServiceType, key)
+		}
+	}
+}
+
+func TestServiceValidate(t *testing.T) {
+	netperf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	iperfUDP := IperfConfDefault()
+	iperfUDP.UDP = true
+	tests := []struct {
+		st      ServiceSt
+		bench   Benchmark
+		clients int
+		ok      bool
+	}{
+		{ServiceSt{ServiceType: ServiceClusterIP}, netperf, 1, true},
+		{ServiceSt{ServiceType: "ExternalName"}, netperf, 1, false},
+		{ServiceSt{ServiceType: ServiceClusterIP, NodePortTarget: "foo"}, netperf, 1, false},
+		{ServiceSt{ServiceType: ServiceClusterIP, SessionAffinity: "foo"}, netperf, 1, false},
+		{ServiceSt{ServiceType: ServiceHeadless, SessionAffinity: "None"}, netperf, 1, true},
+		{ServiceSt{ServiceType: ServiceHeadless, SessionAffinity: "ClientIP"}, netperf, 1, false},
+		{ServiceSt{ServiceType: ServiceClusterIP, InternalTrafficPolicy: "foo"}, netperf, 1, false},
+		{ServiceSt{ServiceType: ServiceClusterIP, ExternalTrafficPolicy: "Local"}, netperf, 1, false},
+		// the default netperf data port (8000) is not in the node port range
+		{ServiceSt{ServiceType: ServiceNodePort}, netperf, 1, false},
+		{ServiceSt{ServiceType: ServiceNodePort}, &iperfUDP, 1, false},
+		{ServiceSt{ServiceType: ServiceLoadBalancer}, netperf, 1, true},
+		// the control and data connections need to reach the same backend
+		{ServiceSt{ServiceType: ServiceClusterIP, Replicas: 2}, netperf, 1, false},
+		{ServiceSt{ServiceType: ServiceClusterIP, Replicas: 2, SessionAffinity: "ClientIP"}, netperf, 1, true},
+	}
+	for i, tt := range tests {
+		tt.st.RunBenchCtx = &RunBenchCtx{benchmark: tt.bench, clients: tt.clients}
+		if err := tt.st.Validate(); (err == nil) != tt.ok {
+			t.Errorf("test %d (%+v): unexpected result: %v", i, tt.st, err)
+		}
+	}
+
+	// the data ports of all the netperf clients need to be in the range
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	cnf.DataPort = nodePortMax - 1
+	st := ServiceSt{ServiceType: ServiceNodePort, RunBenchCtx: &RunBenchCtx{benchmark: cnf, clients: 2}}
+	if err := st.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	st.RunBenchCtx.clients = 3
+	if err := st.Validate(); err == nil {
+		t.Errorf("expected error for data port %d", nodePortMax+1)
+	}
+}
+
+func TestServiceBackends(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+
+	// external traffic policy is not supported for ClusterIP services
+	st := ServiceSt{ServiceType: ServiceClusterIP, ExternalTrafficPolicy: "Local"}
+	newTestService(t, &st, &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}, "")
+	if err := st.Execute(); err == nil {
+		t.Errorf("expected error for external traffic policy")
+	}
+
+	srvLogs := "-----------------------------------------------------------\n" +
+		"Server listening on 5201\n" +
+		"Accepted connection from 10.0.0.9, port 41234\n"
+	cnf := IperfConfDefault()
+	cnf.Timeout = 0
+	st = ServiceSt{
+		ServiceType:           ServiceNodePort,
+		Replicas:              3,
+		SessionAffinity:       "ClientIP",
+		ExternalTrafficPolicy: "Local",
+	}
+	newTestService(t, &st, &cnf, srvLogs)
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := st.RunBenchCtx.getDir()
+	data, err := ioutil.ReadFile(dir + "/netserv.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"replicas: 3", "sessionAffinity: ClientIP", "externalTrafficPolicy: Local"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("server yaml does not contain %q", s)
+		}
+	}
+
+	var backends []BackendInfo
+	data, err = ioutil.ReadFile(dir + "/backends.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &backends); err != nil {
+		t.Fatal(err)
+	}
+	if len(backends) != 3 {
+		t.Fatalf("got %d backends while expected 3", len(backends))
+	}
+	for i, b := range backends {
+		if b.Log != backendLogName(i, 3) || b.Connections == nil || *b.Connections != 1 {
+			t.Errorf("unexpected backend info: %+v", b)
+		}
+		if _, err := os.Stat(dir + "/" + b.Log); err != nil {
+			t.Errorf("backend %s: %s", b.Pod, err)
+		}
+	}
+
+	ri, err := LoadRun(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := ri.ConfigKey(); !strings.HasSuffix(key, "svc=NodePort replicas=3 affinity=ClientIP etp=Local") {
+		t.Errorf("unexpected config key: %s", key)
+	}
+}
+
+func TestServiceTCPStats(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	cnf.Timeout = 0
+	st := ServiceSt{ServiceType: ServiceClusterIP}
+	newTestService(t, &st, cnf, "")
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := st.RunBenchCtx.getDir()
+	data, err := ioutil.ReadFile(dir + "/netserv.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "name: "+tcpStatsContainer) {
+		t.Errorf("server yaml does not contain the %s sidecar:\n%s", tcpStatsContainer, data)
+	}
+
+	var backends []BackendInfo
+	data, err = ioutil.ReadFile(dir + "/backends.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &backends); err != nil {
+		t.Fatal(err)
+	}
+	if len(backends) != 1 || backends[0].TCPAccepted == nil || *backends[0].TCPAccepted != 2 {
+		t.Errorf("unexpected backends: %+v", backends)
+	}
+}
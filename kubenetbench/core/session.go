@@ -5,31 +5,47 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
 )
 
 // SessionCtx is the context for a session run
 type Session struct {
 	id          string // id identifies the run
 	dir         string // directory to store results/etc.
-	portForward bool   // use kubectl port-forward to connect to the monitor
+	portForward bool   // use port-forward to connect to the monitor
+	kube        kube.Client
+	// namespace isolation mode (see NamespacePerSession and NamespacePerRun)
+	namespacePer string
 }
 
-// NewRunCtx creates a new RunCtx
+// NewSession returns the session sessId, creating it if it does not exist.
+// namespace is the namespace explicitly requested by the user (or empty), which
+// needs to match the namespace of an existing session.
 func NewSession(
 	sessId string,
 	sessDirBase string,
 	sessPortForward bool,
+	kubeCli kube.Client,
+	namespace string,
+	namespacePer string,
 ) (*Session, error) {
 
 	sess := &Session{
-		id:          sessId,
-		dir:         fmt.Sprintf("%s/%s", sessDirBase, sessId),
-		portForward: sessPortForward,
+		id:           sessId,
+		dir:          SessionDir(sessDirBase, sessId),
+		portForward:  sessPortForward,
+		kube:         kubeCli,
+		namespacePer: namespacePer,
 	}
 
 	info, err_stat := os.Stat(sess.dir)
 	if err_stat == nil && info.IsDir() {
 		// directory exists, good to go
+		err := sess.loadConf(namespace)
+		if err != nil {
+			return nil, err
+		}
 		return sess, nil
 	} else if os.IsNotExist(err_stat) {
 		// otherwise, create directory if it does not exist
@@ -37,10 +53,14 @@ func NewSession(
 		if err_mkdir != nil {
 			return nil, fmt.Errorf("failed to create directory %s: %w\n", sess.dir, err_mkdir)
 		}
+		err := sess.setupNamespace()
+		if err != nil {
+			return nil, err
+		}
 		sess.writeScript(sessId, sessDirBase)
 		return sess, nil
 	} else {
-		return nil, fmt.Errorf("failed to initialize session using directory %d", sess.dir)
+		return nil, fmt.Errorf("failed to initialize session using directory %s", sess.dir)
 	}
 }
 
@@ -48,12 +68,16 @@ func InitSession(
 	sessId string,
 	sessDirBase string,
 	sessPortForward bool,
+	kubeCli kube.Client,
+	namespacePer string,
 ) (*Session, error) {
 
 	sess := &Session{
-		id:          sessId,
-		dir:         fmt.Sprintf("%s/%s", sessDirBase, sessId),
-		portForward: sessPortForward,
+		id:           sessId,
+		dir:          SessionDir(sessDirBase, sessId),
+		portForward:  sessPortForward,
+		kube:         kubeCli,
+		namespacePer: namespacePer,
 	}
 
 	info, err_stat := os.Stat(sess.dir)
@@ -65,6 +89,10 @@ func InitSession(
 		if err_mkdir != nil {
 			return nil, fmt.Errorf("failed to create directory %s: %w\n", sess.dir, err_mkdir)
 		}
+		err := sess.setupNamespace()
+		if err != nil {
+			return nil, err
+		}
 		sess.writeScript(sessId, sessDirBase)
 		return sess, nil
 	} else {
@@ -72,6 +100,11 @@ func InitSession(
 	}
 }
 
+// SessionDir returns the directory of a session
+func SessionDir(sessDirBase string, sessId string) string {
+	return fmt.Sprintf("%s/%s", sessDirBase, sessId)
+}
+
 func (s *Session) getSessionLabel(sep string) string {
 	return fmt.Sprintf("%s%s%s", sessIdLabel, sep, s.id)
 }
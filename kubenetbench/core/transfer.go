@@ -0,0 +1,164 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+var (
+	// number of attempts to receive a file from a monitor
+	fileTransferAttempts = 5
+	// time to wait before resuming a broken transfer
+	fileTransferRetryDelay = 4 * time.Second
+)
+
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+type FileReceiver interface {
+	Recv() (*pb.File, error)
+}
+
+// partialFile returns the name of the temporary file of a transfer to fname
+func partialFile(fname string) string {
+	return fname + ".part"
+}
+
+// resumeOffset returns the offset to resume the transfer of fname from, i.e.,
+// the size of its temporary file (or 0)
+func resumeOffset(fname string) int64 {
+	info, err := os.Stat(partialFile(fname))
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// copyStreamToFile receives a file from stream, starting at offset, in a
+// temporary file that is renamed to fname after its checksum is verified. If
+// the stream breaks, the temporary file is kept so that the transfer can be
+// resumed (see resumeOffset).
+func copyStreamToFile(fname string, offset int64, stream FileReceiver) error {
+	tmp := partialFile(fname)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	var meta *pb.File
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("io error: %w", err)
+		}
+
+		if meta == nil {
+			meta = chunk
+		} else if chunk.Size != meta.Size || chunk.Sha256 != meta.Sha256 {
+			return fmt.Errorf("metadata of %s changed during transfer (size: %d->%d)", meta.Name, meta.Size, chunk.Size)
+		}
+		if chunk.Offset != offset {
+			return fmt.Errorf("unexpected offset %d of %s (expected: %d)", chunk.Offset, chunk.Name, offset)
+		}
+
+		_, err = f.Write(chunk.Data)
+		if err != nil {
+			return fmt.Errorf("Error writing data: %w", err)
+		}
+		offset += int64(len(chunk.Data))
+	}
+
+	if meta == nil {
+		return fmt.Errorf("io error: no data received: %w", io.ErrUnexpectedEOF)
+	}
+	if offset != meta.Size {
+		return fmt.Errorf("io error: received %d out of %d bytes of %s: %w", offset, meta.Size, meta.Name, io.ErrUnexpectedEOF)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	sum, err := fileSHA256(tmp)
+	if err != nil {
+		return err
+	}
+	if sum != meta.Sha256 {
+		// the received data is not usable, so the transfer needs to start over
+		os.Remove(tmp)
+		return fmt.Errorf("%s: %w (sha256: %s, expected: %s)", meta.Name, errChecksumMismatch, sum, meta.Sha256)
+	}
+	return os.Rename(tmp, fname)
+}
+
+func fileSHA256(fname string) (string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// grpcCode returns the gRPC code of (a possibly wrapped) err
+func grpcCode(err error) codes.Code {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &se) {
+		return se.GRPCStatus().Code()
+	}
+	return status.Code(err)
+}
+
+// receiveFile receives fname using transfer, which starts a transfer from
+// the given offset (e.g., using copyStreamToFile). Broken transfers (e.g., due
+// to a broken port-forward) are resumed, up to fileTransferAttempts times.
+func receiveFile(fname string, transfer func(offset int64) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		offset := resumeOffset(fname)
+		err = transfer(offset)
+		if err == nil {
+			return nil
+		}
+
+		switch grpcCode(err) {
+		case codes.NotFound, codes.Aborted, codes.InvalidArgument, codes.FailedPrecondition:
+			os.Remove(partialFile(fname))
+			return err
+		case codes.OutOfRange:
+			// the partial file does not match the file of the monitor
+			os.Remove(partialFile(fname))
+		}
+
+		if attempt >= fileTransferAttempts {
+			break
+		}
+		log.Printf("transfer of %s failed at offset %d (attempt %d/%d): %s", fname, offset, attempt, fileTransferAttempts, err)
+		time.Sleep(fileTransferRetryDelay)
+	}
+	return fmt.Errorf("transfer of %s failed after %d attempts: %w", fname, fileTransferAttempts, err)
+}
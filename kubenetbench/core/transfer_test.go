@@ -0,0 +1,185 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/cilium/kubenetbench/benchmonitor/api"
+)
+
+// fakeFileStream returns its chunks, and then err (or io.EOF)
+type fakeFileStream struct {
+	chunks []*pb.File
+	err    error
+}
+
+func (s *fakeFileStream) Recv() (*pb.File, error) {
+	if len(s.chunks) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	ret := s.chunks[0]
+	s.chunks = s.chunks[1:]
+	return ret, nil
+}
+
+// fileChunks splits data in chunks of size bytes, starting at offset
+func fileChunks(data []byte, offset int, size int) []*pb.File {
+	sum := sha256.Sum256(data)
+	var ret []*pb.File
+	for {
+		end := offset + size
+		if end > len(data) {
+			end = len(data)
+		}
+		ret = append(ret, &pb.File{
+			Data:   data[offset:end],
+			Name:   "test.tar.gz",
+			Size:   int64(len(data)),
+			Offset: int64(offset),
+			Sha256: hex.EncodeToString(sum[:]),
+		})
+		offset = end
+		if offset == len(data) {
+			return ret
+		}
+	}
+}
+
+func checkFile(t *testing.T, fname string, data []byte) {
+	t.Helper()
+	got, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("unexpected data in %s: %q", fname, got)
+	}
+	if _, err := os.Stat(partialFile(fname)); !os.IsNotExist(err) {
+		t.Errorf("partial file of %s exists (err: %v)", fname, err)
+	}
+}
+
+func TestCopyStreamToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "knb-transfer-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "test.tar.gz")
+	data := []byte("0123456789abcdefghij")
+
+	// existing files are replaced
+	if err := ioutil.WriteFile(fname, []byte("old data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyStreamToFile(fname, 0, &fakeFileStream{chunks: fileChunks(data, 0, 8)}); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, fname, data)
+
+	// broken transfers are resumed
+	os.Remove(fname)
+	broken := &fakeFileStream{
+		chunks: fileChunks(data, 0, 8)[:2],
+		err:    status.Error(codes.Unavailable, "connection closed"),
+	}
+	err = copyStreamToFile(fname, 0, broken)
+	if grpcCode(err) != codes.Unavailable {
+		t.Fatalf("unexpected error of broken transfer: %v", err)
+	}
+	if _, err := os.Stat(fname); !os.IsNotExist(err) {
+		t.Fatalf("file of broken transfer exists (err: %v)", err)
+	}
+	if off := resumeOffset(fname); off != 16 {
+		t.Fatalf("unexpected resume offset: %d", off)
+	}
+	if err := copyStreamToFile(fname, 16, &fakeFileStream{chunks: fileChunks(data, 16, 8)}); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, fname, data)
+
+	// truncated streams are errors
+	os.Remove(fname)
+	err = copyStreamToFile(fname, 0, &fakeFileStream{chunks: fileChunks(data, 0, 8)[:1]})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("unexpected error of truncated stream: %v", err)
+	}
+
+	// corrupted data are discarded
+	chunks := fileChunks(data, 0, 8)
+	chunks[1].Data = []byte("XXXXXXXX")
+	err = copyStreamToFile(fname, 0, &fakeFileStream{chunks: chunks})
+	if !errors.Is(err, errChecksumMismatch) {
+		t.Errorf("unexpected error of corrupted transfer: %v", err)
+	}
+	if off := resumeOffset(fname); off != 0 {
+		t.Errorf("unexpected resume offset after corrupted transfer: %d", off)
+	}
+	if _, err := os.Stat(fname); !os.IsNotExist(err) {
+		t.Errorf("file of corrupted transfer exists (err: %v)", err)
+	}
+}
+
+func TestReceiveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "knb-transfer-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "test.tar.gz")
+	data := []byte("0123456789abcdefghij")
+	origDelay := fileTransferRetryDelay
+	fileTransferRetryDelay = 0
+	defer func() { fileTransferRetryDelay = origDelay }()
+
+	// every transfer breaks after a chunk, until the last one
+	var offsets []int64
+	err = receiveFile(fname, func(offset int64) error {
+		offsets = append(offsets, offset)
+		stream := &fakeFileStream{chunks: fileChunks(data, int(offset), 8)}
+		if len(stream.chunks) > 1 {
+			stream.chunks = stream.chunks[:1]
+			stream.err = status.Error(codes.Unavailable, "connection closed")
+		}
+		return copyStreamToFile(fname, offset, stream)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFile(t, fname, data)
+	if len(offsets) != 3 || offsets[0] != 0 || offsets[1] != 8 || offsets[2] != 16 {
+		t.Errorf("unexpected transfer offsets: %v", offsets)
+	}
+
+	// transfers are not retried for collections that do not exist (anymore)
+	attempts := 0
+	err = receiveFile(fname, func(offset int64) error {
+		attempts++
+		return status.Error(codes.NotFound, "invalid collection id")
+	})
+	if grpcCode(err) != codes.NotFound || attempts != 1 {
+		t.Errorf("unexpected error: %v (attempts: %d)", err, attempts)
+	}
+
+	// ... and are retried at most fileTransferAttempts times
+	attempts = 0
+	err = receiveFile(fname, func(offset int64) error {
+		attempts++
+		return status.Error(codes.Unavailable, "connection refused")
+	})
+	if err == nil || attempts != fileTransferAttempts {
+		t.Errorf("unexpected error: %v (attempts: %d)", err, attempts)
+	}
+}
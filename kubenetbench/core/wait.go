@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+// NB: these are variables so that tests can modify them
+var (
+	// time for a pod to start (this includes scheduling and pulling images)
+	podStartTimeout = 3 * time.Minute
+	// time, in addition to the benchmark duration, for the client to terminate
+	clientExitSlack = time.Minute
+)
+
+// container waiting reasons that indicate that the container will not start
+// without intervention
+var podFailedWaitingReasons = map[string]struct{}{
+	"ErrImagePull":               {},
+	"ImagePullBackOff":           {},
+	"InvalidImageName":           {},
+	"CrashLoopBackOff":           {},
+	"CreateContainerConfigError": {},
+	"CreateContainerError":       {},
+}
+
+// podFailure returns an error if the pod has failed, or if it is not going to
+// make progress (e.g., it cannot be scheduled or its image cannot be pulled).
+func podFailure(pod *kube.Pod) error {
+	if pod.Unschedulable != "" {
+		return fmt.Errorf("pod %s is unschedulable: %s", pod.Name, pod.Unschedulable)
+	}
+
+	containers := append(append([]kube.ContainerStatus(nil), pod.InitContainers...), pod.Containers...)
+	for _, cs := range containers {
+		if _, ok := podFailedWaitingReasons[cs.Waiting]; ok {
+			return fmt.Errorf("pod %s: container %s: %s: %s", pod.Name, cs.Name, cs.Waiting, cs.Message)
+		}
+		if cs.Terminated == "OOMKilled" {
+			return fmt.Errorf("pod %s: container %s: OOMKilled", pod.Name, cs.Name)
+		}
+	}
+
+	if pod.Phase == "Failed" {
+		for _, cs := range containers {
+			if cs.Terminated != "" {
+				return fmt.Errorf("pod %s failed: container %s: %s (exit code: %d)", pod.Name, cs.Name, cs.Terminated, cs.ExitCode)
+			}
+		}
+		return fmt.Errorf("pod %s failed", pod.Name)
+	}
+
+	return nil
+}
+
+// errWaitTimeout is returned by waitForPods when the deadline is exceeded
+type errWaitTimeout struct {
+	what    string
+	timeout time.Duration
+	state   string
+}
+
+func (e *errWaitTimeout) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s (%s)", e.timeout, e.what, e.state)
+}
+
+func podState(pod *kube.Pod) string {
+	ret := fmt.Sprintf("pod %s: phase: %s", pod.Name, pod.Phase)
+	for _, cs := range pod.Containers {
+		if cs.Waiting != "" {
+			ret = fmt.Sprintf("%s, container %s: %s", ret, cs.Name, cs.Waiting)
+		}
+	}
+	return ret
+}
+
+// waitForPods waits until n pods that match the selector satisfy cond, and
+// returns them (sorted by name). It fails immediately if any of the pods fails
+// (see podFailure), and if the pods do not satisfy cond within timeout.
+func (r *RunBenchCtx) waitForPods(
+	selector string,
+	what string,
+	n int,
+	timeout time.Duration,
+	cond func(pod *kube.Pod) bool,
+) ([]kube.Pod, error) {
+	ctx, cancel := context.WithTimeout(r.interruptCtx, timeout)
+	defer cancel()
+
+	log.Printf("waiting for %s (selector: %s, pods: %d, timeout: %s)", what, selector, n, timeout)
+	events, err := r.kube.WatchPods(ctx, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch pods (selector: %s): %w", selector, err)
+	}
+
+	// pods that satisfy cond
+	done := make(map[string]kube.Pod, n)
+	state := "no pods"
+	for ev := range events {
+		pod := ev.Pod
+		if ev.Type == kube.PodDeleted {
+			delete(done, pod.Name)
+			continue
+		}
+
+		state = podState(&pod)
+		if err := podFailure(&pod); err != nil {
+			return nil, err
+		}
+		if !cond(&pod) {
+			delete(done, pod.Name)
+			continue
+		}
+
+		done[pod.Name] = pod
+		if len(done) < n {
+			continue
+		}
+		ret := make([]kube.Pod, 0, len(done))
+		for _, p := range done {
+			ret = append(ret, p)
+		}
+		sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+		if n == 1 {
+			log.Printf("%s: %s", what, state)
+		} else {
+			log.Printf("%s: %d pods", what, len(ret))
+		}
+		return ret, nil
+	}
+
+	if err := r.interrupted(); err != nil {
+		return nil, err
+	}
+	if n > 1 {
+		state = fmt.Sprintf("%d/%d pods, last: %s", len(done), n, state)
+	}
+	return nil, &errWaitTimeout{what: what, timeout: timeout, state: state}
+}
+
+// waitForPod waits until a pod that matches the selector satisfies cond (see
+// waitForPods)
+func (r *RunBenchCtx) waitForPod(
+	selector string,
+	what string,
+	timeout time.Duration,
+	cond func(pod *kube.Pod) bool,
+) (*kube.Pod, error) {
+	pods, err := r.waitForPods(selector, what, 1, timeout, cond)
+	if err != nil {
+		return nil, err
+	}
+	return &pods[0], nil
+}
+
+// waitForServer waits until the server pod(s) matching the selector are
+// ready and have an IP
+func (r *RunBenchCtx) waitForServer(selector string) (*kube.Pod, error) {
+	return r.waitForPod(selector, "server ready", podStartTimeout, func(pod *kube.Pod) bool {
+		return pod.Ready && pod.IP != ""
+	})
+}
+
+// waitForServers waits until n server pods matching the selector are ready
+// and have an IP
+func (r *RunBenchCtx) waitForServers(selector string, n int) ([]kube.Pod, error) {
+	return r.waitForPods(selector, "servers ready", n, podStartTimeout, func(pod *kube.Pod) bool {
+		return pod.Ready && pod.IP != ""
+	})
+}
+
+func podTerminated(pod *kube.Pod) bool {
+	return pod.Phase == "Succeeded" || pod.Phase == "Failed"
+}
+
+// waitForClientStart waits until all the clients are running (or have
+// already terminated)
+func (r *RunBenchCtx) waitForClientStart() error {
+	_, err := r.waitForPods(r.getCliSelector(), "client running", r.numClients(), podStartTimeout, func(pod *kube.Pod) bool {
+		return pod.Phase == "Running" || podTerminated(pod)
+	})
+	if err != nil {
+		return clientError(err)
+	}
+	return nil
+}
+
+// waitForClient waits until all the clients terminate. The deadline is the
+// benchmark duration plus clientExitSlack.
+func (r *RunBenchCtx) waitForClient() error {
+	timeout := time.Duration(r.benchmark.GetTimeout())*time.Second + clientExitSlack
+	_, err := r.waitForPods(r.getCliSelector(), "client terminated", r.numClients(), timeout, podTerminated)
+	if err != nil {
+		return clientError(err)
+	}
+	return nil
+}
+
+// clientError maps errors of waiting for the client to ErrClientTimeout and
+// ErrClientFailed
+func clientError(err error) error {
+	if errors.Is(err, ErrInterrupted) {
+		return err
+	}
+	if _, ok := err.(*errWaitTimeout); ok {
+		return fmt.Errorf("%w: %s", ErrClientTimeout, err)
+	}
+	return fmt.Errorf("%w: %s", ErrClientFailed, err)
+}
+
+// waitForPodsDeleted waits until the given pods are deleted, based on the
+// events of a watch that was started before the pods were deleted
+func waitForPodsDeleted(events <-chan kube.PodEvent, pods []kube.Pod) error {
+	remaining := make(map[string]struct{}, len(pods))
+	for _, pod := range pods {
+		remaining[pod.Name] = struct{}{}
+	}
+
+	for len(remaining) > 0 {
+		ev, ok := <-events
+		if !ok {
+			return fmt.Errorf("timed out waiting for pods to be deleted (remaining: %d)", len(remaining))
+		}
+		if ev.Type == kube.PodDeleted {
+			delete(remaining, ev.Pod.Name)
+		}
+	}
+	return nil
+}
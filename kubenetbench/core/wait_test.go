@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium/kubenetbench/kubenetbench/kube"
+)
+
+// newTestPod2Pod creates a pod2pod run that uses a fake client
+func newTestPod2Pod(t *testing.T, hook kube.FakePodHook) (Pod2PodSt, *kube.FakeClient) {
+	sessDir, err := ioutil.TempDir("", "knb-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sessDir) })
+
+	fake := kube.NewFakeClient(kube.Node{Name: "node1", Addresses: []string{"192.168.1.1"}})
+	fake.PodHook = hook
+
+	sess := &Session{id: "test", dir: sessDir, kube: fake}
+	cnf := &NetperfRRConf{NetperfConfDefault("tcp_rr", nil, nil)}
+	cnf.Timeout = 0
+	cliSpec := &ContainerSpec{Affinity: "different"}
+	srvSpec := &ContainerSpec{Affinity: "none"}
+	r := NewRunBenchCtx(sess, "pod2pod", "foo", cliSpec, srvSpec, true, cnf, false)
+	if err := r.MakeDir(); err != nil {
+		t.Fatal(err)
+	}
+	return Pod2PodSt{RunBenchCtx: r}, fake
+}
+
+func setTestTimeouts(t *testing.T, start, slack time.Duration) {
+	origStart, origSlack := podStartTimeout, clientExitSlack
+	podStartTimeout, clientExitSlack = start, slack
+	t.Cleanup(func() {
+		podStartTimeout, clientExitSlack = origStart, origSlack
+	})
+}
+
+func TestExecuteSuccess(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 5*time.Second)
+	st, fake := newTestPod2Pod(t, func(pod *kube.Pod) string {
+		if pod.Labels["role"] == "cli" {
+			// client starts as pending, and terminates later
+			pod.Phase = "Pending"
+			pod.Ready = false
+			return netperfRROutput
+		}
+		return ""
+	})
+
+	cliSelector := st.RunBenchCtx.getCliSelector()
+	go func() {
+		for _, phase := range []string{"Running", "Succeeded"} {
+			time.Sleep(50 * time.Millisecond)
+			fake.SetPodPhase(cliSelector, phase)
+		}
+	}()
+
+	if err := st.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadRunManifest(st.RunBenchCtx.getDir() + "/run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Outcome != OutcomeSuccess {
+		t.Errorf("got outcome %s while expected %s", m.Outcome, OutcomeSuccess)
+	}
+	if _, err := LoadBenchResult(st.RunBenchCtx.getDir() + "/result.json"); err != nil {
+		t.Errorf("failed to load result: %s", err)
+	}
+
+	pods, err := st.RunBenchCtx.KubeGetPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 0 {
+		t.Errorf("got %d pods after run while expected 0", len(pods))
+	}
+}
+
+func TestExecuteEarlyFailure(t *testing.T) {
+	// timeouts are large, so that the test fails (with a timeout) if early
+	// failures are not detected
+	setTestTimeouts(t, time.Minute, time.Minute)
+
+	tests := []struct {
+		name    string
+		hook    kube.FakePodHook
+		outcome string
+		errStr  string
+	}{
+		{
+			name: "server image pull",
+			hook: func(pod *kube.Pod) string {
+				if pod.Labels["role"] == "srv" {
+					pod.Phase = "Pending"
+					pod.Ready = false
+					pod.Containers = []kube.ContainerStatus{{Name: "netperf", Waiting: "ImagePullBackOff"}}
+				}
+				return ""
+			},
+			outcome: OutcomeError,
+			errStr:  "ImagePullBackOff",
+		},
+		{
+			name: "client unschedulable",
+			hook: func(pod *kube.Pod) string {
+				if pod.Labels["role"] == "cli" {
+					pod.Phase = "Pending"
+					pod.Unschedulable = "0/1 nodes are available"
+				}
+				return ""
+			},
+			outcome: OutcomeClientFailed,
+			errStr:  "unschedulable",
+		},
+		{
+			name: "client OOM",
+			hook: func(pod *kube.Pod) string {
+				if pod.Labels["role"] == "cli" {
+					pod.Phase = "Failed"
+					pod.Containers = []kube.ContainerStatus{{Name: "netperf", Terminated: "OOMKilled", ExitCode: 137}}
+				}
+				return ""
+			},
+			outcome: OutcomeClientFailed,
+			errStr:  "OOMKilled",
+		},
+	}
+
+	for _, tt := range tests {
+		st, _ := newTestPod2Pod(t, tt.hook)
+		start := time.Now()
+		err := st.Execute()
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.errStr) {
+			t.Errorf("%s: error %q does not contain %q", tt.name, err, tt.errStr)
+		}
+		if d := time.Since(start); d > 10*time.Second {
+			t.Errorf("%s: failure was detected after %s", tt.name, d)
+		}
+
+		m, err := LoadRunManifest(st.RunBenchCtx.getDir() + "/run.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Outcome != tt.outcome {
+			t.Errorf("%s: got outcome %s while expected %s", tt.name, m.Outcome, tt.outcome)
+		}
+	}
+}
+
+func TestExecuteClientTimeout(t *testing.T) {
+	setTestTimeouts(t, 5*time.Second, 100*time.Millisecond)
+	st, _ := newTestPod2Pod(t, nil)
+
+	err := st.Execute()
+	if !errors.Is(err, ErrClientTimeout) {
+		t.Fatalf("got error %v while expected %v", err, ErrClientTimeout)
+	}
+}
+
+func TestExecuteInterrupted(t *testing.T) {
+	setTestTimeouts(t, time.Minute, time.Minute)
+	st, _ := newTestPod2Pod(t, nil)
+
+	// interrupt the run while waiting for the client
+	ctx, cancel := context.WithCancel(context.Background())
+	st.RunBenchCtx.SetInterruptContext(ctx)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	err := st.Execute()
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("got error %v while expected %v", err, ErrInterrupted)
+	}
+
+	m, err := LoadRunManifest(st.RunBenchCtx.getDir() + "/run.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Outcome != OutcomeInterrupted {
+		t.Errorf("got outcome %s while expected %s", m.Outcome, OutcomeInterrupted)
+	}
+
+	pods, err := st.RunBenchCtx.KubeGetPods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 0 {
+		t.Errorf("got %d pods after interrupted run while expected 0", len(pods))
+	}
+}
@@ -0,0 +1,548 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// field manager used for server-side apply
+const fieldManager = "kubenetbench"
+
+// time to wait for a port-forward to be established
+const portForwardTimeout = 10 * time.Second
+
+type kindResource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+var kindResources = map[Kind]kindResource{
+	KindPod:           {schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}, true},
+	KindService:       {schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}, true},
+	KindDeployment:    {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, true},
+	KindDaemonSet:     {schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}, true},
+	KindJob:           {schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}, true},
+	KindNetworkPolicy: {schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}, true},
+	KindNamespace:     {schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}, false},
+
+	KindCiliumNetworkPolicy: {schema.GroupVersionResource{Group: "cilium.io", Version: "v2", Resource: "ciliumnetworkpolicies"}, true},
+}
+
+// clientGo is a client-go based Client
+type clientGo struct {
+	config    *rest.Config
+	cs        kubernetes.Interface
+	dyn       dynamic.Interface
+	mapper    meta.RESTMapper
+	namespace string
+}
+
+// NewClient creates a new client-go based client. If kubeconfig is empty,
+// the default loading rules are used (i.e., $KUBECONFIG or ~/.kube/config).
+// If namespace is empty, the namespace of the client is the namespace of the
+// current context.
+func NewClient(kubeconfig string, namespace string) (Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{}
+	overrides.Context.Namespace = namespace
+	cc := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	config, err := cc.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	namespace, _, err = cc.Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	cs, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(cs.Discovery()))
+	return &clientGo{
+		config:    config,
+		cs:        cs,
+		dyn:       dyn,
+		mapper:    mapper,
+		namespace: namespace,
+	}, nil
+}
+
+func (c *clientGo) Namespace() string {
+	return c.namespace
+}
+
+func (c *clientGo) WithNamespace(namespace string) Client {
+	ret := *c
+	ret.namespace = namespace
+	return &ret
+}
+
+func (c *clientGo) CreateNamespace(ctx context.Context, name string, labels map[string]string) error {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+	_, err := c.cs.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+	return err
+}
+
+// Apply uses server-side apply for all the objects of the manifest
+func (c *clientGo) Apply(ctx context.Context, fname string) error {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+
+	force := true
+	dec := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := dec.Decode(&obj.Object)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", fname, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		gvk := obj.GroupVersionKind()
+		mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return fmt.Errorf("%s: failed to find resource for %s: %w", fname, gvk, err)
+		}
+
+		var ri dynamic.ResourceInterface
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			ns := obj.GetNamespace()
+			if ns == "" {
+				ns = c.namespace
+			}
+			if ns == AllNamespaces {
+				return fmt.Errorf("%s: no namespace for %s/%s", fname, gvk.Kind, obj.GetName())
+			}
+			ri = c.dyn.Resource(mapping.Resource).Namespace(ns)
+		} else {
+			ri = c.dyn.Resource(mapping.Resource)
+		}
+
+		body, err := json.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+
+		_, err = ri.Patch(ctx, obj.GetName(), types.ApplyPatchType, body, metav1.PatchOptions{
+			FieldManager: fieldManager,
+			Force:        &force,
+		})
+		if err != nil {
+			return fmt.Errorf("%s: failed to apply %s/%s: %w", fname, gvk.Kind, obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (c *clientGo) GetPods(ctx context.Context, selector string, fieldSelector string) ([]Pod, error) {
+	pods, err := c.cs.CoreV1().Pods(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+		FieldSelector: fieldSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Pod, 0, len(pods.Items))
+	for i := range pods.Items {
+		ret = append(ret, podFromAPI(&pods.Items[i]))
+	}
+	return ret, nil
+}
+
+func podFromAPI(p *corev1.Pod) Pod {
+	ret := Pod{
+		Name:   p.Name,
+		Node:   p.Spec.NodeName,
+		IP:     p.Status.PodIP,
+		Phase:  string(p.Status.Phase),
+		Labels: p.Labels,
+	}
+
+	for _, cond := range p.Status.Conditions {
+		switch {
+		case cond.Type == corev1.PodReady:
+			ret.Ready = cond.Status == corev1.ConditionTrue
+		case cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable:
+			ret.Unschedulable = cond.Message
+		}
+	}
+
+	for i := range p.Status.InitContainerStatuses {
+		ret.InitContainers = append(ret.InitContainers, containerStatusFromAPI(&p.Status.InitContainerStatuses[i]))
+	}
+	for i := range p.Status.ContainerStatuses {
+		ret.Containers = append(ret.Containers, containerStatusFromAPI(&p.Status.ContainerStatuses[i]))
+	}
+	for i := range p.Spec.Containers {
+		ret.ContainerNames = append(ret.ContainerNames, p.Spec.Containers[i].Name)
+	}
+
+	return ret
+}
+
+func containerStatusFromAPI(cs *corev1.ContainerStatus) ContainerStatus {
+	st := ContainerStatus{
+		Name:    cs.Name,
+		Ready:   cs.Ready,
+		Running: cs.State.Running != nil,
+	}
+	if w := cs.State.Waiting; w != nil {
+		st.Waiting = w.Reason
+		st.Message = w.Message
+	}
+	if t := cs.State.Terminated; t != nil {
+		st.Terminated = t.Reason
+		st.ExitCode = int(t.ExitCode)
+		st.Message = t.Message
+	}
+	return st
+}
+
+// WatchPods lists the pods and then watches them for changes. If the watch
+// fails or is closed by the server, the pods are listed again (and reported
+// as PodAdded) and a new watch is started.
+func (c *clientGo) WatchPods(ctx context.Context, selector string) (<-chan PodEvent, error) {
+	pods := c.cs.CoreV1().Pods(c.namespace)
+
+	// list once, so that errors (e.g., an invalid selector) are reported
+	// to the caller
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan PodEvent)
+	send := func(ev PodEvent) bool {
+		select {
+		case ch <- ev:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(ch)
+		for {
+			for i := range list.Items {
+				if !send(PodEvent{Type: PodAdded, Pod: podFromAPI(&list.Items[i])}) {
+					return
+				}
+			}
+
+			w, err := pods.Watch(ctx, metav1.ListOptions{
+				LabelSelector:   selector,
+				ResourceVersion: list.ResourceVersion,
+			})
+			if err == nil {
+				if !c.forwardPodEvents(ctx, w, send) {
+					return
+				}
+			}
+
+			// relist, after a short delay to avoid busy-looping
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			list, err = pods.List(ctx, metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				list = &corev1.PodList{}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// forwardPodEvents forwards the events of a watch until the watch is closed
+// or fails. It returns false if the watch should not be restarted.
+func (c *clientGo) forwardPodEvents(ctx context.Context, w watch.Interface, send func(PodEvent) bool) bool {
+	defer w.Stop()
+	for ev := range w.ResultChan() {
+		var typ PodEventType
+		switch ev.Type {
+		case watch.Added:
+			typ = PodAdded
+		case watch.Modified:
+			typ = PodModified
+		case watch.Deleted:
+			typ = PodDeleted
+		case watch.Error:
+			return ctx.Err() == nil
+		default:
+			continue
+		}
+
+		pod, ok := ev.Object.(*corev1.Pod)
+		if !ok {
+			continue
+		}
+		if !send(PodEvent{Type: typ, Pod: podFromAPI(pod)}) {
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+func (c *clientGo) GetServices(ctx context.Context, selector string) ([]Service, error) {
+	svcs, err := c.cs.CoreV1().Services(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Service, 0, len(svcs.Items))
+	for i := range svcs.Items {
+		s := &svcs.Items[i]
+		svc := Service{
+			Name:      s.Name,
+			Type:      string(s.Spec.Type),
+			ClusterIP: s.Spec.ClusterIP,
+		}
+		for _, p := range s.Spec.Ports {
+			svc.Ports = append(svc.Ports, ServicePort{
+				Name:       p.Name,
+				Port:       int(p.Port),
+				TargetPort: p.TargetPort.IntValue(),
+				NodePort:   int(p.NodePort),
+			})
+		}
+		for _, ing := range s.Status.LoadBalancer.Ingress {
+			if ing.IP != "" {
+				svc.LoadBalancerIngress = append(svc.LoadBalancerIngress, ing.IP)
+			} else if ing.Hostname != "" {
+				svc.LoadBalancerIngress = append(svc.LoadBalancerIngress, ing.Hostname)
+			}
+		}
+		ret = append(ret, svc)
+	}
+	return ret, nil
+}
+
+func (c *clientGo) GetLogs(ctx context.Context, podName string, container string, w io.Writer) error {
+	opts := &corev1.PodLogOptions{Container: container}
+	stream, err := c.cs.CoreV1().Pods(c.namespace).GetLogs(podName, opts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// resource returns the resource interface for listing objects of kind
+func (c *clientGo) resource(kind Kind) (dynamic.ResourceInterface, error) {
+	kr, ok := kindResources[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown kind: %s", kind)
+	}
+	if !kr.namespaced {
+		return c.dyn.Resource(kr.gvr), nil
+	}
+	return c.dyn.Resource(kr.gvr).Namespace(c.namespace), nil
+}
+
+// isMissingResource returns true if err is the error of listing a resource that
+// does not exist (e.g., Cilium policies without the Cilium CRDs)
+func isMissingResource(err error) bool {
+	return err != nil && (apierrors.IsNotFound(err) || meta.IsNoMatchError(err))
+}
+
+func (c *clientGo) List(ctx context.Context, selector string, kinds ...Kind) ([]Object, error) {
+	var ret []Object
+	for _, kind := range kinds {
+		ri, err := c.resource(kind)
+		if err != nil {
+			return nil, err
+		}
+
+		objs, err := ri.List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if isMissingResource(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to list %s objects: %w", kind, err)
+		}
+
+		for _, obj := range objs.Items {
+			ret = append(ret, Object{
+				Kind:      kind,
+				Name:      obj.GetName(),
+				Namespace: obj.GetNamespace(),
+				Labels:    obj.GetLabels(),
+				Created:   obj.GetCreationTimestamp().Time,
+			})
+		}
+	}
+
+	return ret, nil
+}
+
+func (c *clientGo) Delete(ctx context.Context, selector string, kinds ...Kind) error {
+	propagation := metav1.DeletePropagationBackground
+	// NB: errors do not stop the deletion of other objects, and objects that
+	// were already deleted (e.g., pods of a deleted job that were garbage
+	// collected) are ignored
+	var errs []error
+	for _, kind := range kinds {
+		ri, err := c.resource(kind)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		objs, err := ri.List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if isMissingResource(err) {
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list %s objects: %w", kind, err))
+			continue
+		}
+
+		for _, obj := range objs.Items {
+			// NB: the client might be for all namespaces, so we use the
+			// namespace of the object
+			gvr := kindResources[kind].gvr
+			err := c.dyn.Resource(gvr).Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), metav1.DeleteOptions{
+				PropagationPolicy: &propagation,
+			})
+			if err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to delete %s/%s: %w", kind, obj.GetName(), err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *clientGo) GetNodes(ctx context.Context) ([]Node, error) {
+	nodes, err := c.cs.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Node, 0, len(nodes.Items))
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+		addrs := make([]string, 0, len(n.Status.Addresses))
+		for _, addr := range n.Status.Addresses {
+			addrs = append(addrs, addr.Address)
+		}
+		ret = append(ret, Node{
+			Name:      n.Name,
+			Addresses: addrs,
+		})
+	}
+	return ret, nil
+}
+
+func (c *clientGo) PortForward(ctx context.Context, podName string, port int) (int, error) {
+	req := c.cs.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(c.namespace).
+		Name(podName).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return 0, err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	var stopOnce sync.Once
+	stop := func() { stopOnce.Do(func() { close(stopCh) }) }
+
+	ports := []string{fmt.Sprintf("0:%d", port)}
+	fw, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, ports, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return 0, err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-errCh:
+		return 0, fmt.Errorf("port-forward to %s:%d failed: %w", podName, port, err)
+	case <-time.After(portForwardTimeout):
+		stop()
+		return 0, fmt.Errorf("timed out waiting for port-forward on %s:%d", podName, port)
+	}
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	fwPorts, err := fw.GetPorts()
+	if err != nil {
+		stop()
+		return 0, err
+	}
+	if len(fwPorts) == 0 {
+		stop()
+		return 0, fmt.Errorf("no forwarded ports for %s:%d", podName, port)
+	}
+
+	return int(fwPorts[0].Local), nil
+}
@@ -0,0 +1,729 @@
+package kube
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// FakePodHook is called when the fake client creates a pod. It may modify the
+// pod (e.g., set its phase or node), and returns the logs of the pod.
+type FakePodHook func(pod *Pod) string
+
+type fakeObject struct {
+	kind      Kind
+	name      string
+	namespace string
+	labels    map[string]string
+	created   time.Time
+	// for deployments, daemonsets, and jobs: the keys of the pods they created
+	pods []string
+	// for services
+	svc *Service
+}
+
+func (o *fakeObject) object() Object {
+	return Object{Kind: o.kind, Name: o.name, Namespace: o.namespace, Labels: o.labels, Created: o.created}
+}
+
+type fakePod struct {
+	Pod
+	namespace string
+	logs      string
+	created   time.Time
+}
+
+// fakeWatcher queues events for a WatchPods channel, so that notifying
+// watchers never blocks
+type fakeWatcher struct {
+	namespace string
+	sel       labels.Selector
+	mu        sync.Mutex
+	queue     []PodEvent
+	notify    chan struct{}
+}
+
+func (w *fakeWatcher) push(namespace string, ev PodEvent) {
+	if w.namespace != AllNamespaces && w.namespace != namespace {
+		return
+	}
+	if !w.sel.Matches(labels.Set(ev.Pod.Labels)) {
+		return
+	}
+	w.mu.Lock()
+	w.queue = append(w.queue, ev)
+	w.mu.Unlock()
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *fakeWatcher) pop() []PodEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ret := w.queue
+	w.queue = nil
+	return ret
+}
+
+// FakeCluster is the in-memory state of a fake cluster. It is shared by the
+// FakeClients of all namespaces (see FakeClient.WithNamespace).
+type FakeCluster struct {
+	// PodHook, if not nil, is called for every created pod
+	PodHook FakePodHook
+	// Applied records the applied objects as kind/name
+	Applied []string
+	// Deleted records the deleted objects as kind/name
+	Deleted []string
+	// Now returns the creation time of objects (default: time.Now)
+	Now func() time.Time
+	// PortForwardHook, if not nil, is called by PortForward and returns the
+	// local port (e.g., of a listener that emulates the pod port)
+	PortForwardHook func(podName string, port int) (int, error)
+
+	mu       sync.Mutex
+	objects  map[string]*fakeObject // key: see objKey
+	pods     map[string]*fakePod    // key: see podKey
+	watchers map[*fakeWatcher]struct{}
+	nodes    []Node
+	nextIP   int
+	nextPort int
+	// next allocated node port (of NodePort and LoadBalancer services)
+	nextNodePort int
+}
+
+// FakeClient is an in-memory Client for testing.
+//
+// Applied pods, deployments (one pod per replica), daemonsets (one pod
+// per node), jobs (one pod per parallelism), and services (with their node
+// ports and load balancer addresses) are tracked so that they can be queried.
+// Pods are placed on the node specified by nodeName or by a
+// kubernetes.io/hostname node selector (or the first node), are assigned an
+// IP, and are in the Running phase, unless PodHook modifies them.
+//
+// Objects can only be applied to the default namespace, or to namespaces
+// created with CreateNamespace.
+type FakeClient struct {
+	*FakeCluster
+	namespace string
+}
+
+var _ Client = &FakeClient{}
+
+// fakeDefaultNamespace is the namespace of clients created by NewFakeClient.
+// It always exists.
+const fakeDefaultNamespace = "default"
+
+// NewFakeClient creates a new fake client for a cluster with the given nodes
+func NewFakeClient(nodes ...Node) *FakeClient {
+	return &FakeClient{
+		FakeCluster: &FakeCluster{
+			objects:      make(map[string]*fakeObject),
+			pods:         make(map[string]*fakePod),
+			watchers:     make(map[*fakeWatcher]struct{}),
+			nodes:        nodes,
+			nextIP:       1,
+			nextPort:     40000,
+			nextNodePort: 30000,
+			Now:          time.Now,
+		},
+		namespace: fakeDefaultNamespace,
+	}
+}
+
+func objKey(kind Kind, namespace string, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+func podKey(namespace string, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+// inNamespace returns true if an object of the given namespace is visible
+// to the client
+func (f *FakeClient) inNamespace(namespace string) bool {
+	return f.namespace == AllNamespaces || f.namespace == namespace
+}
+
+func (f *FakeCluster) namespaceExists(namespace string) bool {
+	if namespace == fakeDefaultNamespace {
+		return true
+	}
+	_, ok := f.objects[objKey(KindNamespace, "", namespace)]
+	return ok
+}
+
+func (f *FakeCluster) newIP() string {
+	ip := fmt.Sprintf("10.0.%d.%d", f.nextIP/256, f.nextIP%256)
+	f.nextIP++
+	return ip
+}
+
+func (f *FakeCluster) defaultNode() string {
+	if len(f.nodes) == 0 {
+		return ""
+	}
+	return f.nodes[0].Name
+}
+
+func (f *FakeCluster) addPod(namespace string, name string, node string, podLabels map[string]string, containers []string) string {
+	if node == "" {
+		node = f.defaultNode()
+	}
+	p := &fakePod{
+		Pod: Pod{
+			Name:           name,
+			Node:           node,
+			IP:             f.newIP(),
+			Phase:          "Running",
+			Labels:         podLabels,
+			Ready:          true,
+			ContainerNames: containers,
+		},
+		namespace: namespace,
+		created:   f.Now(),
+	}
+	if f.PodHook != nil {
+		p.logs = f.PodHook(&p.Pod)
+	}
+	key := podKey(namespace, name)
+	f.pods[key] = p
+	f.notify(PodAdded, p)
+	return key
+}
+
+func (f *FakeCluster) notify(typ PodEventType, p *fakePod) {
+	for w := range f.watchers {
+		w.push(p.namespace, PodEvent{Type: typ, Pod: copyPod(&p.Pod)})
+	}
+}
+
+func copyPod(pod *Pod) Pod {
+	ret := *pod
+	ret.Containers = append([]ContainerStatus(nil), pod.Containers...)
+	ret.InitContainers = append([]ContainerStatus(nil), pod.InitContainers...)
+	ret.ContainerNames = append([]string(nil), pod.ContainerNames...)
+	return ret
+}
+
+// podNode returns the node of a pod spec: either its nodeName, or the
+// kubernetes.io/hostname node selector
+func podNode(obj map[string]interface{}, specPath ...string) string {
+	if node, _, _ := unstructured.NestedString(obj, append(specPath, "nodeName")...); node != "" {
+		return node
+	}
+	node, _, _ := unstructured.NestedString(obj, append(specPath, "nodeSelector", "kubernetes.io/hostname")...)
+	return node
+}
+
+// nestedInt returns an integer field of an object (0 if it does not exist).
+// NB: numbers are float64 when parsed from JSON, and int64 when parsed from
+// YAML.
+func nestedInt(obj map[string]interface{}, fields ...string) int {
+	switch v, _, _ := unstructured.NestedFieldNoCopy(obj, fields...); n := v.(type) {
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// podContainers returns the names of the containers of a pod spec
+func podContainers(obj map[string]interface{}, specPath ...string) []string {
+	containers, _, _ := unstructured.NestedSlice(obj, append(specPath, "containers")...)
+	var ret []string
+	for _, c := range containers {
+		if cm, ok := c.(map[string]interface{}); ok {
+			name, _, _ := unstructured.NestedString(cm, "name")
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// nestedCount returns a count field of an object (e.g., replicas), which is 1
+// if it does not exist
+func nestedCount(obj map[string]interface{}, fields ...string) int {
+	if _, ok, _ := unstructured.NestedFieldNoCopy(obj, fields...); !ok {
+		return 1
+	}
+	return nestedInt(obj, fields...)
+}
+
+// newService creates a service: it allocates a cluster IP (unless the service
+// is headless), node ports (for NodePort and LoadBalancer services, unless
+// they are specified), and a load balancer address (the loadBalancerIP of the
+// service, or a new IP).
+func (f *FakeCluster) newService(name string, obj *unstructured.Unstructured) *Service {
+	svcType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	if svcType == "" {
+		svcType = "ClusterIP"
+	}
+	svc := &Service{
+		Name: name,
+		Type: svcType,
+	}
+
+	if ip, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); ip == "None" {
+		svc.ClusterIP = ip
+	} else {
+		svc.ClusterIP = f.newIP()
+	}
+
+	ports, _, _ := unstructured.NestedSlice(obj.Object, "spec", "ports")
+	for _, p := range ports {
+		pm, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(pm, "name")
+		sp := ServicePort{
+			Name:       name,
+			Port:       nestedInt(pm, "port"),
+			TargetPort: nestedInt(pm, "targetPort"),
+			NodePort:   nestedInt(pm, "nodePort"),
+		}
+		if sp.TargetPort == 0 {
+			sp.TargetPort = sp.Port
+		}
+		if sp.NodePort == 0 && (svcType == "NodePort" || svcType == "LoadBalancer") {
+			sp.NodePort = f.nextNodePort
+			f.nextNodePort++
+		}
+		svc.Ports = append(svc.Ports, sp)
+	}
+
+	if svcType == "LoadBalancer" {
+		ip, _, _ := unstructured.NestedString(obj.Object, "spec", "loadBalancerIP")
+		if ip == "" {
+			ip = f.newIP()
+		}
+		svc.LoadBalancerIngress = []string{ip}
+	}
+	return svc
+}
+
+func (f *FakeClient) apply(obj *unstructured.Unstructured) error {
+	name := obj.GetName()
+	ns := obj.GetNamespace()
+	if ns == "" {
+		ns = f.namespace
+	}
+	if !f.namespaceExists(ns) {
+		return fmt.Errorf("fake client: namespace %q not found", ns)
+	}
+
+	var kind Kind
+	fo := &fakeObject{name: name, namespace: ns, labels: obj.GetLabels(), created: f.Now()}
+
+	switch obj.GetKind() {
+	case "Pod":
+		kind = KindPod
+		f.addPod(ns, name, podNode(obj.Object, "spec"), obj.GetLabels(), podContainers(obj.Object, "spec"))
+
+	case "Deployment":
+		kind = KindDeployment
+		podLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		for i := 0; i < nestedCount(obj.Object, "spec", "replicas"); i++ {
+			podName := fmt.Sprintf("%s-%d", name, i)
+			fo.pods = append(fo.pods, f.addPod(ns, podName, podNode(obj.Object, "spec", "template", "spec"), podLabels, podContainers(obj.Object, "spec", "template", "spec")))
+		}
+
+	case "DaemonSet":
+		kind = KindDaemonSet
+		podLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		for _, node := range f.nodes {
+			podName := fmt.Sprintf("%s-%s", name, node.Name)
+			fo.pods = append(fo.pods, f.addPod(ns, podName, node.Name, podLabels, podContainers(obj.Object, "spec", "template", "spec")))
+		}
+
+	case "Job":
+		kind = KindJob
+		podLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		// NB: numbers might be decoded as int64 or float64
+		for i := 0; i < nestedCount(obj.Object, "spec", "parallelism"); i++ {
+			podName := fmt.Sprintf("%s-%d", name, i)
+			fo.pods = append(fo.pods, f.addPod(ns, podName, podNode(obj.Object, "spec", "template", "spec"), podLabels, podContainers(obj.Object, "spec", "template", "spec")))
+		}
+
+	case "Service":
+		kind = KindService
+		fo.svc = f.newService(name, obj)
+
+	case "NetworkPolicy":
+		kind = KindNetworkPolicy
+
+	case "CiliumNetworkPolicy":
+		kind = KindCiliumNetworkPolicy
+
+	default:
+		return fmt.Errorf("fake client: unsupported kind: %s", obj.GetKind())
+	}
+
+	fo.kind = kind
+	f.objects[objKey(kind, ns, name)] = fo
+	f.Applied = append(f.Applied, fmt.Sprintf("%s/%s", kind, name))
+	return nil
+}
+
+// Namespace implements Client
+func (f *FakeClient) Namespace() string {
+	return f.namespace
+}
+
+// WithNamespace implements Client
+func (f *FakeClient) WithNamespace(namespace string) Client {
+	return &FakeClient{FakeCluster: f.FakeCluster, namespace: namespace}
+}
+
+// CreateNamespace implements Client
+func (f *FakeClient) CreateNamespace(ctx context.Context, name string, labels map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.namespaceExists(name) {
+		return fmt.Errorf("namespace %s already exists", name)
+	}
+	f.objects[objKey(KindNamespace, "", name)] = &fakeObject{
+		kind:    KindNamespace,
+		name:    name,
+		labels:  labels,
+		created: f.Now(),
+	}
+	f.Applied = append(f.Applied, fmt.Sprintf("%s/%s", KindNamespace, name))
+	return nil
+}
+
+// Apply implements Client
+func (f *FakeClient) Apply(ctx context.Context, fname string) error {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+
+	if f.namespace == AllNamespaces {
+		return fmt.Errorf("fake client: cannot apply %s to all namespaces", fname)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dec := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := dec.Decode(&obj.Object)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", fname, err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		if err := f.apply(obj); err != nil {
+			return err
+		}
+	}
+}
+
+// GetPods implements Client. Supported field selector fields are
+// metadata.name, spec.nodeName, and status.phase.
+func (f *FakeClient) GetPods(ctx context.Context, selector string, fieldSelector string) ([]Pod, error) {
+	lsel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	fsel, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ret := []Pod{}
+	for _, p := range f.pods {
+		if !f.inNamespace(p.namespace) {
+			continue
+		}
+		fset := fields.Set{
+			"metadata.name": p.Name,
+			"spec.nodeName": p.Node,
+			"status.phase":  p.Phase,
+		}
+		if lsel.Matches(labels.Set(p.Labels)) && fsel.Matches(fset) {
+			ret = append(ret, copyPod(&p.Pod))
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+// WatchPods implements Client
+func (f *FakeClient) WatchPods(ctx context.Context, selector string) (<-chan PodEvent, error) {
+	lsel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &fakeWatcher{namespace: f.namespace, sel: lsel, notify: make(chan struct{}, 1)}
+	f.mu.Lock()
+	for _, p := range f.pods {
+		w.push(p.namespace, PodEvent{Type: PodAdded, Pod: copyPod(&p.Pod)})
+	}
+	f.watchers[w] = struct{}{}
+	f.mu.Unlock()
+
+	ch := make(chan PodEvent)
+	go func() {
+		defer func() {
+			f.mu.Lock()
+			delete(f.watchers, w)
+			f.mu.Unlock()
+			close(ch)
+		}()
+		for {
+			for _, ev := range w.pop() {
+				select {
+				case ch <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-w.notify:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// GetServices implements Client
+func (f *FakeClient) GetServices(ctx context.Context, selector string) ([]Service, error) {
+	lsel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ret := []Service{}
+	for _, obj := range f.objects {
+		if obj.kind != KindService || !f.inNamespace(obj.namespace) {
+			continue
+		}
+		if lsel.Matches(labels.Set(obj.labels)) {
+			ret = append(ret, *obj.svc)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+// GetLogs implements Client. As the API server, it requires a container name
+// for pods with multiple containers. All the containers of a pod have the same
+// logs.
+func (f *FakeClient) GetLogs(ctx context.Context, podName string, container string, w io.Writer) error {
+	f.mu.Lock()
+	p, ok := f.pods[podKey(f.namespace, podName)]
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("pod %s not found", podName)
+	}
+	if container == "" && len(p.ContainerNames) > 1 {
+		return fmt.Errorf("a container name must be specified for pod %s, choose one of: %v", podName, p.ContainerNames)
+	}
+	if container != "" {
+		found := false
+		for _, name := range p.ContainerNames {
+			found = found || name == container
+		}
+		if !found {
+			return fmt.Errorf("container %s is not valid for pod %s", container, podName)
+		}
+	}
+
+	_, err := io.WriteString(w, p.logs)
+	return err
+}
+
+// List implements Client
+func (f *FakeClient) List(ctx context.Context, selector string, kinds ...Kind) ([]Object, error) {
+	lsel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ret := []Object{}
+	for _, kind := range kinds {
+		var objs []Object
+		if kind == KindPod {
+			for _, p := range f.pods {
+				if f.inNamespace(p.namespace) {
+					objs = append(objs, Object{Kind: KindPod, Name: p.Name, Namespace: p.namespace, Labels: p.Labels, Created: p.created})
+				}
+			}
+		} else {
+			for _, obj := range f.objects {
+				if obj.kind == kind && (kind == KindNamespace || f.inNamespace(obj.namespace)) {
+					objs = append(objs, obj.object())
+				}
+			}
+		}
+		sort.Slice(objs, func(i, j int) bool {
+			if objs[i].Name != objs[j].Name {
+				return objs[i].Name < objs[j].Name
+			}
+			return objs[i].Namespace < objs[j].Namespace
+		})
+
+		for _, obj := range objs {
+			if lsel.Matches(labels.Set(obj.Labels)) {
+				ret = append(ret, obj)
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (f *FakeCluster) deletePod(key string) {
+	p, ok := f.pods[key]
+	if !ok {
+		return
+	}
+	delete(f.pods, key)
+	f.Deleted = append(f.Deleted, fmt.Sprintf("%s/%s", KindPod, p.Name))
+	f.notify(PodDeleted, p)
+}
+
+func (f *FakeCluster) deleteObject(key string) {
+	obj, ok := f.objects[key]
+	if !ok {
+		return
+	}
+	delete(f.objects, key)
+	f.Deleted = append(f.Deleted, fmt.Sprintf("%s/%s", obj.kind, obj.name))
+	for _, podKey := range obj.pods {
+		f.deletePod(podKey)
+	}
+}
+
+// deleteNamespace deletes a namespace and all of its objects
+func (f *FakeCluster) deleteNamespace(name string) {
+	for key, p := range f.pods {
+		if p.namespace == name {
+			f.deletePod(key)
+		}
+	}
+	for key, obj := range f.objects {
+		if obj.kind != KindNamespace && obj.namespace == name {
+			f.deleteObject(key)
+		}
+	}
+	f.deleteObject(objKey(KindNamespace, "", name))
+}
+
+// Delete implements Client
+func (f *FakeClient) Delete(ctx context.Context, selector string, kinds ...Kind) error {
+	lsel, err := labels.Parse(selector)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, kind := range kinds {
+		if kind == KindPod {
+			for key, p := range f.pods {
+				if f.inNamespace(p.namespace) && lsel.Matches(labels.Set(p.Labels)) {
+					f.deletePod(key)
+				}
+			}
+			continue
+		}
+
+		for key, obj := range f.objects {
+			if obj.kind != kind || !lsel.Matches(labels.Set(obj.labels)) {
+				continue
+			}
+			if kind == KindNamespace {
+				f.deleteNamespace(obj.name)
+			} else if f.inNamespace(obj.namespace) {
+				f.deleteObject(key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetNodes implements Client
+func (f *FakeClient) GetNodes(ctx context.Context) ([]Node, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Node(nil), f.nodes...), nil
+}
+
+// PortForward implements Client. No forwarding is performed: it returns the
+// port of PortForwardHook, if set, or a new local port for every call.
+func (f *FakeClient) PortForward(ctx context.Context, podName string, port int) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.pods[podKey(f.namespace, podName)]; !ok {
+		return 0, fmt.Errorf("pod %s not found", podName)
+	}
+	if f.PortForwardHook != nil {
+		return f.PortForwardHook(podName, port)
+	}
+	ret := f.nextPort
+	f.nextPort++
+	return ret, nil
+}
+
+// UpdatePod modifies the pods (of all namespaces) that match the selector,
+// and notifies watchers
+func (f *FakeCluster) UpdatePod(selector string, update func(pod *Pod)) error {
+	lsel, err := labels.Parse(selector)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.pods {
+		if lsel.Matches(labels.Set(p.Labels)) {
+			update(&p.Pod)
+			f.notify(PodModified, p)
+		}
+	}
+	return nil
+}
+
+// SetPodPhase sets the phase of the pods (of all namespaces) that match the
+// selector
+func (f *FakeCluster) SetPodPhase(selector string, phase string) error {
+	return f.UpdatePod(selector, func(pod *Pod) {
+		pod.Phase = phase
+	})
+}
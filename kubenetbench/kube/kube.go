@@ -0,0 +1,178 @@
+// Package kube provides the interface to the Kubernetes API used by
+// kubenetbench, a client-go based implementation, and a fake (in-memory)
+// implementation for testing.
+package kube
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Kind is a kind of Kubernetes objects that can be listed and deleted
+type Kind string
+
+// Kinds of objects created by kubenetbench
+const (
+	KindPod           Kind = "pod"
+	KindDeployment    Kind = "deployment"
+	KindService       Kind = "service"
+	KindNetworkPolicy Kind = "networkpolicy"
+	KindDaemonSet     Kind = "daemonset"
+	KindJob           Kind = "job"
+	KindNamespace     Kind = "namespace"
+
+	// KindCiliumNetworkPolicy requires the Cilium CRDs, so it is not
+	// included in AllKinds
+	KindCiliumNetworkPolicy Kind = "ciliumnetworkpolicy"
+)
+
+// AllKinds are all the kinds of objects created by kubenetbench
+// NB: namespaces are last, so that deleting all kinds in order deletes the
+// objects of a namespace before the namespace itself.
+var AllKinds = []Kind{KindPod, KindDeployment, KindService, KindNetworkPolicy, KindDaemonSet, KindJob, KindNamespace}
+
+// AllNamespaces can be passed to Client.WithNamespace to get a client whose
+// list, watch, and delete operations apply to all namespaces
+const AllNamespaces = ""
+
+// Object holds generic information about an object
+type Object struct {
+	Kind Kind
+	Name string
+	// Namespace is empty for cluster-scoped objects (e.g., namespaces)
+	Namespace string
+	Labels    map[string]string
+	Created   time.Time
+}
+
+// ContainerStatus holds the container status information used by kubenetbench
+type ContainerStatus struct {
+	Name  string
+	Ready bool
+	// Running is true if the container is running
+	Running bool
+	// Waiting is the reason the container is waiting (e.g.,
+	// ImagePullBackOff), or empty if it is not waiting
+	Waiting string
+	// Terminated is the reason the container terminated (e.g., Completed,
+	// OOMKilled), or empty if it has not terminated
+	Terminated string
+	ExitCode   int
+	Message    string
+}
+
+// Pod holds the pod information used by kubenetbench
+type Pod struct {
+	Name   string
+	Node   string
+	IP     string
+	Phase  string
+	Labels map[string]string
+	// Ready is the status of the pod's Ready condition
+	Ready bool
+	// Unschedulable is the message of the PodScheduled condition if the pod
+	// cannot be scheduled, or empty otherwise
+	Unschedulable  string
+	Containers     []ContainerStatus
+	InitContainers []ContainerStatus
+	// ContainerNames are the names of the containers of the pod spec, in
+	// order
+	ContainerNames []string
+}
+
+// PodEventType is the type of a pod event
+type PodEventType string
+
+// Pod event types
+const (
+	PodAdded    PodEventType = "added"
+	PodModified PodEventType = "modified"
+	PodDeleted  PodEventType = "deleted"
+)
+
+// PodEvent is a change of a pod
+type PodEvent struct {
+	Type PodEventType
+	Pod  Pod
+}
+
+// Service holds the service information used by kubenetbench
+type Service struct {
+	Name      string
+	Type      string
+	ClusterIP string
+	Ports     []ServicePort
+	// addresses (IPs or hostnames) of the load balancer (for LoadBalancer
+	// services), as reported by the service status
+	LoadBalancerIngress []string
+}
+
+// ServicePort is a port of a service
+type ServicePort struct {
+	Name string
+	Port int
+	// TargetPort is 0 if the target port is named
+	TargetPort int
+	// NodePort is 0 if no node port is allocated
+	NodePort int
+}
+
+// Node holds the node information used by kubenetbench
+type Node struct {
+	Name string
+	// node addresses, in the order reported by the API server
+	Addresses []string
+}
+
+// Address returns the first address of the node, or "" if the node has no
+// addresses.
+func (n *Node) Address() string {
+	if len(n.Addresses) == 0 {
+		return ""
+	}
+	return n.Addresses[0]
+}
+
+// Client is the interface to the Kubernetes API.
+//
+// Selectors are label selectors (e.g., "knb-runid=foo,role=srv").
+// Namespaced operations use the namespace of the client.
+type Client interface {
+	// Namespace returns the namespace of the client
+	Namespace() string
+	// WithNamespace returns a client for the given namespace that shares
+	// the underlying connection with this client
+	WithNamespace(namespace string) Client
+	// CreateNamespace creates a namespace with the given labels
+	CreateNamespace(ctx context.Context, name string, labels map[string]string) error
+	// Apply creates or updates the objects of a (multi-document) YAML manifest file
+	Apply(ctx context.Context, fname string) error
+	// GetPods returns the pods that match the selector, and the field
+	// selector (if not empty)
+	GetPods(ctx context.Context, selector string, fieldSelector string) ([]Pod, error)
+	// WatchPods watches the pods that match the selector. Existing pods
+	// are reported first as PodAdded events. The channel is closed when
+	// ctx is done.
+	WatchPods(ctx context.Context, selector string) (<-chan PodEvent, error)
+	// GetServices returns the services that match the selector
+	GetServices(ctx context.Context, selector string) ([]Service, error)
+	// GetLogs writes the logs of a container of a pod to w. The container
+	// may be empty if the pod has a single container.
+	GetLogs(ctx context.Context, podName string, container string, w io.Writer) error
+	// List returns the objects of the given kinds that match the selector.
+	// Cluster-scoped kinds (i.e., namespaces) are listed independently of
+	// the namespace of the client. Kinds whose resource does not exist
+	// (e.g., without the Cilium CRDs) have no objects.
+	List(ctx context.Context, selector string, kinds ...Kind) ([]Object, error)
+	// Delete deletes the objects of the given kinds that match the selector.
+	// Deleting a namespace deletes all of its objects. Objects that no longer
+	// exist are ignored, and errors do not stop the deletion of the other
+	// objects (all the errors are returned).
+	Delete(ctx context.Context, selector string, kinds ...Kind) error
+	// GetNodes returns the nodes of the cluster
+	GetNodes(ctx context.Context) ([]Node, error)
+	// PortForward forwards a local port to the given pod port, and
+	// returns the local port. Forwarding stops when ctx is done.
+	PortForward(ctx context.Context, podName string, port int) (int, error)
+}
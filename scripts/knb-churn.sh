@@ -0,0 +1,30 @@
+#!/bin/sh
+#
+# Usage: knb-churn.sh <fortio load args>...
+#
+# Executes fortio load, and reports the TCP counters of the network namespace
+# of the pod (e.g., SYN retransmits) before and after the load, as lines of
+# the form: knb-tcp-counters before|after Name=Value...
+
+tcp_counters() {
+	# each table in /proc/net/{snmp,netstat} is a line with the names,
+	# followed by a line with the values
+	awk '$1 == "Tcp:" || $1 == "TcpExt:" {
+		if (!($1 in names)) {
+			names[$1] = $0
+			next
+		}
+		n = split(names[$1], name)
+		for (i = 2; i <= n; i++) {
+			if (name[i] == "ActiveOpens" || name[i] == "AttemptFails" || name[i] == "TCPSynRetrans")
+				printf " %s=%s", name[i], $i
+		}
+		delete names[$1]
+	}' /proc/net/snmp /proc/net/netstat
+}
+
+echo "knb-tcp-counters before$(tcp_counters)"
+fortio load "$@"
+ret=$?
+echo "knb-tcp-counters after$(tcp_counters)"
+exit $ret
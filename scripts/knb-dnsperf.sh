@@ -0,0 +1,47 @@
+#!/bin/sh
+#
+# Usage: knb-dnsperf.sh <query>... -- <dnsperf args>...
+#
+# Writes the queries (e.g., "kubernetes.default.svc.cluster.local A") to a
+# data file, and executes dnsperf with it. The per-query lines of dnsperf -v
+# (e.g., "> NOERROR kubernetes.default.svc.cluster.local A 0.000512") are not
+# printed, so that the logs of long runs do not exceed the log rotation of
+# the kubelet. Instead, the latency percentiles (in seconds) are reported
+# after the output of dnsperf, as a line of the form:
+# knb-dnsperf-latencies n=<queries> p50=<s> p90=<s> p99=<s> p99.9=<s>
+
+queries=$(mktemp)
+while [ $# -gt 0 ] && [ "$1" != "--" ]; do
+	echo "$1" >> "$queries"
+	shift
+done
+[ $# -gt 0 ] && shift
+
+out=$(mktemp)
+latencies=$(mktemp)
+dnsperf -d "$queries" "$@" > "$out"
+ret=$?
+
+# NB: query lines that cannot be parsed are printed, so that they are reported
+awk -v latencies="$latencies" '
+	/^> / && NF >= 3 && $NF ~ /^[0-9.]+$/ { print $NF > latencies; next }
+	{ print }
+' "$out"
+
+# the percentiles are the nearest-rank ones (see utils.Percentile)
+sort -n "$latencies" | awk '
+	function rank(p,  r) {
+		r = int(p * NR / 100)
+		if (r < p * NR / 100)
+			r++
+		return r < 1 ? 1 : r
+	}
+	{ lat[NR] = $1 }
+	END {
+		if (NR > 0)
+			printf "knb-dnsperf-latencies n=%d p50=%s p90=%s p99=%s p99.9=%s\n",
+				NR, lat[rank(50)], lat[rank(90)], lat[rank(99)], lat[rank(99.9)]
+	}'
+
+rm -f "$queries" "$out" "$latencies"
+exit $ret
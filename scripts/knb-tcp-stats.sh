@@ -0,0 +1,22 @@
+#!/bin/sh
+#
+# Usage: knb-tcp-stats.sh
+#
+# Reports the number of TCP connections accepted (PassiveOpens) in the network
+# namespace of the pod. It is executed by socat for every connection of a
+# sidecar of service backends, so the connection itself is included.
+
+# the Tcp table of /proc/net/snmp is a line with the names, followed by a line
+# with the values
+awk '$1 == "Tcp:" {
+	if (names == "") {
+		names = $0
+		next
+	}
+	n = split(names, name)
+	for (i = 2; i <= n; i++) {
+		if (name[i] == "PassiveOpens")
+			print $i
+	}
+	exit
+}' /proc/net/snmp
@@ -9,11 +9,15 @@ import (
 
 // PrefixWriter wraps an io.Writer to add a prefix on each line
 // NB: caller is responsble for calling Flush().
+// The first write error is recorded: subsequent writes fail with it, and it
+// is returned by Flush() and Done(). This allows the *OrDie methods to be used
+// without checking errors after every line.
 type PrefixWriter struct {
 	prefixes []string
 	writer   io.Writer
 	buff     bytes.Buffer
 	skip     bool
+	err      error
 }
 
 // NewPrefixWriter creates a new PrefixWriter
@@ -29,24 +33,40 @@ func (pw *PrefixWriter) Prefix() string {
 	return strings.Join(pw.prefixes, "")
 }
 
+// setErr records err, if it is the first write error
+func (pw *PrefixWriter) setErr(err error) error {
+	if pw.err == nil {
+		pw.err = err
+	}
+	return err
+}
+
 func (pw *PrefixWriter) flush() (int, error) {
+	if pw.err != nil {
+		return 0, pw.err
+	}
+
 	if pw.buff.Len() > 0 {
 		if pw.skip {
 			pw.skip = false
 		} else {
 			_, err := io.WriteString(pw.writer, pw.Prefix())
 			if err != nil {
-				return 0, err
+				return 0, pw.setErr(err)
 			}
 		}
 
-		return io.WriteString(pw.writer, pw.buff.String())
+		n, err := io.WriteString(pw.writer, pw.buff.String())
+		return n, pw.setErr(err)
 	}
 
 	return 0, nil
 }
 
 func (pw *PrefixWriter) Write(data []byte) (int, error) {
+	if pw.err != nil {
+		return 0, pw.err
+	}
 
 	for i, b := range data {
 		if b == '\n' {
@@ -57,7 +77,7 @@ func (pw *PrefixWriter) Write(data []byte) (int, error) {
 
 			_, err = fmt.Fprintln(pw.writer)
 			if err != nil {
-				return i, err
+				return i, pw.setErr(err)
 			}
 
 			pw.buff.Reset()
@@ -71,6 +91,9 @@ func (pw *PrefixWriter) Write(data []byte) (int, error) {
 
 // WriteString  writes a string
 func (pw *PrefixWriter) WriteString(data string) (int, error) {
+	if pw.err != nil {
+		return 0, pw.err
+	}
 
 	for i, b := range data {
 		if b == '\n' {
@@ -81,7 +104,7 @@ func (pw *PrefixWriter) WriteString(data string) (int, error) {
 
 			_, err = fmt.Fprintln(pw.writer)
 			if err != nil {
-				return i, err
+				return i, pw.setErr(err)
 			}
 
 			pw.buff.Reset()
@@ -93,10 +116,13 @@ func (pw *PrefixWriter) WriteString(data string) (int, error) {
 	return len(data), nil
 }
 
-// AppendNewLineOrDie appends a new line and panics in case of an error
+// AppendNewLineOrDie appends a new line. Errors are recorded, and returned by
+// Flush() and Done().
 // here PrefixWriter will not scan the input for new lines
 func (pw *PrefixWriter) AppendNewLineOrDie(s string) {
-	pw.flush()
+	if _, err := pw.flush(); err != nil {
+		return
+	}
 
 	var prefix string
 	if pw.skip {
@@ -107,25 +133,19 @@ func (pw *PrefixWriter) AppendNewLineOrDie(s string) {
 	}
 
 	_, err := fmt.Fprintf(pw.writer, "%s%s\n", prefix, s)
-	if err != nil {
-		panic("AppendNewLineOrDie failed")
-	}
+	pw.setErr(err)
 }
 
-// WriteOrDie writes or panics
+// WriteOrDie writes data. Errors are recorded, and returned by Flush() and
+// Done().
 func (pw *PrefixWriter) WriteOrDie(data []byte) {
-	_, err := pw.Write(data)
-	if err != nil {
-		panic("WriteOrDie failed")
-	}
+	pw.Write(data)
 }
 
-// WriteStringOrDie writes a string or panics
+// WriteStringOrDie writes a string. Errors are recorded, and returned by
+// Flush() and Done().
 func (pw *PrefixWriter) WriteStringOrDie(data string) {
-	_, err := pw.WriteString(data)
-	if err != nil {
-		panic("WriteStringOrDie failed")
-	}
+	pw.WriteString(data)
 }
 
 // PushPrefix pushes a prefix to the stack
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// Mean returns the arithmetic mean of xs (NaN if xs is empty)
+func Mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return math.NaN()
+	}
+
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// Median returns the median of xs (NaN if xs is empty)
+func Median(xs []float64) float64 {
+	n := len(xs)
+	if n == 0 {
+		return math.NaN()
+	}
+
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// Percentile returns the p-th (0 < p <= 100) percentile of xs using the
+// nearest-rank method (NaN if xs is empty)
+func Percentile(xs []float64, p float64) float64 {
+	n := len(xs)
+	if n == 0 {
+		return math.NaN()
+	}
+
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	rank := int(math.Ceil(p / 100 * float64(n)))
+	if rank < 1 {
+		rank = 1
+	} else if rank > n {
+		rank = n
+	}
+	return s[rank-1]
+}
+
+// Stddev returns the sample standard deviation of xs (NaN if len(xs) < 2)
+func Stddev(xs []float64) float64 {
+	n := len(xs)
+	if n < 2 {
+		return math.NaN()
+	}
+
+	m := Mean(xs)
+	ss := 0.0
+	for _, x := range xs {
+		ss += (x - m) * (x - m)
+	}
+	return math.Sqrt(ss / float64(n-1))
+}
+
+// maximum number of rank assignments for which MannWhitneyU computes the exact
+// p-value (e.g., 10 repetitions on each side: 184756)
+const mannWhitneyExactMax = 200000
+
+// binomial returns n choose k, as a float64
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	ret := 1.0
+	for i := 1; i <= k; i++ {
+		ret = ret * float64(n-k+i) / float64(i)
+	}
+	return ret
+}
+
+// MannWhitneyMinP returns the smallest two-sided p-value that a Mann-Whitney U
+// test can produce for samples of size n1 and n2 (i.e., when the samples do
+// not overlap). Tests with a minimum p-value above the significance level
+// cannot detect a difference (e.g., 3 repetitions on each side: 0.1).
+func MannWhitneyMinP(n1, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	return math.Min(1, 2/binomial(n1+n2, n1))
+}
+
+// MannWhitneyU performs a two-sided Mann-Whitney U test on samples xs and
+// ys, and returns the U statistic of xs and the p-value. For small samples
+// (see mannWhitneyExactMax), the p-value is exact: it is computed from all
+// the assignments of the (tied) ranks to the two samples. For larger samples,
+// it is computed using the normal approximation (with tie and continuity
+// correction). If either sample is empty, the p-value is NaN.
+func MannWhitneyU(xs, ys []float64) (float64, float64) {
+	n1, n2 := len(xs), len(ys)
+	if n1 == 0 || n2 == 0 {
+		return math.NaN(), math.NaN()
+	}
+
+	type obs struct {
+		v   float64
+		grp int
+	}
+	all := make([]obs, 0, n1+n2)
+	for _, x := range xs {
+		all = append(all, obs{x, 0})
+	}
+	for _, y := range ys {
+		all = append(all, obs{y, 1})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	// assign ranks, averaging ties
+	n := len(all)
+	ranks := make([]float64, n)
+	r1 := 0.0
+	tieSum := 0.0
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // ranks are 1-based: (i+1 + j) / 2
+		for k := i; k < j; k++ {
+			ranks[k] = rank
+			if all[k].grp == 0 {
+				r1 += rank
+			}
+		}
+		t := float64(j - i)
+		tieSum += t*t*t - t
+		i = j
+	}
+
+	fn1, fn2, fn := float64(n1), float64(n2), float64(n)
+	u1 := r1 - fn1*(fn1+1)/2
+	mu := fn1 * fn2 / 2
+	if binomial(n, n1) <= mannWhitneyExactMax {
+		return u1, mannWhitneyExactP(ranks, n1, r1)
+	}
+
+	sigma := math.Sqrt(fn1 * fn2 / 12 * ((fn + 1) - tieSum/(fn*(fn-1))))
+	if sigma == 0 || math.IsNaN(sigma) {
+		return u1, 1
+	}
+
+	d := math.Abs(u1-mu) - 0.5
+	if d < 0 {
+		d = 0
+	}
+	z := d / sigma
+	return u1, math.Erfc(z / math.Sqrt2)
+}
+
+// mannWhitneyExactP returns the fraction of the assignments of n1 of ranks to
+// the first sample whose rank sum is at least as far from its mean as r1
+func mannWhitneyExactP(ranks []float64, n1 int, r1 float64) float64 {
+	n := len(ranks)
+	mean := float64(n1) * float64(n+1) / 2
+	dist := math.Abs(r1-mean) - 1e-9
+
+	var count func(i, k int, sum float64) float64
+	count = func(i, k int, sum float64) float64 {
+		if k == 0 {
+			if math.Abs(sum-mean) >= dist {
+				return 1
+			}
+			return 0
+		}
+		if n-i < k {
+			return 0
+		}
+		return count(i+1, k-1, sum+ranks[i]) + count(i+1, k, sum)
+	}
+	return count(0, n1, 0) / binomial(n, n1)
+}
+
+// two-sided 95% critical values of Student's t distribution, for 1 to 30
+// degrees of freedom
+var tCrit95 = [...]float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// MeanCI95 returns the 95% confidence interval of the mean of xs, using
+// Student's t distribution. If len(xs) < 2, NaNs are returned.
+func MeanCI95(xs []float64) (float64, float64) {
+	n := len(xs)
+	if n < 2 {
+		return math.NaN(), math.NaN()
+	}
+
+	t := 1.960
+	if df := n - 1; df <= len(tCrit95) {
+		t = tCrit95[df-1]
+	}
+
+	m := Mean(xs)
+	h := t * Stddev(xs) / math.Sqrt(float64(n))
+	return m - h, m + h
+}
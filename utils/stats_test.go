@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-4
+}
+
+func TestStats(t *testing.T) {
+	xs := []float64{4, 1, 3, 2}
+	if m := Mean(xs); m != 2.5 {
+		t.Errorf("Mean: got %v while expected 2.5", m)
+	}
+	if m := Median(xs); m != 2.5 {
+		t.Errorf("Median: got %v while expected 2.5", m)
+	}
+	if p := Percentile(xs, 50); p != 2 {
+		t.Errorf("Percentile(50): got %v while expected 2", p)
+	}
+	if p := Percentile(xs, 99.9); p != 4 {
+		t.Errorf("Percentile(99.9): got %v while expected 4", p)
+	}
+	if s := Stddev(xs); !almostEqual(s, 1.290994) {
+		t.Errorf("Stddev: got %v while expected 1.290994", s)
+	}
+	if !math.IsNaN(Stddev([]float64{1})) {
+		t.Errorf("Stddev of a single value should be NaN")
+	}
+
+	// mean: 2.5, stddev: 1.291, t(0.975, 3) = 3.182
+	lo, hi := MeanCI95(xs)
+	if !almostEqual(lo, 0.446028) || !almostEqual(hi, 4.553972) {
+		t.Errorf("MeanCI95: got [%v, %v] while expected [0.446028, 4.553972]", lo, hi)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	// small samples: exact p-values
+	u, p := MannWhitneyU([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	if u != 0 || !almostEqual(p, 2.0/252) {
+		t.Errorf("got u=%v p=%v while expected u=0 p=0.007937", u, p)
+	}
+
+	// with ties
+	u, p = MannWhitneyU([]float64{1, 2, 2, 3}, []float64{2, 3, 4, 4})
+	if u != 2.5 || !almostEqual(p, 0.2) {
+		t.Errorf("got u=%v p=%v while expected u=2.5 p=0.2", u, p)
+	}
+
+	// identical samples
+	_, p = MannWhitneyU([]float64{1, 1}, []float64{1, 1})
+	if p != 1 {
+		t.Errorf("got p=%v while expected 1", p)
+	}
+
+	// large samples: normal approximation, with continuity correction
+	var xs, ys []float64
+	for i := 0; i < 15; i++ {
+		xs = append(xs, float64(i))
+		ys = append(ys, float64(i+15))
+	}
+	u, p = MannWhitneyU(xs, ys)
+	if u != 0 || math.Abs(p-3.391821e-6) > 1e-10 {
+		t.Errorf("got u=%v p=%v while expected u=0 p=3.391821e-6", u, p)
+	}
+}
+
+func TestMannWhitneyMinP(t *testing.T) {
+	for _, tc := range []struct {
+		n1, n2 int
+		p      float64
+	}{
+		{1, 1, 1},
+		{3, 3, 0.1},
+		{4, 4, 2.0 / 70},
+		{1, 10, 2.0 / 11},
+	} {
+		if p := MannWhitneyMinP(tc.n1, tc.n2); !almostEqual(p, tc.p) {
+			t.Errorf("MannWhitneyMinP(%d, %d): got %v while expected %v", tc.n1, tc.n2, p, tc.p)
+		}
+		// non-overlapping samples produce the minimum p-value
+		xs, ys := make([]float64, tc.n1), make([]float64, tc.n2)
+		for i := range xs {
+			xs[i] = float64(i)
+		}
+		for i := range ys {
+			ys[i] = float64(100 + i)
+		}
+		if _, p := MannWhitneyU(xs, ys); tc.p < 1 && !almostEqual(p, tc.p) {
+			t.Errorf("MannWhitneyU(%d, %d): got p=%v while expected %v", tc.n1, tc.n2, p, tc.p)
+		}
+	}
+}
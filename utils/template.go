@@ -9,8 +9,9 @@ import (
 	"text/template/parse"
 )
 
-// PrefixRenderer writes data to pw
-type PrefixRenderer = func(pw *PrefixWriter, params map[string]interface{})
+// PrefixRenderer writes data to pw, or returns an error (e.g., for invalid
+// parameters)
+type PrefixRenderer = func(pw *PrefixWriter, params map[string]interface{}) error
 
 // RenderTemplate renders templates respecting indentation
 // Its intended use is for YAML templates
@@ -23,8 +24,14 @@ func RenderTemplate(
 ) error {
 	var buff bytes.Buffer
 
-	main0.Execute(&buff, vmap)
-	main := template.Must(template.New("main").Parse(buff.String()))
+	err := main0.Execute(&buff, vmap)
+	if err != nil {
+		return err
+	}
+	main, err := template.New("main").Parse(buff.String())
+	if err != nil {
+		return err
+	}
 
 	mainTree := main.Tree
 	lastIndent := -1
@@ -58,18 +65,21 @@ func RenderTemplate(
 				return fmt.Errorf("template %s does not exist", nodeTmpl.Name)
 			}
 			if lastIndent == -1 {
-				panic("NYI")
+				return fmt.Errorf("template %s: rendering without indentation is not supported", nodeTmpl.Name)
 			}
 			pw := NewPrefixWriter(wr, true)
 			pw.PushPrefix(strings.Repeat(" ", lastIndent))
-			renderer(pw, vmap)
+			err := renderer(pw, vmap)
+			if err != nil {
+				return fmt.Errorf("template %s: %w", nodeTmpl.Name, err)
+			}
 			pw.PopPrefix()
-			err := pw.Done()
+			err = pw.Done()
 			if err != nil {
 				return fmt.Errorf("error terminating prefix writer: %w", err)
 			}
 		default:
-			panic("Unexpected node type")
+			return fmt.Errorf("unexpected node type: %v", ty)
 		}
 	}
 	return nil
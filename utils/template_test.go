@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"text/template"
@@ -30,11 +31,8 @@ func TestPizza(t *testing.T) {
 	pizzaT := template.Must(template.New("pizza").Parse(pizzaTmpl))
 
 	templates := map[string]PrefixRenderer{
-		"pizza": func(pw *PrefixWriter, params map[string]interface{}) {
-			err := pizzaT.Execute(pw, params)
-			if err != nil {
-				panic(err)
-			}
+		"pizza": func(pw *PrefixWriter, params map[string]interface{}) error {
+			return pizzaT.Execute(pw, params)
 		},
 	}
 
@@ -48,3 +46,58 @@ func TestPizza(t *testing.T) {
 		t.Errorf("RenderTemplate produced unexpected result:\n-->%s<--\nvs:\n-->%s<--", bld.String(), expected)
 	}
 }
+
+func TestRenderTemplateError(t *testing.T) {
+	values := map[string]interface{}{
+		"title":   "Best food",
+		"section": "{{template \"pizza\"}}",
+	}
+
+	mainT := template.Must(template.New("main").Parse(mainTmpl))
+	templates := map[string]PrefixRenderer{
+		"pizza": func(pw *PrefixWriter, params map[string]interface{}) error {
+			return errors.New("out of pizza")
+		},
+	}
+
+	var bld strings.Builder
+	err := RenderTemplate(mainT, values, templates, &bld)
+	if err == nil || err.Error() != "template pizza: out of pizza" {
+		t.Errorf("RenderTemplate returned unexpected error: %v", err)
+	}
+}
+
+// failingWriter fails writes after n bytes
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(data []byte) (int, error) {
+	if len(data) > w.n {
+		return 0, errors.New("disk full")
+	}
+	w.n -= len(data)
+	return len(data), nil
+}
+
+func TestRenderTemplateWriteError(t *testing.T) {
+	values := map[string]interface{}{
+		"title":   "Best food",
+		"section": "{{template \"pizza\"}}",
+	}
+
+	mainT := template.Must(template.New("main").Parse(mainTmpl))
+	templates := map[string]PrefixRenderer{
+		"pizza": func(pw *PrefixWriter, params map[string]interface{}) error {
+			pw.AppendNewLineOrDie("title: Pizza is the best")
+			pw.AppendNewLineOrDie("body: I love pizza!")
+			return nil
+		},
+	}
+
+	// the main template is written, and the renderer fails
+	err := RenderTemplate(mainT, values, templates, &failingWriter{n: 40})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("RenderTemplate returned unexpected error: %v", err)
+	}
+}